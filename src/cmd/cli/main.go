@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	api2 "obsfind/src/pkg/api"
 	"obsfind/src/pkg/config"
 	consoleutil2 "obsfind/src/pkg/consoleutil"
 	"obsfind/src/pkg/consts"
 	"obsfind/src/pkg/indexer"
+	"obsfind/src/pkg/markdown"
+	"obsfind/src/pkg/mcp"
+	query2 "obsfind/src/pkg/query"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,8 +47,13 @@ func main() {
 	rootCmd.AddCommand(
 		newSearchCommand(),
 		newSimilarCommand(),
+		newLinksCommand(),
+		newBacklinksCommand(),
+		newAskCommand(),
+		newMCPCommand(),
 		newStatusCommand(),
 		newReindexCommand(),
+		newGCCommand(),
 		newStartCommand(),
 		newStopCommand(),
 		newConfigCommand(),
@@ -59,17 +71,83 @@ func main() {
 // newSearchCommand creates the search command
 func newSearchCommand() *cobra.Command {
 	var limit int
+	var offset int
 	var minScore float32
 	var tags string
 	var pathPrefix string
+	var mode string
+	var where []string
+	var groupBy string
+	var groupSize int
+	var diversity float32
+	var graphBoost float32
+	var expandLinks bool
 
 	cmd := &cobra.Command{
 		Use:   "search [query]",
 		Short: "Search for content in your vault",
-		Args:  cobra.ExactArgs(1),
+		Long: `Search for content in your vault.
+
+The query can mix free text with filters:
+
+  tag:work -tag:archive path:projects/ title:"weekly" fm.status:active created:>2024-01-01 "exact phrase"
+
+  tag:a,b             notes tagged a or b
+  path:folder/        notes inside a folder
+  title:word          notes whose title contains the word
+  vault:name          notes from one vault
+  lang:go             code blocks in a language (with indexing.index_code_blocks)
+  fm.key:value        frontmatter value, fm.key:>3 compares numbers and dates
+  created:2024-01     created in a period, also >, >=, <, <= and start..end
+  modified:>2024-05-01
+  "exact phrase"      text that has to occur in the note
+  -filter, -word      exclude matches
+
+Results are grouped by note, showing the best matching sections of each.
+Use --group-by chunk to list every matching chunk on its own.
+
+--diversity between 0 and 1 pushes near-duplicate matches, such as notes made
+from the same template, down in favor of different ones.
+
+--graph-boost between 0 and 1 raises notes that link to or from the best
+matches, and notes many others link to. --expand-links also lists the notes
+linked with the best matches that didn't match the query themselves.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			query := args[0]
 
+			searchMode, err := indexer.ParseSearchMode(mode)
+			if err != nil {
+				return err
+			}
+
+			grouping, err := indexer.ParseGroupBy(groupBy)
+			if err != nil {
+				return err
+			}
+
+			if offset < 0 {
+				return fmt.Errorf("invalid offset %d: must not be negative", offset)
+			}
+
+			if diversity < 0 || diversity > 1 {
+				return fmt.Errorf("invalid diversity %g: expected a value between 0 and 1", diversity)
+			}
+
+			if graphBoost < 0 || graphBoost > 1 {
+				return fmt.Errorf("invalid graph boost %g: expected a value between 0 and 1", graphBoost)
+			}
+
+			// Report malformed queries without a round trip to the daemon
+			if _, err := query2.Parse(query); err != nil {
+				return err
+			}
+			for _, clause := range where {
+				if _, err := query2.ParseWhere(clause); err != nil {
+					return err
+				}
+			}
+
 			// Create API client
 			client, err := getClient()
 			if err != nil {
@@ -84,11 +162,19 @@ func newSearchCommand() *cobra.Command {
 
 			// Create search request
 			req := &api2.SearchRequest{
-				Query:      query,
-				Limit:      limit,
-				MinScore:   minScore,
-				Tags:       tagSlice,
-				PathPrefix: pathPrefix,
+				Query:       query,
+				Limit:       limit,
+				Offset:      offset,
+				MinScore:    minScore,
+				Tags:        tagSlice,
+				PathPrefix:  pathPrefix,
+				Mode:        string(searchMode),
+				Where:       where,
+				GroupBy:     string(grouping),
+				GroupSize:   groupSize,
+				Diversity:   diversity,
+				GraphBoost:  graphBoost,
+				ExpandLinks: expandLinks,
 			}
 
 			// Execute search
@@ -134,16 +220,26 @@ func newSearchCommand() *cobra.Command {
 			fmt.Printf("Found %d results for query: %s\n\n", len(results), query)
 
 			// Format and print results
-			displaySearchResults(convertToAPIResults(results))
+			displaySearchResults(results)
 
 			return nil
 		},
 	}
 
 	cmd.Flags().IntVar(&limit, "limit", 10, "Maximum number of results")
+	cmd.Flags().IntVar(&offset, "offset", 0, "Number of results to skip, for paging")
 	cmd.Flags().Float32Var(&minScore, "score", 0.6, "Minimum similarity score (0-1)")
 	cmd.Flags().StringVar(&tags, "tags", "", "Filter by tags (comma-separated)")
-	cmd.Flags().StringVar(&pathPrefix, "path", "", "Filter by path prefix")
+	cmd.Flags().StringVar(&pathPrefix, "folder", "", "Filter by folder, matching whole folder names, or by note path")
+	cmd.Flags().StringVar(&pathPrefix, "path", "", "Filter by folder")
+	cmd.Flags().MarkDeprecated("path", "use --folder, which matches whole folder names rather than any path prefix")
+	cmd.Flags().StringVar(&mode, "mode", string(indexer.SearchModeHybrid), "Ranking mode: semantic, keyword or hybrid")
+	cmd.Flags().StringArrayVar(&where, "where", nil, "Filter by frontmatter, e.g. status=active, priority>=2 or due<2025-01-01 (repeatable)")
+	cmd.Flags().StringVar(&groupBy, "group-by", string(indexer.GroupByDocument), "Group results by document or list every chunk")
+	cmd.Flags().IntVar(&groupSize, "group-size", indexer.DefaultGroupSize, "Number of sections shown per document")
+	cmd.Flags().Float32Var(&diversity, "diversity", 0, "Trade relevance for variety between 0 (off) and 1")
+	cmd.Flags().Float32Var(&graphBoost, "graph-boost", 0, "Boost notes linked with the best matches between 0 (off) and 1")
+	cmd.Flags().BoolVar(&expandLinks, "expand-links", false, "Add notes linked to or from the best matches")
 
 	return cmd
 }
@@ -154,17 +250,40 @@ func newSimilarCommand() *cobra.Command {
 	var minScore float32
 	var tags string
 	var pathPrefix string
+	var strategy string
+	var groupBy string
+	var groupSize int
 
 	cmd := &cobra.Command{
 		Use:   "similar [file_path]",
 		Short: "Find notes similar to a reference file",
-		Args:  cobra.ExactArgs(1),
+		Long: `Find notes similar to a reference file.
+
+The file is either a path on disk or a path relative to the vault.
+Every chunk of the note is compared with the index:
+
+  --strategy max       score each match by the chunk of the note it resembles most
+  --strategy centroid  compare the note as a whole, using the mean of its chunks
+  --strategy section   list the matches of every section of the note separately`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			filePath := args[0]
 
-			// Check if file exists
-			if _, err := os.Stat(filePath); os.IsNotExist(err) {
-				return fmt.Errorf("file not found: %s", filePath)
+			similarStrategy, err := indexer.ParseSimilarStrategy(strategy)
+			if err != nil {
+				return err
+			}
+
+			grouping, err := indexer.ParseGroupBy(groupBy)
+			if err != nil {
+				return err
+			}
+
+			// Files on disk are sent with their absolute path, anything else is taken as relative to the vault
+			if _, err := os.Stat(filePath); err == nil {
+				if absPath, err := filepath.Abs(filePath); err == nil {
+					filePath = absPath
+				}
 			}
 
 			// Create API client
@@ -186,6 +305,9 @@ func newSimilarCommand() *cobra.Command {
 				MinScore:   minScore,
 				Tags:       tagSlice,
 				PathPrefix: pathPrefix,
+				Strategy:   string(similarStrategy),
+				GroupBy:    string(grouping),
+				GroupSize:  groupSize,
 			}
 
 			// Execute similar search
@@ -231,7 +353,7 @@ func newSimilarCommand() *cobra.Command {
 			fmt.Printf("Found %d documents similar to: %s\n\n", len(results), filePath)
 
 			// Format and print results
-			displaySearchResults(convertToAPIResults(results))
+			displaySearchResults(results)
 
 			return nil
 		},
@@ -240,7 +362,147 @@ func newSimilarCommand() *cobra.Command {
 	cmd.Flags().IntVar(&limit, "limit", 10, "Maximum number of results")
 	cmd.Flags().Float32Var(&minScore, "score", 0.6, "Minimum similarity score (0-1)")
 	cmd.Flags().StringVar(&tags, "tags", "", "Filter by tags (comma-separated)")
-	cmd.Flags().StringVar(&pathPrefix, "path", "", "Filter by path prefix")
+	cmd.Flags().StringVar(&pathPrefix, "folder", "", "Filter by folder, matching whole folder names, or by note path")
+	cmd.Flags().StringVar(&pathPrefix, "path", "", "Filter by folder")
+	cmd.Flags().MarkDeprecated("path", "use --folder, which matches whole folder names rather than any path prefix")
+	cmd.Flags().StringVar(&strategy, "strategy", string(indexer.SimilarMax), "How the note's chunks are compared: max, centroid or section")
+	cmd.Flags().StringVar(&groupBy, "group-by", string(indexer.GroupByDocument), "Group results by document or list every chunk")
+	cmd.Flags().IntVar(&groupSize, "group-size", indexer.DefaultGroupSize, "Number of sections shown per document")
+
+	return cmd
+}
+
+// newAskCommand creates the ask command
+func newAskCommand() *cobra.Command {
+	var limit int
+	var tags string
+	var pathPrefix string
+	var mode string
+	var where []string
+
+	cmd := &cobra.Command{
+		Use:   "ask [question]",
+		Short: "Answer a question from your notes",
+		Long: `Answer a question from your notes with a local Ollama chat model.
+
+The best matching passages are retrieved like in search and given to the model,
+which answers from them only and cites them by number. The answer is printed as
+it is generated, followed by the cited notes with their line ranges.
+
+The question may contain the same filters as search, e.g.
+  obsfind ask "what did we decide about the auth migration? path:meetings/"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			question := args[0]
+
+			searchMode, err := indexer.ParseSearchMode(mode)
+			if err != nil {
+				return err
+			}
+
+			// Report malformed questions without a round trip to the daemon
+			if _, err := query2.Parse(question); err != nil {
+				return err
+			}
+			for _, clause := range where {
+				if _, err := query2.ParseWhere(clause); err != nil {
+					return err
+				}
+			}
+
+			client, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			req := &api2.AskRequest{
+				Question:   question,
+				Limit:      limit,
+				Tags:       splitTags(tags),
+				PathPrefix: pathPrefix,
+				Where:      where,
+				Mode:       string(searchMode),
+			}
+
+			response, err := client.Ask(cmd.Context(), req, nil, func(token string) {
+				fmt.Print(token)
+			})
+			if err != nil {
+				return fmt.Errorf("ask failed: %w", err)
+			}
+			fmt.Println()
+
+			displayCitations(response.Sources)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 0, "Number of passages given to the model (default from ask.context_chunks)")
+	cmd.Flags().StringVar(&tags, "tags", "", "Filter by tags (comma-separated)")
+	cmd.Flags().StringVar(&pathPrefix, "folder", "", "Filter by folder, matching whole folder names, or by note path")
+	cmd.Flags().StringVar(&pathPrefix, "path", "", "Filter by folder")
+	cmd.Flags().MarkDeprecated("path", "use --folder, which matches whole folder names rather than any path prefix")
+	cmd.Flags().StringVar(&mode, "mode", string(indexer.SearchModeHybrid), "Retrieval mode: semantic, keyword or hybrid")
+	cmd.Flags().StringArrayVar(&where, "where", nil, "Filter by frontmatter, e.g. status=active (repeatable)")
+
+	return cmd
+}
+
+// displayCitations lists the passages an answer cites, or all retrieved ones if it cites none
+func displayCitations(sources []api2.Citation) {
+	var cited []api2.Citation
+	for _, source := range sources {
+		if source.Cited {
+			cited = append(cited, source)
+		}
+	}
+	if len(cited) == 0 {
+		cited = sources
+	}
+	if len(cited) == 0 {
+		return
+	}
+
+	fmt.Println("\nSources:")
+	for _, source := range cited {
+		location := source.Path
+		if source.StartLine > 0 && source.EndLine > source.StartLine {
+			location = fmt.Sprintf("%s:%d-%d", source.Path, source.StartLine, source.EndLine)
+		} else if source.StartLine > 0 {
+			location = fmt.Sprintf("%s:%d", source.Path, source.StartLine)
+		}
+		if source.Section != "" {
+			location += " (" + source.Section + ")"
+		}
+		fmt.Printf("  [%d] %s\n", source.Number, location)
+	}
+}
+
+// newMCPCommand creates the mcp command
+func newMCPCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve vault search to local agents over the Model Context Protocol",
+		Long: `Serve vault search to local agents over the Model Context Protocol (MCP) on stdio.
+
+Agents get the tools search_notes, find_similar, read_note and list_tags, all
+answered by the running daemon. Register the command with your agent, e.g.
+
+  {"mcpServers": {"obsfind": {"command": "obsfind", "args": ["mcp"]}}}`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Stdout carries the protocol, anything else printed has to go to stderr
+			out := os.Stdout
+			os.Stdout = os.Stderr
+
+			client, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			return mcp.NewServer(client, version).Serve(cmd.Context(), os.Stdin, out)
+		},
+	}
 
 	return cmd
 }
@@ -318,6 +580,27 @@ func newStatusCommand() *cobra.Command {
 					Value:  strconv.Itoa(status.IndexStats.FailedDocuments),
 					Status: getStatusForFailedDocs(status.IndexStats.FailedDocuments),
 				},
+				"skipped": {
+					Label:  "Unchanged Documents",
+					Value:  strconv.Itoa(status.IndexStats.SkippedDocuments),
+					Status: consoleutil2.StatusActive,
+				},
+			}
+
+			if status.IndexStats.QueuedJobs > 0 {
+				indexItems["queued"] = consoleutil2.StatusRow{
+					Label:  "Queued Jobs",
+					Value:  strconv.Itoa(status.IndexStats.QueuedJobs),
+					Status: consoleutil2.StatusPending,
+				}
+			}
+
+			if status.IndexStats.Cancelled {
+				indexItems["cancelled"] = consoleutil2.StatusRow{
+					Label:  "Last Run",
+					Value:  "Cancelled, counts are partial",
+					Status: consoleutil2.StatusPending,
+				}
 			}
 
 			// Add indexing progress bar if currently indexing
@@ -398,6 +681,10 @@ func getStatusForFailedDocs(failedCount int) consoleutil2.Status {
 
 // newReindexCommand creates the reindex command
 func newReindexCommand() *cobra.Command {
+	var force bool
+	var follow bool
+	var cancelRun bool
+
 	cmd := &cobra.Command{
 		Use:   "reindex",
 		Short: "Reindex vault contents",
@@ -414,23 +701,309 @@ func newReindexCommand() *cobra.Command {
 				return fmt.Errorf("daemon is not running or not responding. Start the daemon with 'obsfind start' before using this command")
 			}
 
+			if cancelRun {
+				cancelled, err := client.CancelIndexing(cmd.Context())
+				if err != nil {
+					return fmt.Errorf("failed to cancel reindexing: %w", err)
+				}
+				if !cancelled {
+					fmt.Println("No reindexing in progress.")
+					return nil
+				}
+				fmt.Println("Reindexing is being cancelled, it stops before the next note.")
+				return nil
+			}
+
 			fmt.Println("Starting reindexing of vault content...")
 
-			// Execute reindexing
-			if err := client.Reindex(cmd.Context(), false); err != nil {
+			if !follow {
+				// Execute reindexing
+				if err := client.Reindex(cmd.Context(), force); err != nil {
+					return fmt.Errorf("reindexing failed: %w", err)
+				}
+
+				fmt.Println("Reindexing started successfully.")
+				fmt.Println("Use 'obsfind status' to check progress.")
+
+				return nil
+			}
+
+			// Listen before starting so no event of the run is missed
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			events, errs, err := client.IndexEvents(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to follow indexing: %w", err)
+			}
+
+			// Ctrl+C cancels the run on the daemon instead of leaving it running unseen
+			interrupts := make(chan os.Signal, 1)
+			signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(interrupts)
+
+			if err := client.Reindex(ctx, force); err != nil {
 				return fmt.Errorf("reindexing failed: %w", err)
 			}
 
-			fmt.Println("Reindexing started successfully.")
-			fmt.Println("Use 'obsfind status' to check progress.")
+			return followIndexing(events, errs, interrupts, func() error {
+				_, err := client.CancelIndexing(context.Background())
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Drop the index and re-embed every note, even unchanged ones")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Show live progress until reindexing completes, Ctrl+C cancels it")
+	cmd.Flags().BoolVar(&cancelRun, "cancel", false, "Cancel the running reindex, notes indexed so far are kept")
+	cmd.MarkFlagsMutuallyExclusive("cancel", "force")
+	cmd.MarkFlagsMutuallyExclusive("cancel", "follow")
+
+	return cmd
+}
+
+// followIndexing renders indexing events as a live progress line until the run completes.
+// Failed notes are listed above the progress line as they happen. The first interrupt
+// cancels the run through cancelRun and waits for its partial results, a second one quits.
+func followIndexing(events <-chan indexer.IndexEvent, errs <-chan error, interrupts <-chan os.Signal, cancelRun func() error) error {
+	fmt.Print("Waiting for the indexer...")
+
+	cancelling := false
+	for {
+		select {
+		case <-interrupts:
+			if cancelling {
+				fmt.Println()
+				return fmt.Errorf("interrupted, reindexing is still being cancelled by the daemon")
+			}
+			cancelling = true
+
+			fmt.Print("\r\033[KCancelling reindexing, press Ctrl+C again to stop waiting...\n")
+			if err := cancelRun(); err != nil {
+				return fmt.Errorf("failed to cancel reindexing: %w", err)
+			}
+
+		case event, ok := <-events:
+			if !ok {
+				fmt.Println()
+				if err := <-errs; err != nil {
+					return fmt.Errorf("lost connection to the daemon: %w", err)
+				}
+				return nil
+			}
+
+			if event.Type == indexer.EventFileFailed {
+				fmt.Printf("\r\033[K%s %s: %s\n", consoleutil2.ColorText("✗", consoleutil2.FgRed), event.Path, event.Error)
+			}
+
+			if event.Type != indexer.EventCompleted {
+				fmt.Printf("\r\033[K%s", indexProgressLine(event))
+				continue
+			}
+
+			fmt.Printf("\r\033[K%s\n", indexProgressLine(event))
+			if event.Error != "" {
+				return fmt.Errorf("reindexing failed: %s", event.Error)
+			}
+
+			outcome := "completed"
+			if event.Cancelled {
+				outcome = "cancelled"
+			}
+			elapsed := event.Time.Sub(event.StartTime).Round(time.Second)
+			fmt.Printf("Reindexing %s after %s: %d indexed, %d unchanged, %d failed, %d chunks.\n",
+				outcome, elapsed, event.Indexed, event.Skipped, event.Failed, event.TotalChunks)
+			if event.Cancelled {
+				fmt.Printf("%d of %d notes were processed, the rest is indexed on the next run.\n", event.Processed, event.Total)
+			}
+			return nil
+		}
+	}
+}
+
+// indexProgressLine formats the progress bar, counters, ETA and current note of an event
+func indexProgressLine(event indexer.IndexEvent) string {
+	percent := 0
+	if event.Total > 0 {
+		percent = event.Processed * 100 / event.Total
+	}
+
+	line := fmt.Sprintf("%s %d/%d notes, %d chunks",
+		consoleutil2.ProgressBar(percent, 30), event.Processed, event.Total, event.TotalChunks)
+
+	if event.ETASeconds > 0 {
+		eta := time.Duration(event.ETASeconds * float64(time.Second)).Round(time.Second)
+		line += fmt.Sprintf(", ETA %s", eta)
+	}
+	if event.Type == indexer.EventFileStarted && event.Path != "" {
+		line += "  " + consoleutil2.ColorText(filepath.Base(event.Path), consoleutil2.Dim)
+	}
+	return line
+}
+
+// newGCCommand creates the gc command that purges points of deleted notes
+func newGCCommand() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove index entries for notes that no longer exist",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Create API client
+			client, err := getClient()
+			if err != nil {
+				return err
+			}
+
+			// Check daemon health
+			healthy, err := client.Health(cmd.Context())
+			if err != nil || !healthy {
+				return fmt.Errorf("daemon is not running or not responding. Start the daemon with 'obsfind start' before using this command")
+			}
+
+			result, err := client.GarbageCollect(cmd.Context(), dryRun)
+			if err != nil {
+				return fmt.Errorf("garbage collection failed: %w", err)
+			}
+
+			if len(result.RemovedFiles) == 0 {
+				fmt.Printf("Scanned %d points, nothing to remove.\n", result.ScannedPoints)
+				return nil
+			}
+
+			verb := "Removed"
+			if result.DryRun {
+				verb = "Would remove"
+			}
+
+			for _, path := range result.RemovedFiles {
+				fmt.Printf("  %s\n", path)
+			}
+			fmt.Printf("%s %d points from %d deleted notes (scanned %d points).\n",
+				verb, result.RemovedPoints, len(result.RemovedFiles), result.ScannedPoints)
 
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report what would be removed")
+
 	return cmd
 }
 
+// newLinksCommand creates the links command listing the outgoing links of a note
+func newLinksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "links [note]",
+		Short: "List the links of a note to other notes",
+		Long: `List the wikilinks, embeds and markdown links of a note with the notes they
+resolve to. The note is a path on disk, a path relative to the vault or just
+its name, like in a wikilink.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			links, err := fetchNoteLinks(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(links.Links) == 0 {
+				fmt.Printf("%s has no links.\n", links.Path)
+				return nil
+			}
+
+			fmt.Printf("%d links in %s:\n\n", len(links.Links), links.Path)
+			for _, link := range links.Links {
+				target := consoleutil2.Format("(unresolved)", consoleutil2.FgYellow)
+				if link.Resolved != "" {
+					target = link.Resolved
+				}
+				fmt.Printf("  %4d  %s -> %s\n", link.Line, formatLink(link), target)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// newBacklinksCommand creates the backlinks command listing the notes linking to a note
+func newBacklinksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backlinks [note]",
+		Short: "List the notes linking to a note",
+		Long: `List the links from other notes to a note. The note is a path on disk, a
+path relative to the vault or just its name, like in a wikilink.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			links, err := fetchNoteLinks(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(links.Backlinks) == 0 {
+				fmt.Printf("No notes link to %s.\n", links.Path)
+				return nil
+			}
+
+			fmt.Printf("%d backlinks to %s:\n\n", len(links.Backlinks), links.Path)
+			for _, backlink := range links.Backlinks {
+				location := fmt.Sprintf("%s:%d", backlink.Source, backlink.Line)
+				fmt.Printf("  %s  %s\n", consoleutil2.Format(location, consoleutil2.Bold), formatLink(backlink.Link))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// fetchNoteLinks asks the daemon for the links of a note in both directions
+func fetchNoteLinks(ctx context.Context, note string) (*indexer.NoteLinks, error) {
+	// Files on disk are sent with their absolute path, anything else is resolved by the daemon
+	if _, err := os.Stat(note); err == nil {
+		if absPath, err := filepath.Abs(note); err == nil {
+			note = absPath
+		}
+	}
+
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := client.NoteLinks(ctx, note)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	return links, nil
+}
+
+// formatLink writes a link the way it appears in the note
+func formatLink(link markdown.Link) string {
+	target := link.Target
+	if link.Heading != "" {
+		target += "#" + link.Heading
+	}
+	if link.Block != "" {
+		target += "#^" + link.Block
+	}
+
+	prefix := ""
+	if link.Embed {
+		prefix = "!"
+	}
+
+	if link.Markdown {
+		return fmt.Sprintf("%s[%s](%s)", prefix, link.Alias, target)
+	}
+	if link.Alias != "" {
+		target += "|" + link.Alias
+	}
+	return fmt.Sprintf("%s[[%s]]", prefix, target)
+}
+
 // newStartCommand creates the start command for the daemon
 func newStartCommand() *cobra.Command {
 	var foreground bool
@@ -578,40 +1151,86 @@ func splitTags(tags string) []string {
 	return result
 }
 
-// convertToAPIResults converts indexer search results to API search results
-func convertToAPIResults(results []indexer.SearchResult) []api2.SearchResult {
-	apiResults := make([]api2.SearchResult, len(results))
-	for i, r := range results {
-		apiResults[i] = api2.SearchResult{
-			ID:       fmt.Sprintf("result-%d", i+1),
-			Path:     r.Path,
-			Title:    r.Title,
-			Score:    float32(r.Score),
-			Tags:     r.Tags,
-			Section:  r.Section,
-			Metadata: r.Metadata,
-			Excerpt:  r.Content,
-		}
-	}
-	return apiResults
-}
-
 // displaySearchResults formats and displays search results
 func displaySearchResults(results []api2.SearchResult) {
 	for i, result := range results {
 		fmt.Printf("%d. [%.2f] %s\n", i+1, result.Score, result.Title)
-		fmt.Printf("   Path: %s\n", result.Path)
-		if result.Section != "" {
-			fmt.Printf("   Section: %s\n", result.Section)
+		fmt.Printf("   Path: %s\n", resultLocation(result))
+		if source, ok := result.Metadata["source_section"].(string); ok && source != "" {
+			fmt.Printf("   Similar to: %s\n", source)
+		}
+		if linkedFrom, ok := result.Metadata["linked_from"].(string); ok && linkedFrom != "" {
+			fmt.Printf("   Linked from: %s\n", linkedFrom)
+		}
+		if host, ok := result.Metadata["embedded_in"].(string); ok && host != "" {
+			fmt.Printf("   Embedded in: %s\n", host)
+		}
+		if embeds, ok := result.Metadata["embeds"].([]interface{}); ok && len(embeds) > 0 {
+			fmt.Printf("   Embeds: %v\n", embeds)
+		}
+		if result.Metadata["block_type"] == "code" {
+			if language, _ := result.Metadata["language"].(string); language != "" {
+				fmt.Printf("   Code block: %s\n", language)
+			} else {
+				fmt.Println("   Code block")
+			}
 		}
 		if len(result.Tags) > 0 {
 			fmt.Printf("   Tags: %v\n", result.Tags)
 		}
-		fmt.Printf("   Content: %s\n", truncateString(result.Content, 80))
+
+		// Grouped results list their best sections instead of a single chunk
+		if len(result.Sections) > 0 {
+			for _, section := range result.Sections {
+				name := section.Section
+				if name == "" {
+					name = "(top)"
+				}
+				if section.Line > 0 {
+					name = fmt.Sprintf("%s (line %d)", name, section.Line)
+				}
+				fmt.Printf("   - [%.2f] %s: %s\n", section.Score, name, formatExcerpt(section))
+			}
+			fmt.Println()
+			continue
+		}
+
+		if result.Section != "" {
+			fmt.Printf("   Section: %s\n", result.Section)
+		}
+		fmt.Printf("   Excerpt: %s\n", formatExcerpt(result))
 		fmt.Println()
 	}
 }
 
+// resultLocation returns the path of a result with the line its excerpt starts on
+func resultLocation(result api2.SearchResult) string {
+	if result.Line > 0 {
+		return fmt.Sprintf("%s:%d", result.Path, result.Line)
+	}
+	return result.Path
+}
+
+// formatExcerpt returns the excerpt of a result with the query matches highlighted
+func formatExcerpt(result api2.SearchResult) string {
+	if result.Excerpt == "" {
+		return truncateString(result.Content, 80)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, h := range result.Highlights {
+		if h.Start < last || h.End > len(result.Excerpt) || h.Start >= h.End {
+			continue
+		}
+		b.WriteString(result.Excerpt[last:h.Start])
+		b.WriteString(consoleutil2.Format(result.Excerpt[h.Start:h.End], consoleutil2.Bold, consoleutil2.FgYellow))
+		last = h.End
+	}
+	b.WriteString(result.Excerpt[last:])
+	return b.String()
+}
+
 // truncateString truncates a string to maxLength and adds "..." if needed
 func truncateString(s string, maxLength int) string {
 	if len(s) <= maxLength {
@@ -724,6 +1343,10 @@ func newConfigViewCommand() *cobra.Command {
 			fmt.Println("\nQdrant Vector Database:")
 			fmt.Printf("Embedded: %v\n", cfg.Qdrant.Embedded)
 			if cfg.Qdrant.Embedded {
+				if cfg.Qdrant.BinaryPath != "" {
+					fmt.Printf("Binary Path: %s\n", cfg.Qdrant.BinaryPath)
+					fmt.Printf("Port: %d\n", cfg.Qdrant.Port)
+				}
 				fmt.Printf("Data Path: %s\n", cfg.Qdrant.DataPath)
 			} else {
 				fmt.Printf("Host: %s\n", cfg.Qdrant.Host)
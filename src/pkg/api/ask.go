@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	indexer2 "obsfind/src/pkg/indexer"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoChatModel is returned by Ask when no chat model is configured
+var ErrNoChatModel = errors.New("question answering unavailable: no chat model configured")
+
+// maxAskContext caps the bytes of note text given to the chat model
+const maxAskContext = 16000
+
+// noMatchesAnswer is the answer when no passage matches the question, the model isn't asked
+const noMatchesAnswer = "No notes matching the question were found."
+
+// askSystemPrompt tells the chat model to stick to the passages and cite them
+const askSystemPrompt = `You answer questions about the user's notes.
+Use only the numbered note passages you are given, not your own knowledge.
+Cite the passages an answer is based on by their number in brackets, like [2] or [1][3].
+If the passages don't answer the question, say so briefly.
+Answer concisely, in the language of the question.`
+
+// citationPattern matches citations like [2] and [1, 3] in an answer
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Ask answers a question from the notes. The best matching chunks are retrieved
+// with the indexer, numbered and given to the chat model, which is asked to cite
+// them. onSources receives the retrieved passages before the model starts and
+// onToken every piece of the answer as it is generated; both may be nil.
+// The returned sources are marked Cited if the answer refers to them.
+func (s *Service) Ask(ctx context.Context, question, filter string, options indexer2.SearchOptions,
+	onSources func([]Citation) error, onToken func(string) error) (*AskResponse, error) {
+	if s.chat == nil {
+		return nil, ErrNoChatModel
+	}
+
+	input, parsed, err := parseQuery(question, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Limit <= 0 {
+		options.Limit = s.config.Ask.ContextChunks
+	}
+	options.GroupBy = indexer2.GroupByChunk
+
+	log.Info().
+		Str("question", input).
+		Int("limit", options.Limit).
+		Str("model", s.chat.Name()).
+		Msg("Answering question")
+
+	results, err := s.indexer.Search(ctx, input, options)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	sources, passages := askContext(results)
+	if onSources != nil {
+		if err := onSources(sources); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &AskResponse{
+		Model:   s.chat.Name(),
+		Sources: sources,
+	}
+
+	if len(sources) == 0 {
+		response.Answer = noMatchesAnswer
+		if onToken != nil {
+			if err := onToken(noMatchesAnswer); err != nil {
+				return nil, err
+			}
+		}
+		return response, nil
+	}
+
+	message := fmt.Sprintf("Note passages:\n\n%s\nQuestion: %s", passages, parsed.Text())
+	answer, err := s.chat.Chat(ctx, askSystemPrompt, message, onToken)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Answer = strings.TrimSpace(answer)
+	markCited(response.Sources, response.Answer)
+
+	log.Info().
+		Int("sources", len(sources)).
+		Int("answerLength", len(response.Answer)).
+		Msg("Question answered")
+
+	return response, nil
+}
+
+// askContext numbers the results as citations and formats their text for the prompt,
+// leaving out the ones that no longer fit
+func askContext(results []indexer2.SearchResult) ([]Citation, string) {
+	sources := make([]Citation, 0, len(results))
+	var b strings.Builder
+
+	for _, r := range results {
+		text := strings.TrimSpace(r.Text)
+		if text == "" {
+			text = strings.TrimSpace(r.Content)
+		}
+		if text == "" {
+			continue
+		}
+
+		citation := Citation{
+			Number:    len(sources) + 1,
+			Path:      r.Path,
+			Title:     r.Title,
+			Section:   r.Section,
+			StartLine: r.StartLine,
+			EndLine:   r.EndLine,
+			Score:     float32(r.Score),
+		}
+
+		header := fmt.Sprintf("[%d] %s\n", citation.Number, citationLabel(citation))
+		if b.Len() > 0 && b.Len()+len(header)+len(text) > maxAskContext {
+			break
+		}
+
+		b.WriteString(header)
+		b.WriteString(text)
+		b.WriteString("\n\n")
+		sources = append(sources, citation)
+	}
+
+	return sources, b.String()
+}
+
+// citationLabel describes where a passage comes from, e.g. "notes/a.md, Intro, lines 3-9"
+func citationLabel(c Citation) string {
+	parts := []string{c.Path}
+	if c.Section != "" {
+		parts = append(parts, c.Section)
+	}
+	if c.StartLine > 0 {
+		if c.EndLine > c.StartLine {
+			parts = append(parts, fmt.Sprintf("lines %d-%d", c.StartLine, c.EndLine))
+		} else {
+			parts = append(parts, fmt.Sprintf("line %d", c.StartLine))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markCited marks the sources the answer refers to by number
+func markCited(sources []Citation, answer string) {
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, number := range strings.Split(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(number))
+			if err == nil && n >= 1 && n <= len(sources) {
+				sources[n-1].Cited = true
+			}
+		}
+	}
+}
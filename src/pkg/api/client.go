@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 	httputil2 "obsfind/src/pkg/httputil"
@@ -58,7 +59,7 @@ func (c *Client) Health(ctx context.Context) (bool, error) {
 }
 
 // Search performs a semantic search
-func (c *Client) Search(ctx context.Context, req *SearchRequest) ([]indexer.SearchResult, error) {
+func (c *Client) Search(ctx context.Context, req *SearchRequest) ([]SearchResult, error) {
 	logger := loggingutil.Get(ctx)
 	logger.Debug("Performing semantic search",
 		"query", req.Query,
@@ -84,9 +85,30 @@ func (c *Client) Search(ctx context.Context, req *SearchRequest) ([]indexer.Sear
 	if req.PathPrefix != "" {
 		values.Set("path_prefix", req.PathPrefix)
 	}
+	if req.Mode != "" {
+		values.Set("mode", req.Mode)
+	}
+	for _, where := range req.Where {
+		values.Add("where", where)
+	}
+	if req.GroupBy != "" {
+		values.Set("group_by", req.GroupBy)
+	}
+	if req.GroupSize > 0 {
+		values.Set("group_size", strconv.Itoa(req.GroupSize))
+	}
+	if req.Diversity > 0 {
+		values.Set("diversity", strconv.FormatFloat(float64(req.Diversity), 'f', 4, 32))
+	}
+	if req.GraphBoost > 0 {
+		values.Set("graph_boost", strconv.FormatFloat(float64(req.GraphBoost), 'f', 4, 32))
+	}
+	if req.ExpandLinks {
+		values.Set("expand_links", "true")
+	}
 
 	// Get results directly using the GetJSON helper
-	results, err := httputil2.GetJSON[[]indexer.SearchResult](ctx, c.httpClient, c.baseURL, "/api/v1/search/query", values)
+	results, err := httputil2.GetJSON[[]SearchResult](ctx, c.httpClient, c.baseURL, "/api/v1/search/query", values)
 	if err != nil {
 		logger.Error("Search request failed", "error", err)
 		return nil, err
@@ -97,13 +119,13 @@ func (c *Client) Search(ctx context.Context, req *SearchRequest) ([]indexer.Sear
 }
 
 // Similar finds documents similar to the specified file
-func (c *Client) Similar(ctx context.Context, req *SimilarRequest) ([]indexer.SearchResult, error) {
+func (c *Client) Similar(ctx context.Context, req *SimilarRequest) ([]SearchResult, error) {
 	logger := loggingutil.Get(ctx)
 	logger.Debug("Finding similar documents",
 		"path", req.Path,
 		"limit", req.Limit)
 
-	results, err := httputil2.PostJSON[[]indexer.SearchResult](ctx, c.httpClient, c.baseURL, "/api/v1/search/similar", req)
+	results, err := httputil2.PostJSON[[]SearchResult](ctx, c.httpClient, c.baseURL, "/api/v1/search/similar", req)
 	if err != nil {
 		logger.Error("Similar search request failed", "error", err)
 		return nil, err
@@ -113,6 +135,138 @@ func (c *Client) Similar(ctx context.Context, req *SimilarRequest) ([]indexer.Se
 	return results, nil
 }
 
+// Ask answers a question from the vault. If onToken is set the answer is streamed
+// and passed on piece by piece, onSources receives the retrieved passages first.
+func (c *Client) Ask(ctx context.Context, req *AskRequest, onSources func([]Citation), onToken func(string)) (*AskResponse, error) {
+	logger := loggingutil.Get(ctx)
+	logger.Debug("Asking question",
+		"question", req.Question,
+		"limit", req.Limit,
+		"stream", onToken != nil)
+
+	if onToken == nil {
+		response, err := httputil2.PostJSON[AskResponse](ctx, c.httpClient, c.baseURL, "/api/v1/ask", req)
+		if err != nil {
+			logger.Error("Ask request failed", "error", err)
+			return nil, err
+		}
+		if onSources != nil {
+			onSources(response.Sources)
+		}
+		return &response, nil
+	}
+
+	streamed := *req
+	streamed.Stream = true
+
+	resp := httputil2.Post(ctx, c.httpClient, c.baseURL, "/api/v1/ask", &streamed).CheckStatus()
+	if resp.Error() != nil {
+		logger.Error("Ask request failed", "error", resp.Error())
+		if resp.Response != nil {
+			httputil2.CloseBodyWithContext(ctx, resp.Response)
+		}
+		return nil, resp.Error()
+	}
+	defer httputil2.CloseBodyWithContext(ctx, resp.Response)
+
+	var response *AskResponse
+	err := httputil2.ReadEvents(resp.Body, func(event httputil2.Event) error {
+		switch event.Name {
+		case "sources":
+			var sources []Citation
+			if err := event.Decode(&sources); err != nil {
+				return err
+			}
+			if onSources != nil {
+				onSources(sources)
+			}
+		case "token":
+			var token struct {
+				Text string `json:"text"`
+			}
+			if err := event.Decode(&token); err != nil {
+				return err
+			}
+			onToken(token.Text)
+		case "done":
+			response = &AskResponse{}
+			return event.Decode(response)
+		case "error":
+			var failure httputil2.ErrorResponse
+			if err := event.Decode(&failure); err != nil {
+				return err
+			}
+			return fmt.Errorf("ask failed: %s", failure.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Ask stream failed", "error", err)
+		return nil, err
+	}
+
+	if response == nil {
+		return nil, fmt.Errorf("answer stream ended unexpectedly")
+	}
+
+	logger.Debug("Question answered", "sources", len(response.Sources))
+	return response, nil
+}
+
+// ReadNote returns the lines startLine to endLine of a note, zero selects the whole note
+func (c *Client) ReadNote(ctx context.Context, path string, startLine, endLine int) (*NoteResponse, error) {
+	logger := loggingutil.Get(ctx)
+	logger.Debug("Reading note", "path", path, "startLine", startLine, "endLine", endLine)
+
+	values := url.Values{}
+	values.Set("path", path)
+	if startLine > 0 {
+		values.Set("start_line", strconv.Itoa(startLine))
+	}
+	if endLine > 0 {
+		values.Set("end_line", strconv.Itoa(endLine))
+	}
+
+	note, err := httputil2.GetJSON[NoteResponse](ctx, c.httpClient, c.baseURL, "/api/v1/notes", values)
+	if err != nil {
+		logger.Error("Read note request failed", "error", err, "path", path)
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+// ListTags returns the tags of the indexed notes, most used first
+func (c *Client) ListTags(ctx context.Context) ([]indexer.TagCount, error) {
+	logger := loggingutil.Get(ctx)
+	logger.Debug("Listing tags")
+
+	tags, err := httputil2.GetJSON[[]indexer.TagCount](ctx, c.httpClient, c.baseURL, "/api/v1/tags", nil)
+	if err != nil {
+		logger.Error("List tags request failed", "error", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// NoteLinks returns the outgoing links and the backlinks of a note, given by its path or its name
+func (c *Client) NoteLinks(ctx context.Context, path string) (*indexer.NoteLinks, error) {
+	logger := loggingutil.Get(ctx)
+	logger.Debug("Listing note links", "path", path)
+
+	values := url.Values{}
+	values.Set("path", path)
+
+	links, err := httputil2.GetJSON[indexer.NoteLinks](ctx, c.httpClient, c.baseURL, "/api/v1/links", values)
+	if err != nil {
+		logger.Error("Note links request failed", "error", err, "path", path)
+		return nil, err
+	}
+
+	return &links, nil
+}
+
 // Reindex triggers a full reindexing of the vault
 func (c *Client) Reindex(ctx context.Context, force bool) error {
 	logger := loggingutil.Get(ctx)
@@ -139,20 +293,26 @@ func (c *Client) Reindex(ctx context.Context, force bool) error {
 	return nil
 }
 
-// CancelIndexing cancels an ongoing indexing operation
-func (c *Client) CancelIndexing(ctx context.Context) error {
+// CancelIndexing cancels an ongoing indexing operation and reports whether one was running.
+// The daemon stops the run before its next note, follow IndexEvents to see it complete.
+func (c *Client) CancelIndexing(ctx context.Context) (bool, error) {
 	logger := loggingutil.Get(ctx)
 	logger.Info("Canceling ongoing indexing operation")
 
-	resp := httputil2.DeleteTyped[struct{}](ctx, c.httpClient, c.baseURL, "/api/v1/index/all")
+	resp := httputil2.DeleteTyped[map[string]string](ctx, c.httpClient, c.baseURL, "/api/v1/index/all")
 	if resp.Error() != nil {
 		logger.Error("Cancel indexing request failed", "error", resp.Error())
-		return resp.Error()
+		return false, resp.Error()
 	}
 	defer httputil2.CloseBodyWithContext(ctx, resp.Response)
 
-	logger.Info("Indexing cancellation request successful")
-	return nil
+	result, err := resp.Data()
+	if err != nil {
+		return false, err
+	}
+
+	logger.Info("Indexing cancellation request successful", "status", result["status"])
+	return result["status"] == "cancelling", nil
 }
 
 // IndexFile indexes a specific file
@@ -176,6 +336,76 @@ func (c *Client) IndexFile(ctx context.Context, filePath string, force bool) err
 	return nil
 }
 
+// GarbageCollect removes index points of files that no longer exist
+func (c *Client) GarbageCollect(ctx context.Context, dryRun bool) (*indexer.GCResult, error) {
+	logger := loggingutil.Get(ctx)
+	logger.Info("Requesting index garbage collection", "dryRun", dryRun)
+
+	req := GCRequest{
+		DryRun: dryRun,
+	}
+
+	result, err := httputil2.PostJSON[indexer.GCResult](ctx, c.httpClient, c.baseURL, "/api/v1/index/gc", req)
+	if err != nil {
+		logger.Error("Garbage collection request failed", "error", err)
+		return nil, err
+	}
+
+	logger.Info("Garbage collection request successful",
+		"removedFiles", len(result.RemovedFiles),
+		"removedPoints", result.RemovedPoints)
+	return &result, nil
+}
+
+// IndexEvents connects to the daemon's stream of indexing progress events. It returns
+// once the stream is open, so a reindex requested afterwards is seen from its start.
+// The events channel is closed when the stream ends or ctx is done, the reason is then
+// available on the error channel, nil if ctx ended the stream.
+func (c *Client) IndexEvents(ctx context.Context) (<-chan indexer.IndexEvent, <-chan error, error) {
+	logger := loggingutil.Get(ctx)
+	logger.Debug("Following indexing events")
+
+	// The stream lasts as long as the caller wants, so it can't share the client timeout
+	streamClient := &http.Client{}
+
+	resp := httputil2.Get(ctx, streamClient, c.baseURL, "/api/v1/index/events", nil).CheckStatus()
+	if resp.Error() != nil {
+		logger.Error("Index events request failed", "error", resp.Error())
+		if resp.Response != nil {
+			httputil2.CloseBodyWithContext(ctx, resp.Response)
+		}
+		return nil, nil, resp.Error()
+	}
+
+	events := make(chan indexer.IndexEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+		defer httputil2.CloseBodyWithContext(ctx, resp.Response)
+
+		err := httputil2.ReadEvents(resp.Body, func(event httputil2.Event) error {
+			var indexEvent indexer.IndexEvent
+			if err := event.Decode(&indexEvent); err != nil {
+				return err
+			}
+			select {
+			case events <- indexEvent:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if ctx.Err() != nil {
+			err = nil
+		} else if err == nil {
+			err = fmt.Errorf("index event stream ended unexpectedly")
+		}
+		errs <- err
+	}()
+
+	return events, errs, nil
+}
+
 // GetIndexingStatus gets the current status of the indexing process
 func (c *Client) GetIndexingStatus(ctx context.Context) (*IndexingStatus, error) {
 	logger := loggingutil.Get(ctx)
@@ -0,0 +1,373 @@
+package api
+
+import (
+	"context"
+	model2 "obsfind/src/pkg/model"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// excerptLength is the maximum length of an excerpt in bytes
+const excerptLength = 160
+
+// embeddedSegments is how many sentences of a chunk are compared to the query by embedding
+const embeddedSegments = 4
+
+// maxEmbeddedCandidates caps the sentences embedded per search, which go to the best results first
+const maxEmbeddedCandidates = 32
+
+// similarityWeight weighs embedding similarity against term overlap when picking the excerpt
+const similarityWeight = 0.5
+
+// Highlight marks a query match inside an excerpt as byte offsets, End is exclusive
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// segment is a sentence or line of a chunk that may become the excerpt
+type segment struct {
+	start int // byte offset in the chunk text
+	end   int
+	line  int // line offset from the first non-blank line of the chunk
+	score float64
+}
+
+// addExcerpts picks the passage of every result, including grouped sections,
+// that best matches the query. Sentences are scored by the share of query terms
+// they contain and, if queryVector is set, by the embedding similarity of the
+// best candidates, so excerpts also work for purely semantic matches.
+func (s *Service) addExcerpts(ctx context.Context, results []SearchResult, query string, queryVector []float32) {
+	// Sections come before their group head, which is usually one of them
+	var all []*SearchResult
+	var collect func(results []SearchResult)
+	collect = func(results []SearchResult) {
+		for i := range results {
+			collect(results[i].Sections)
+			all = append(all, &results[i])
+		}
+	}
+	collect(results)
+
+	terms := queryTerms(query)
+
+	// Rank the sentences of every chunk by term overlap first. A chunk seen
+	// before, such as a group head repeating its best section, reuses its scores.
+	segments := make([][]segment, len(all))
+	scored := make(map[string]int)
+	var candidates []*segment
+	var texts []string
+	for i, result := range all {
+		text := result.text
+		if first, ok := scored[text]; ok {
+			segments[i] = segments[first]
+			continue
+		}
+		scored[text] = i
+
+		segments[i] = splitSegments(text)
+		for j := range segments[i] {
+			seg := &segments[i][j]
+			seg.score = termOverlap(text[seg.start:seg.end], terms)
+		}
+
+		if len(segments[i]) < 2 || len(candidates) >= maxEmbeddedCandidates {
+			continue
+		}
+
+		ranked := make([]*segment, len(segments[i]))
+		for j := range segments[i] {
+			ranked[j] = &segments[i][j]
+		}
+		sort.SliceStable(ranked, func(a, b int) bool {
+			return ranked[a].score > ranked[b].score
+		})
+		if len(ranked) > embeddedSegments {
+			ranked = ranked[:embeddedSegments]
+		}
+		if len(ranked) > maxEmbeddedCandidates-len(candidates) {
+			ranked = ranked[:maxEmbeddedCandidates-len(candidates)]
+		}
+		for _, seg := range ranked {
+			candidates = append(candidates, seg)
+			texts = append(texts, text[seg.start:seg.end])
+		}
+	}
+
+	// Then let the embeddings of the best candidates weigh in, in a single batch
+	// of distinct sentences
+	if len(queryVector) > 0 && len(texts) > 0 && s.embedder != nil {
+		var unique []string
+		index := make([]int, len(texts))
+		seen := make(map[string]int)
+		for i, text := range texts {
+			j, ok := seen[text]
+			if !ok {
+				j = len(unique)
+				seen[text] = j
+				unique = append(unique, text)
+			}
+			index[i] = j
+		}
+
+		vectors, err := s.embedder.EmbedBatch(ctx, unique)
+		if err != nil || len(vectors) != len(unique) {
+			log.Warn().Err(err).Msg("Failed to embed excerpt candidates, using term overlap only")
+		} else {
+			for i, seg := range candidates {
+				seg.score += similarityWeight * model2.CosineSimilarity(queryVector, vectors[index[i]])
+			}
+		}
+	}
+
+	for i, result := range all {
+		if len(segments[i]) == 0 {
+			result.Excerpt = truncateExcerpt(result.text)
+			continue
+		}
+
+		// Stable order prefers the earliest of equally good sentences
+		best := 0
+		for j := range segments[i] {
+			if segments[i][j].score > segments[i][best].score {
+				best = j
+			}
+		}
+
+		seg := segments[i][best]
+		result.Excerpt = excerptAround(result.text, segments[i][best:], terms)
+		result.Highlights = highlightTerms(result.Excerpt, terms)
+		if result.StartLine > 0 {
+			result.Line = result.StartLine + seg.line
+			// Inlined embeds make a chunk longer than its lines in the note
+			if result.EndLine >= result.StartLine && result.Line > result.EndLine {
+				result.Line = result.EndLine
+			}
+		}
+	}
+}
+
+// splitSegments splits text into sentences, never across lines
+func splitSegments(text string) []segment {
+	var segments []segment
+
+	line := -1
+	offset := 0
+	for _, raw := range strings.SplitAfter(text, "\n") {
+		lineStart := offset
+		offset += len(raw)
+
+		content := strings.TrimRight(raw, "\r\n")
+		if strings.TrimSpace(content) == "" {
+			if line >= 0 {
+				line++
+			}
+			continue
+		}
+		line++
+
+		start := 0
+		for i := 0; i < len(content); i++ {
+			c := content[i]
+			if (c == '.' || c == '!' || c == '?') && i+1 < len(content) && content[i+1] == ' ' {
+				segments = appendSegment(segments, content, lineStart, start, i+1, line)
+				start = i + 1
+			}
+		}
+		segments = appendSegment(segments, content, lineStart, start, len(content), line)
+	}
+
+	return segments
+}
+
+// appendSegment adds content[start:end] without surrounding space, unless it's blank
+func appendSegment(segments []segment, content string, lineStart, start, end, line int) []segment {
+	for start < end && (content[start] == ' ' || content[start] == '\t') {
+		start++
+	}
+	for end > start && (content[end-1] == ' ' || content[end-1] == '\t') {
+		end--
+	}
+	if start == end {
+		return segments
+	}
+	return append(segments, segment{start: lineStart + start, end: lineStart + end, line: line})
+}
+
+// queryTerms returns the distinct lowercase words of a query
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, span := range wordSpans(query) {
+		term := strings.ToLower(query[span.Start:span.End])
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// wordSpans returns the byte ranges of the words in text
+func wordSpans(text string) []Highlight {
+	var spans []Highlight
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			spans = append(spans, Highlight{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, Highlight{Start: start, End: len(text)})
+	}
+	return spans
+}
+
+// matchesTerm reports whether a lowercase word matches a query term.
+// Longer terms also match as a prefix, so "meeting" finds "meetings".
+func matchesTerm(word, term string) bool {
+	return word == term || len(term) >= 4 && strings.HasPrefix(word, term)
+}
+
+// matchesAnyTerm reports whether a lowercase word matches one of the query terms
+func matchesAnyTerm(word string, terms []string) bool {
+	for _, term := range terms {
+		if matchesTerm(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// termOverlap returns the share of query terms that occur in text
+func termOverlap(text string, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	found := make(map[string]bool)
+	for _, span := range wordSpans(text) {
+		word := strings.ToLower(text[span.Start:span.End])
+		for _, term := range terms {
+			if matchesTerm(word, term) {
+				found[term] = true
+			}
+		}
+	}
+	return float64(len(found)) / float64(len(terms))
+}
+
+// excerptAround builds an excerpt starting at the first segment and continuing
+// with the following ones while they fit. A sentence too long to fit is cut
+// around its first match instead.
+func excerptAround(text string, segments []segment, terms []string) string {
+	first := segments[0]
+
+	if first.end-first.start > excerptLength {
+		start := first.start
+		for _, span := range wordSpans(text[first.start:first.end]) {
+			if matchesAnyTerm(strings.ToLower(text[first.start+span.Start:first.start+span.End]), terms) {
+				start = first.start + span.Start - excerptLength/3
+				break
+			}
+		}
+		if start < first.start {
+			start = first.start
+		}
+		if start+excerptLength > first.end {
+			start = first.end - excerptLength
+		}
+
+		start = wordBoundary(text, start)
+		end := runeBoundary(text, start+excerptLength)
+
+		excerpt := flatten(text[start:end])
+		if start > first.start {
+			excerpt = "..." + excerpt
+		}
+		if end < first.end {
+			excerpt += "..."
+		}
+		return excerpt
+	}
+
+	end := first.end
+	for _, seg := range segments[1:] {
+		if seg.end-first.start > excerptLength {
+			break
+		}
+		end = seg.end
+	}
+
+	return flatten(text[first.start:end])
+}
+
+// truncateExcerpt shortens text to the excerpt length without splitting a character
+func truncateExcerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= excerptLength {
+		return flatten(text)
+	}
+	return flatten(text[:runeBoundary(text, excerptLength-3)]) + "..."
+}
+
+// runeBoundary moves a byte offset back to the start of the character it falls into
+func runeBoundary(text string, offset int) int {
+	if offset >= len(text) {
+		return len(text)
+	}
+	for offset > 0 && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
+
+// wordBoundary moves a byte offset forward to the start of the next word
+// unless it's already at one
+func wordBoundary(text string, offset int) int {
+	offset = runeBoundary(text, offset)
+	if offset == 0 {
+		return 0
+	}
+
+	prev, _ := utf8.DecodeLastRuneInString(text[:offset])
+	if unicode.IsSpace(prev) {
+		return offset
+	}
+
+	for i, r := range text[offset:] {
+		if unicode.IsSpace(r) {
+			return offset + i + utf8.RuneLen(r)
+		}
+	}
+	return offset
+}
+
+// flatten turns line breaks into spaces, keeping the byte length and so the highlight offsets
+func flatten(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// highlightTerms returns the ranges of the words in excerpt that match a query term
+func highlightTerms(excerpt string, terms []string) []Highlight {
+	var highlights []Highlight
+	for _, span := range wordSpans(excerpt) {
+		if matchesAnyTerm(strings.ToLower(excerpt[span.Start:span.End]), terms) {
+			highlights = append(highlights, span)
+		}
+	}
+	return highlights
+}
@@ -7,22 +7,73 @@ import (
 
 // SearchRequest represents a search query
 type SearchRequest struct {
-	Query      string   `json:"query"`
+	Query       string   `json:"query"`
+	Limit       int      `json:"limit,omitempty"`
+	Offset      int      `json:"offset,omitempty"`
+	MinScore    float32  `json:"min_score,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	PathPrefix  string   `json:"path_prefix,omitempty"`
+	Mode        string   `json:"mode,omitempty"`
+	Where       []string `json:"where,omitempty"`
+	GroupBy     string   `json:"group_by,omitempty"`
+	GroupSize   int      `json:"group_size,omitempty"`
+	Diversity   float32  `json:"diversity,omitempty"`
+	GraphBoost  float32  `json:"graph_boost,omitempty"`
+	ExpandLinks bool     `json:"expand_links,omitempty"`
+}
+
+// SimilarRequest represents a similar document query.
+// Path is relative to the vault or absolute.
+type SimilarRequest struct {
+	Path       string   `json:"path"`
 	Limit      int      `json:"limit,omitempty"`
 	Offset     int      `json:"offset,omitempty"`
 	MinScore   float32  `json:"min_score,omitempty"`
 	Tags       []string `json:"tags,omitempty"`
 	PathPrefix string   `json:"path_prefix,omitempty"`
+	Strategy   string   `json:"strategy,omitempty"`
+	GroupBy    string   `json:"group_by,omitempty"`
+	GroupSize  int      `json:"group_size,omitempty"`
 }
 
-// SimilarRequest represents a similar document query
-type SimilarRequest struct {
-	Path       string   `json:"path"`
+// AskRequest represents a question answered from the vault.
+// With Stream set the answer arrives as server-sent events.
+type AskRequest struct {
+	Question   string   `json:"question"`
 	Limit      int      `json:"limit,omitempty"`
-	Offset     int      `json:"offset,omitempty"`
-	MinScore   float32  `json:"min_score,omitempty"`
 	Tags       []string `json:"tags,omitempty"`
 	PathPrefix string   `json:"path_prefix,omitempty"`
+	Where      []string `json:"where,omitempty"`
+	Mode       string   `json:"mode,omitempty"`
+	Stream     bool     `json:"stream,omitempty"`
+}
+
+// Citation is a retrieved note passage an answer may refer to by its number
+type Citation struct {
+	Number    int     `json:"number"`
+	Path      string  `json:"path"`
+	Title     string  `json:"title,omitempty"`
+	Section   string  `json:"section,omitempty"`
+	StartLine int     `json:"start_line,omitempty"`
+	EndLine   int     `json:"end_line,omitempty"`
+	Score     float32 `json:"score"`
+	Cited     bool    `json:"cited"`
+}
+
+// AskResponse represents an answer and the passages it was based on
+type AskResponse struct {
+	Answer  string     `json:"answer"`
+	Model   string     `json:"model,omitempty"`
+	Sources []Citation `json:"sources"`
+}
+
+// NoteResponse represents the content of a note, or of a range of its lines
+type NoteResponse struct {
+	Path       string `json:"path"`
+	Content    string `json:"content"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	TotalLines int    `json:"total_lines"`
 }
 
 // StatusResponse represents the daemon status
@@ -41,6 +92,11 @@ type IndexFileRequest struct {
 	Force    bool   `json:"force,omitempty"`
 }
 
+// GCRequest represents a request to remove index points of deleted files
+type GCRequest struct {
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
 // IndexingStatus represents the current status of the indexing process
 type IndexingStatus struct {
 	IsIndexing        bool      `json:"is_indexing"`
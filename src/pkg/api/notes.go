@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	indexer2 "obsfind/src/pkg/indexer"
+	"os"
+	"strings"
+)
+
+// ErrInvalidLineRange is returned by ReadNote for line ranges outside the note
+var ErrInvalidLineRange = errors.New("invalid line range")
+
+// ReadNote returns the lines startLine to endLine of a note, both 1-based and
+// inclusive. Zero values select the first and last line.
+func (s *Service) ReadNote(ctx context.Context, path string, startLine, endLine int) (*NoteResponse, error) {
+	fullPath, err := s.indexer.ResolveNote(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read note: %w", err)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return &NoteResponse{Path: path}, nil
+	}
+
+	if startLine <= 0 {
+		startLine = 1
+	}
+	if endLine <= 0 || endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine > endLine {
+		return nil, fmt.Errorf("%w: %d-%d, the note has %d lines", ErrInvalidLineRange, startLine, endLine, len(lines))
+	}
+
+	return &NoteResponse{
+		Path:       path,
+		Content:    strings.Join(lines[startLine-1:endLine], ""),
+		StartLine:  startLine,
+		EndLine:    endLine,
+		TotalLines: len(lines),
+	}, nil
+}
+
+// ListTags returns the tags of the indexed notes with the number of notes carrying each
+func (s *Service) ListTags(ctx context.Context) ([]indexer2.TagCount, error) {
+	return s.indexer.ListTags(ctx)
+}
+
+// NoteLinks returns the outgoing links and the backlinks of a note, given by its path or its name
+func (s *Service) NoteLinks(ctx context.Context, path string) (*indexer2.NoteLinks, error) {
+	return s.indexer.NoteLinks(path)
+}
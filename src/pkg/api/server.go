@@ -2,11 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"obsfind/src/pkg/consts"
 	"obsfind/src/pkg/httputil"
+	"obsfind/src/pkg/indexer"
 	"obsfind/src/pkg/loggingutil"
+	query2 "obsfind/src/pkg/query"
 	"strings"
 	"time"
 )
@@ -92,6 +95,35 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc(consts.APIIndexFile, s.handleIndexFile)
 	s.router.HandleFunc(consts.APIIndexAll, s.handleIndexAll)
 	s.router.HandleFunc(consts.APIIndexStatus, s.handleIndexStatus)
+	s.router.HandleFunc(consts.APIIndexGC, s.handleIndexGC)
+	s.router.HandleFunc(consts.APIIndexEvents, s.handleIndexEvents)
+
+	// Question answering
+	s.router.HandleFunc(consts.APIAsk, s.handleAsk)
+
+	// Note endpoints
+	s.router.HandleFunc(consts.APINotes, s.handleReadNote)
+	s.router.HandleFunc(consts.APITags, s.handleListTags)
+	s.router.HandleFunc(consts.APILinks, s.handleNoteLinks)
+}
+
+// requestFilter builds a filter in query syntax from the filter fields of a request body
+func requestFilter(pathPrefix string, tags, where []string) (string, error) {
+	var filters []string
+	if pathPrefix != "" {
+		filters = append(filters, query2.Term(query2.FieldPath, pathPrefix))
+	}
+	if len(tags) > 0 {
+		filters = append(filters, query2.Term(query2.FieldTag, tags...))
+	}
+	for _, clause := range where {
+		whereFilter, err := query2.ParseWhere(clause)
+		if err != nil {
+			return "", err
+		}
+		filters = append(filters, whereFilter.String())
+	}
+	return strings.Join(filters, " "), nil
 }
 
 // ErrorResponse represents an error response
@@ -157,15 +189,92 @@ func (s *Server) handleSearchQuery(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		offset, err := httputil.ParseOffsetQueryParameter(r, consts.QueryParamOffset)
+		if err != nil {
+			logger.Warn("Invalid offset", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		minScore, err := httputil.ParseFloatQueryParameter(r, consts.QueryParamMinScore, 0, 0, 1)
+		if err != nil {
+			logger.Warn("Invalid minimum score", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mode, err := indexer.ParseSearchMode(r.URL.Query().Get(consts.QueryParamMode))
+		if err != nil {
+			logger.Warn("Invalid search mode", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		groupBy, err := indexer.ParseGroupBy(r.URL.Query().Get(consts.QueryParamGroupBy))
+		if err != nil {
+			logger.Warn("Invalid search grouping", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		groupSize, err := httputil.ParseIntQueryParameter(r, consts.QueryParamGroupSize, indexer.DefaultGroupSize)
+		if err != nil {
+			logger.Warn("Invalid group size", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		diversity, err := httputil.ParseFloatQueryParameter(r, consts.QueryParamDiversity, 0, 0, 1)
+		if err != nil {
+			logger.Warn("Invalid diversity", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		graphBoost, err := httputil.ParseFloatQueryParameter(r, consts.QueryParamGraphBoost, 0, 0, 1)
+		if err != nil {
+			logger.Warn("Invalid graph boost", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		expandLinks, err := httputil.ParseBoolQueryParameter(r, consts.QueryParamExpandLinks, false)
+		if err != nil {
+			logger.Warn("Invalid expand links", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		logger.Debug("GET search request",
 			"query", query,
 			"limit", limit,
+			"offset", offset,
 			"filter", filter,
+			"mode", mode,
+			"group_by", groupBy,
 			"remote_addr", r.RemoteAddr)
 
 		// Execute search
-		results, err := s.service.Search(ctx, query, limit, filter)
+		results, err := s.service.Search(ctx, query, filter, indexer.SearchOptions{
+			Limit:       limit,
+			Offset:      offset,
+			MinScore:    float32(minScore),
+			Mode:        mode,
+			GroupBy:     groupBy,
+			GroupSize:   groupSize,
+			Diversity:   float32(diversity),
+			GraphBoost:  float32(graphBoost),
+			ExpandLinks: expandLinks,
+		})
 		if err != nil {
+			// Malformed queries are the caller's fault
+			var queryErr *query2.Error
+			if errors.As(err, &queryErr) {
+				logger.Warn("Invalid search query", "error", err, "query", query)
+				httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
 			logger.Error("Search failed", "error", err, "query", query)
 			
 			// Handle embedding service errors with a more user-friendly message
@@ -185,12 +294,19 @@ func (s *Server) handleSearchQuery(w http.ResponseWriter, r *http.Request) {
 	} else if r.Method == http.MethodPost {
 		// Parse request body for POST
 		var request struct {
-			Query      string   `json:"query"`
-			Limit      int      `json:"limit,omitempty"`
-			Offset     int      `json:"offset,omitempty"`
-			MinScore   float32  `json:"min_score,omitempty"`
-			Tags       []string `json:"tags,omitempty"`
-			PathPrefix string   `json:"path_prefix,omitempty"`
+			Query       string   `json:"query"`
+			Limit       int      `json:"limit,omitempty"`
+			Offset      int      `json:"offset,omitempty"`
+			MinScore    float32  `json:"min_score,omitempty"`
+			Tags        []string `json:"tags,omitempty"`
+			PathPrefix  string   `json:"path_prefix,omitempty"`
+			Mode        string   `json:"mode,omitempty"`
+			Where       []string `json:"where,omitempty"`
+			GroupBy     string   `json:"group_by,omitempty"`
+			GroupSize   int      `json:"group_size,omitempty"`
+			Diversity   float32  `json:"diversity,omitempty"`
+			GraphBoost  float32  `json:"graph_boost,omitempty"`
+			ExpandLinks bool     `json:"expand_links,omitempty"`
 		}
 
 		if err := httputil.ParseJSONRequest(r, &request); err != nil {
@@ -209,23 +325,82 @@ func (s *Server) handleSearchQuery(w http.ResponseWriter, r *http.Request) {
 			request.Limit = consts.DefaultSearchLimit // Default limit
 		}
 
-		// Build the filter string from the POST data
-		var filter string
-		if request.PathPrefix != "" {
-			filter = consts.FilterPrefixPath + request.PathPrefix
-		} else if len(request.Tags) > 0 {
-			filter = consts.FilterPrefixTags + strings.Join(request.Tags, ",")
+		mode, err := indexer.ParseSearchMode(request.Mode)
+		if err != nil {
+			logger.Warn("Invalid search mode", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		groupBy, err := indexer.ParseGroupBy(request.GroupBy)
+		if err != nil {
+			logger.Warn("Invalid search grouping", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if request.Offset < 0 || request.GroupSize < 0 {
+			logger.Warn("Invalid paging parameters", "offset", request.Offset, "group_size", request.GroupSize, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, "offset and group_size must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		if request.MinScore < 0 || request.MinScore > 1 {
+			logger.Warn("Invalid minimum score", "min_score", request.MinScore, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, "invalid min_score parameter: expected a number between 0 and 1", http.StatusBadRequest)
+			return
+		}
+
+		if request.Diversity < 0 || request.Diversity > 1 {
+			logger.Warn("Invalid diversity", "diversity", request.Diversity, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, "invalid diversity parameter: expected a number between 0 and 1", http.StatusBadRequest)
+			return
+		}
+
+		if request.GraphBoost < 0 || request.GraphBoost > 1 {
+			logger.Warn("Invalid graph boost", "graph_boost", request.GraphBoost, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, "invalid graph_boost parameter: expected a number between 0 and 1", http.StatusBadRequest)
+			return
+		}
+
+		// Build the filter in query syntax from the POST data
+		filter, err := requestFilter(request.PathPrefix, request.Tags, request.Where)
+		if err != nil {
+			logger.Warn("Invalid where clause", "error", err, "remote_addr", r.RemoteAddr)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		logger.Debug("POST search request",
 			"query", request.Query,
 			"limit", request.Limit,
+			"offset", request.Offset,
 			"filter", filter,
+			"mode", mode,
+			"group_by", groupBy,
 			"remote_addr", r.RemoteAddr)
 
 		// Execute search
-		results, err := s.service.Search(ctx, request.Query, request.Limit, filter)
+		results, err := s.service.Search(ctx, request.Query, filter, indexer.SearchOptions{
+			Limit:       request.Limit,
+			Offset:      request.Offset,
+			MinScore:    request.MinScore,
+			Mode:        mode,
+			GroupBy:     groupBy,
+			GroupSize:   request.GroupSize,
+			Diversity:   request.Diversity,
+			GraphBoost:  request.GraphBoost,
+			ExpandLinks: request.ExpandLinks,
+		})
 		if err != nil {
+			// Malformed queries are the caller's fault
+			var queryErr *query2.Error
+			if errors.As(err, &queryErr) {
+				logger.Warn("Invalid search query", "error", err, "query", request.Query)
+				httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
 			logger.Error("Search failed", "error", err, "query", request.Query)
 			
 			// Handle embedding service errors with a more user-friendly message
@@ -257,10 +432,18 @@ func (s *Server) handleSearchSimilar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request
+	// Parse request, path is what api.Client sends and file_path the older name
 	var request struct {
-		FilePath string `json:"file_path"`
-		Limit    int    `json:"limit,omitempty"`
+		FilePath   string   `json:"file_path"`
+		Path       string   `json:"path"`
+		Limit      int      `json:"limit,omitempty"`
+		Offset     int      `json:"offset,omitempty"`
+		MinScore   float32  `json:"min_score,omitempty"`
+		Tags       []string `json:"tags,omitempty"`
+		PathPrefix string   `json:"path_prefix,omitempty"`
+		Strategy   string   `json:"strategy,omitempty"`
+		GroupBy    string   `json:"group_by,omitempty"`
+		GroupSize  int      `json:"group_size,omitempty"`
 	}
 
 	if err := httputil.ParseJSONRequest(r, &request); err != nil {
@@ -269,6 +452,10 @@ func (s *Server) handleSearchSimilar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if request.FilePath == "" {
+		request.FilePath = request.Path
+	}
+
 	if request.FilePath == "" {
 		logger.Warn("Missing file_path parameter", "remote_addr", r.RemoteAddr)
 		httputil.WriteError(w, "Missing file_path parameter", http.StatusBadRequest)
@@ -279,13 +466,44 @@ func (s *Server) handleSearchSimilar(w http.ResponseWriter, r *http.Request) {
 		request.Limit = consts.DefaultSearchLimit // Default limit
 	}
 
+	strategy, err := indexer.ParseSimilarStrategy(request.Strategy)
+	if err != nil {
+		logger.Warn("Invalid similarity strategy", "error", err, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groupBy, err := indexer.ParseGroupBy(request.GroupBy)
+	if err != nil {
+		logger.Warn("Invalid search grouping", "error", err, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Offset < 0 || request.GroupSize < 0 {
+		logger.Warn("Invalid paging parameters", "offset", request.Offset, "group_size", request.GroupSize, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, "offset and group_size must not be negative", http.StatusBadRequest)
+		return
+	}
+
 	logger.Debug("Similar search request",
 		"path", request.FilePath,
 		"limit", request.Limit,
+		"strategy", strategy,
+		"group_by", groupBy,
 		"remote_addr", r.RemoteAddr)
 
 	// Execute search
-	results, err := s.service.FindSimilar(ctx, request.FilePath, request.Limit)
+	results, err := s.service.FindSimilar(ctx, request.FilePath, indexer.SearchOptions{
+		Limit:      request.Limit,
+		Offset:     request.Offset,
+		MinScore:   request.MinScore,
+		Tags:       request.Tags,
+		PathPrefix: request.PathPrefix,
+		Strategy:   strategy,
+		GroupBy:    groupBy,
+		GroupSize:  request.GroupSize,
+	})
 	if err != nil {
 		logger.Error("Similar search failed", "error", err, "path", request.FilePath)
 		
@@ -357,13 +575,18 @@ func (s *Server) handleIndexFile(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteJSON(w, map[string]string{"status": "success"}, http.StatusOK)
 }
 
-// handleIndexAll handles full reindexing requests
+// handleIndexAll handles full reindexing requests, DELETE cancels a running reindex
 func (s *Server) handleIndexAll(w http.ResponseWriter, r *http.Request) {
 	// Use the request's context but enhance it with our logger
 	ctx := r.Context()
 	logger := loggingutil.Get(ctx)
 
-	if !httputil.MethodChecker(w, r, http.MethodPost) {
+	if !httputil.MethodChecker(w, r, http.MethodPost, http.MethodDelete) {
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		s.handleCancelIndexing(w, r)
 		return
 	}
 
@@ -392,6 +615,28 @@ func (s *Server) handleIndexAll(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteJSON(w, map[string]string{"status": "reindexing_started"}, http.StatusOK)
 }
 
+// handleCancelIndexing stops a running reindex. The status is "cancelling" while the
+// run winds down, or "not_indexing" if there was nothing to cancel.
+func (s *Server) handleCancelIndexing(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	logger.Info("Cancel indexing request", "remote_addr", r.RemoteAddr)
+
+	cancelled, err := s.service.CancelIndexing(ctx)
+	if err != nil {
+		logger.Error("Failed to cancel indexing", "error", err)
+		httputil.WriteError(w, fmt.Sprintf("Failed to cancel indexing: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	status := "not_indexing"
+	if cancelled {
+		status = "cancelling"
+	}
+	httputil.WriteJSON(w, map[string]string{"status": status}, http.StatusOK)
+}
+
 // handleIndexStatus handles indexing status requests
 func (s *Server) handleIndexStatus(w http.ResponseWriter, r *http.Request) {
 	// Use the request's context but enhance it with our logger
@@ -416,3 +661,307 @@ func (s *Server) handleIndexStatus(w http.ResponseWriter, r *http.Request) {
 		"indexedDocs", status.IndexedDocs)
 	httputil.WriteJSON(w, status, http.StatusOK)
 }
+
+// indexEventsKeepAlive is how often an idle index event stream gets a comment
+const indexEventsKeepAlive = 15 * time.Second
+
+// handleIndexEvents streams the progress of vault indexing runs as server-sent events,
+// one event per indexer.IndexEvent named after its type, until the client disconnects
+func (s *Server) handleIndexEvents(w http.ResponseWriter, r *http.Request) {
+	// Use the request's context but enhance it with our logger
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	if !httputil.MethodChecker(w, r, http.MethodGet) {
+		return
+	}
+
+	// Subscribe before the stream starts so a reindex requested right after connecting is seen
+	events, unsubscribe, err := s.service.IndexEvents()
+	if err != nil {
+		httputil.WriteError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer unsubscribe()
+
+	writer, err := httputil.NewEventWriter(w)
+	if err != nil {
+		logger.Error("Failed to start index event stream", "error", err)
+		httputil.WriteError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Debug("Index event stream opened", "remote_addr", r.RemoteAddr)
+
+	ticker := time.NewTicker(indexEventsKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Index event stream closed", "remote_addr", r.RemoteAddr)
+			return
+		case <-ticker.C:
+			if err := writer.Comment("keep-alive"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writer.Send(string(event.Type), event); err != nil {
+				logger.Debug("Failed to send index event", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// handleIndexGC handles garbage collection requests for stale index points
+func (s *Server) handleIndexGC(w http.ResponseWriter, r *http.Request) {
+	// Use the request's context but enhance it with our logger
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	if !httputil.MethodChecker(w, r, http.MethodPost) {
+		return
+	}
+
+	// Parse request
+	var request GCRequest
+	if err := httputil.ParseJSONRequest(r, &request); err != nil {
+		logger.Warn("Invalid request body", "error", err, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Index garbage collection request", "dryRun", request.DryRun, "remote_addr", r.RemoteAddr)
+
+	result, err := s.service.GarbageCollect(ctx, request.DryRun)
+	if err != nil {
+		logger.Error("Garbage collection failed", "error", err)
+		httputil.WriteError(w, fmt.Sprintf("Garbage collection failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Garbage collection completed",
+		"removedFiles", len(result.RemovedFiles),
+		"removedPoints", result.RemovedPoints)
+	httputil.WriteJSON(w, result, http.StatusOK)
+}
+
+// handleAsk answers a question from the vault, as server-sent events if the request asks
+// for a stream: a sources event with the retrieved passages, token events with the pieces
+// of the answer and a done event with the complete response, or an error event.
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	// Use the request's context but enhance it with our logger
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	if !httputil.MethodChecker(w, r, http.MethodPost) {
+		return
+	}
+
+	var request AskRequest
+	if err := httputil.ParseJSONRequest(r, &request); err != nil {
+		logger.Warn("Invalid request body", "error", err, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(request.Question) == "" {
+		logger.Warn("Missing question in ask request", "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, "Missing question parameter", http.StatusBadRequest)
+		return
+	}
+
+	mode, err := indexer.ParseSearchMode(request.Mode)
+	if err != nil {
+		logger.Warn("Invalid search mode", "error", err, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := requestFilter(request.PathPrefix, request.Tags, request.Where)
+	if err != nil {
+		logger.Warn("Invalid where clause", "error", err, "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debug("Ask request",
+		"question", request.Question,
+		"limit", request.Limit,
+		"filter", filter,
+		"stream", request.Stream,
+		"remote_addr", r.RemoteAddr)
+
+	options := indexer.SearchOptions{
+		Limit: request.Limit,
+		Mode:  mode,
+	}
+
+	// writeError reports errors that occur before any response was written
+	writeError := func(err error) {
+		var queryErr *query2.Error
+		switch {
+		case errors.As(err, &queryErr):
+			logger.Warn("Invalid question", "error", err, "question", request.Question)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrNoChatModel):
+			httputil.WriteError(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			logger.Error("Ask failed", "error", err, "question", request.Question)
+			httputil.WriteError(w, fmt.Sprintf("Ask failed: %v", err), http.StatusInternalServerError)
+		}
+	}
+
+	if !request.Stream {
+		response, err := s.service.Ask(ctx, request.Question, filter, options, nil, nil)
+		if err != nil {
+			writeError(err)
+			return
+		}
+		httputil.WriteJSON(w, response, http.StatusOK)
+		return
+	}
+
+	// The stream starts with the sources, so errors during retrieval still get a status code
+	var events *httputil.EventWriter
+	onSources := func(sources []Citation) error {
+		writer, err := httputil.NewEventWriter(w)
+		if err != nil {
+			return err
+		}
+		events = writer
+		return events.Send("sources", sources)
+	}
+	onToken := func(token string) error {
+		return events.Send("token", map[string]string{"text": token})
+	}
+
+	response, err := s.service.Ask(ctx, request.Question, filter, options, onSources, onToken)
+	if err != nil {
+		if events == nil {
+			writeError(err)
+			return
+		}
+		logger.Error("Ask failed", "error", err, "question", request.Question)
+		if sendErr := events.Send("error", httputil.ErrorResponse{Error: err.Error()}); sendErr != nil {
+			logger.Debug("Failed to send error event", "error", sendErr)
+		}
+		return
+	}
+
+	if err := events.Send("done", response); err != nil {
+		logger.Debug("Failed to send done event", "error", err)
+	}
+}
+
+// handleReadNote returns the content of a note, optionally limited to a range of lines
+func (s *Server) handleReadNote(w http.ResponseWriter, r *http.Request) {
+	// Use the request's context but enhance it with our logger
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	if !httputil.MethodChecker(w, r, http.MethodGet) {
+		return
+	}
+
+	path, ok := httputil.ParseQueryParameter(r, consts.QueryParamPath)
+	if !ok {
+		logger.Warn("Missing path parameter", "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, "Missing path parameter", http.StatusBadRequest)
+		return
+	}
+
+	startLine, err := httputil.ParseIntQueryParameter(r, consts.QueryParamStartLine, 0)
+	if err != nil {
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	endLine, err := httputil.ParseIntQueryParameter(r, consts.QueryParamEndLine, 0)
+	if err != nil {
+		httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debug("Read note request", "path", path, "start_line", startLine, "end_line", endLine, "remote_addr", r.RemoteAddr)
+
+	note, err := s.service.ReadNote(ctx, path, startLine, endLine)
+	if err != nil {
+		switch {
+		case errors.Is(err, indexer.ErrNoteNotFound):
+			httputil.WriteError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, indexer.ErrInvalidPath), errors.Is(err, ErrInvalidLineRange):
+			logger.Warn("Invalid read note request", "error", err, "path", path)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Error("Failed to read note", "error", err, "path", path)
+			httputil.WriteError(w, fmt.Sprintf("Failed to read note: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	httputil.WriteJSON(w, note, http.StatusOK)
+}
+
+// handleListTags returns the tags of the indexed notes with their note counts
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	// Use the request's context but enhance it with our logger
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	if !httputil.MethodChecker(w, r, http.MethodGet) {
+		return
+	}
+
+	logger.Debug("List tags request", "remote_addr", r.RemoteAddr)
+
+	tags, err := s.service.ListTags(ctx)
+	if err != nil {
+		logger.Error("Failed to list tags", "error", err)
+		httputil.WriteError(w, fmt.Sprintf("Failed to list tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, tags, http.StatusOK)
+}
+
+// handleNoteLinks returns the outgoing links and the backlinks of a note
+func (s *Server) handleNoteLinks(w http.ResponseWriter, r *http.Request) {
+	// Use the request's context but enhance it with our logger
+	ctx := r.Context()
+	logger := loggingutil.Get(ctx)
+
+	if !httputil.MethodChecker(w, r, http.MethodGet) {
+		return
+	}
+
+	path, ok := httputil.ParseQueryParameter(r, consts.QueryParamPath)
+	if !ok {
+		logger.Warn("Missing path parameter", "remote_addr", r.RemoteAddr)
+		httputil.WriteError(w, "Missing path parameter", http.StatusBadRequest)
+		return
+	}
+
+	logger.Debug("Note links request", "path", path, "remote_addr", r.RemoteAddr)
+
+	links, err := s.service.NoteLinks(ctx, path)
+	if err != nil {
+		switch {
+		case errors.Is(err, indexer.ErrNoteNotFound):
+			httputil.WriteError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, indexer.ErrInvalidPath):
+			logger.Warn("Invalid note links request", "error", err, "path", path)
+			httputil.WriteError(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Error("Failed to list note links", "error", err, "path", path)
+			httputil.WriteError(w, fmt.Sprintf("Failed to list note links: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	httputil.WriteJSON(w, links, http.StatusOK)
+}
@@ -7,6 +7,7 @@ import (
 	"obsfind/src/pkg/config"
 	indexer2 "obsfind/src/pkg/indexer"
 	model2 "obsfind/src/pkg/model"
+	"obsfind/src/pkg/query"
 	"strings"
 	"time"
 
@@ -22,6 +23,9 @@ type Service struct {
 	qdrantClient model2.QdrantClient
 	config       *config.Config
 
+	// chat answers questions in Ask, nil disables it
+	chat model2.ChatModel
+
 	// Status tracking
 	status struct {
 		StartTime      time.Time
@@ -88,6 +92,11 @@ func NewPlaceholderService() *Service {
 	}
 }
 
+// SetChatModel sets the model Ask answers questions with
+func (s *Service) SetChatModel(chat model2.ChatModel) {
+	s.chat = chat
+}
+
 // GetStatus returns the current daemon status
 func (s *Service) GetStatus() (*StatusResponse, error) {
 	var indexStats indexer2.Stats
@@ -133,6 +142,9 @@ func (s *Service) GetStatus() (*StatusResponse, error) {
 		if s.config.Qdrant.Embedded {
 			configMap["qdrant_mode"] = "embedded"
 			configMap["qdrant_data_path"] = s.config.Qdrant.DataPath
+			if s.config.Qdrant.BinaryPath != "" {
+				configMap["qdrant_binary_path"] = s.config.Qdrant.BinaryPath
+			}
 		} else {
 			configMap["qdrant_mode"] = "external"
 			configMap["qdrant_server"] = fmt.Sprintf("%s:%d", s.config.Qdrant.Host, s.config.Qdrant.Port)
@@ -172,78 +184,75 @@ type SearchResult struct {
 	Tags     []string               `json:"tags,omitempty"`
 	Section  string                 `json:"section,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Highlights mark the query matches in Excerpt and Line is the note line the excerpt starts on
+	Highlights []Highlight `json:"highlights,omitempty"`
+	Line       int         `json:"line,omitempty"`
+
+	// StartLine and EndLine are the lines of the matching chunk in the note
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+
+	// BestScore and Sections are set when results are grouped by document
+	BestScore float32        `json:"best_score,omitempty"`
+	Sections  []SearchResult `json:"sections,omitempty"`
+
+	// text is the chunk with its markup, which the excerpt is taken from
+	text string
 }
 
-// Search performs a semantic search using Qdrant for vector similarity search
-func (s *Service) Search(ctx context.Context, query string, limit int, filter string) ([]SearchResult, error) {
+// Search performs a semantic, keyword or hybrid search depending on options.Mode,
+// returning chunks or notes depending on options.GroupBy
+func (s *Service) Search(ctx context.Context, input string, filter string, options indexer2.SearchOptions) ([]SearchResult, error) {
 	// Configure search options
-	if limit <= 0 {
-		limit = 10
-	}
-
-	// Parse filter if provided (e.g., "tags:note,important" or "path:/folder/")
-	var pathPrefix string
-	var tags []string
-
-	// Parse filter if it's in format "type:value"
-	if filter != "" && strings.Contains(filter, ":") {
-		filterParts := strings.Split(filter, ":")
-		if len(filterParts) == 2 {
-			filterType := strings.TrimSpace(filterParts[0])
-			filterValue := strings.TrimSpace(filterParts[1])
-
-			switch filterType {
-			case "tags":
-				tags = strings.Split(filterValue, ",")
-				for i, tag := range tags {
-					tags[i] = strings.TrimSpace(tag)
-				}
-			case "path":
-				pathPrefix = filterValue
-			}
-		}
-	} else {
-		// Direct filter is provided (from CLI parameters)
-		// If filter contains a path separator, treat it as a path filter
-		if filter != "" && (strings.Contains(filter, "/") || strings.Contains(filter, "\\")) {
-			pathPrefix = filter
-		}
+	if options.Limit <= 0 {
+		options.Limit = 10
 	}
 
-	// Log the search request
-	log.Info().
-		Str("query", query).
-		Int("limit", limit).
-		Str("pathPrefix", pathPrefix).
-		Strs("tags", tags).
-		Msg("Executing semantic search")
-
-	// Step 1: Generate embedding for the query
-	embeddings, err := s.embedder.EmbedBatch(ctx, []string{query})
+	// Reject malformed queries before doing any work
+	input, parsed, err := parseQuery(input, filter)
 	if err != nil {
-		log.Error().Err(err).Str("query", query).Msg("Embedding generation failed")
-		// Return an explicit user-friendly error message
-		return nil, fmt.Errorf("unable to process search query: embedding service unavailable - please check if Ollama is running")
+		return nil, err
 	}
 
-	if len(embeddings) == 0 {
-		log.Warn().Str("query", query).Msg("Empty embedding generated")
-		return nil, fmt.Errorf("search processing error: empty embedding generated")
-	}
+	// Log the search request
+	log.Info().
+		Str("query", input).
+		Int("limit", options.Limit).
+		Int("filters", len(parsed.Filters)).
+		Str("mode", string(options.Mode)).
+		Str("groupBy", string(options.GroupBy)).
+		Float32("diversity", options.Diversity).
+		Float32("graph_boost", options.GraphBoost).
+		Bool("expand_links", options.ExpandLinks).
+		Msg("Executing search")
+
+	// Step 1: Check the embedding service, keyword search works without it
+	var queryVector []float32
+	if options.Mode != indexer2.SearchModeKeyword {
+		embeddings, err := s.embedder.EmbedBatch(ctx, []string{parsed.Text()})
+		if err != nil {
+			log.Error().Err(err).Str("query", input).Msg("Embedding generation failed")
+			// Return an explicit user-friendly error message
+			return nil, fmt.Errorf("unable to process search query: embedding service unavailable - please check if Ollama is running")
+		}
 
-	// Note: We're getting the embedding but not using it directly here
-	// because we're delegating the search to the indexer which will use the query text
+		if len(embeddings) == 0 {
+			log.Warn().Str("query", input).Msg("Empty embedding generated")
+			return nil, fmt.Errorf("search processing error: empty embedding generated")
+		}
 
-	// Step 2: Create search options for indexer
-	searchOptions := indexer2.SearchOptions{
-		Limit:      limit,
-		MinScore:   0.6, // Reasonable default
-		Tags:       tags,
-		PathPrefix: pathPrefix,
+		// The indexer embeds the query itself, this one picks the excerpts
+		queryVector = embeddings[0]
+	}
+
+	// Step 2: Complete the search options for the indexer
+	if options.MinScore <= 0 {
+		options.MinScore = 0.6 // Reasonable default
 	}
 
 	// Step 3: Perform search using indexer
-	indexerResults, err := s.indexer.Search(ctx, query, searchOptions)
+	indexerResults, err := s.indexer.Search(ctx, input, options)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
@@ -257,18 +266,9 @@ func (s *Service) Search(ctx context.Context, query string, limit int, filter st
 	// Step 4: Convert indexer results to API results
 	results := make([]SearchResult, len(indexerResults))
 	for i, r := range indexerResults {
-		results[i] = SearchResult{
-			ID:       fmt.Sprintf("result-%d", i),
-			Path:     r.Path,
-			Title:    r.Title,
-			Content:  r.Content,
-			Excerpt:  extractExcerpt(r.Content, query, 150),
-			Score:    float32(r.Score),
-			Tags:     r.Tags,
-			Section:  r.Section,
-			Metadata: r.Metadata,
-		}
+		results[i] = toSearchResult(fmt.Sprintf("result-%d", i), r)
 	}
+	s.addExcerpts(ctx, results, parsed.Text(), queryVector)
 
 	log.Info().
 		Int("resultCount", len(results)).
@@ -278,31 +278,65 @@ func (s *Service) Search(ctx context.Context, query string, limit int, filter st
 	return results, nil
 }
 
-// extractExcerpt creates a relevant excerpt from content based on the query
-// This function would be used in a real implementation to show the most relevant
-// part of the content in search results
-func extractExcerpt(content, query string, maxLength int) string {
-	// This is a simplified implementation
-	// A real implementation would:
-	// 1. Break content into sentences
-	// 2. Score each sentence based on relevance to query terms
-	// 3. Return the highest scoring sentence/section
+// parseQuery appends the filter to the input and parses the result.
+// The filter uses the query language too, e.g. "tag:note,important path:folder/",
+// but may not contain search terms.
+func parseQuery(input, filter string) (string, *query.Query, error) {
+	if filter != "" {
+		parsedFilter, err := query.Parse(filter)
+		if err != nil {
+			return "", nil, err
+		}
+		if parsedFilter.Text() != "" || len(parsedFilter.Excluded) > 0 {
+			return "", nil, &query.Error{Pos: -1, Msg: fmt.Sprintf("filter %q may only contain field filters", filter)}
+		}
+		input = strings.TrimSpace(input + " " + filter)
+	}
+
+	parsed, err := query.Parse(input)
+	if err != nil {
+		return "", nil, err
+	}
+	if parsed.Text() == "" {
+		return "", nil, &query.Error{Pos: -1, Msg: "no search terms, only filters"}
+	}
+
+	return input, parsed, nil
+}
+
+// toSearchResult converts an indexer result, including its sections, into an API result.
+// The excerpt is filled in later by addExcerpts.
+func toSearchResult(id string, r indexer2.SearchResult) SearchResult {
+	text := r.Text
+	if text == "" {
+		text = r.Content
+	}
 
-	if len(content) <= maxLength {
-		return content
+	result := SearchResult{
+		ID:        id,
+		Path:      r.Path,
+		Title:     r.Title,
+		Content:   r.Content,
+		Score:     float32(r.Score),
+		Tags:      r.Tags,
+		Section:   r.Section,
+		Metadata:  r.Metadata,
+		StartLine: r.StartLine,
+		EndLine:   r.EndLine,
+		BestScore: float32(r.BestScore),
+		text:      text,
 	}
 
-	// For this example, just return the first part of the content
-	excerpt := content
-	if len(excerpt) > maxLength {
-		excerpt = excerpt[:maxLength-3] + "..."
+	for j, section := range r.Sections {
+		result.Sections = append(result.Sections, toSearchResult(fmt.Sprintf("%s-%d", id, j), section))
 	}
 
-	return excerpt
+	return result
 }
 
-// FindSimilar finds documents similar to the specified file
-func (s *Service) FindSimilar(ctx context.Context, filePath string, limit int) ([]SearchResult, error) {
+// FindSimilar finds chunks or notes similar to the specified file using the
+// strategy, grouping and filters of options
+func (s *Service) FindSimilar(ctx context.Context, filePath string, options indexer2.SearchOptions) ([]SearchResult, error) {
 	// Check if Qdrant collection has data before proceeding
 	if s.qdrantClient != nil {
 		// Use collection name from config
@@ -358,18 +392,15 @@ func (s *Service) FindSimilar(ctx context.Context, filePath string, limit int) (
 	}
 
 	// Validate input
-	if limit <= 0 {
-		limit = 10
+	if options.Limit <= 0 {
+		options.Limit = 10
 	}
-
-	// Create search options
-	searchOptions := indexer2.SearchOptions{
-		Limit:    limit,
-		MinScore: 0.6, // Reasonable default
+	if options.MinScore <= 0 {
+		options.MinScore = 0.6 // Reasonable default
 	}
 
 	// Execute similar search via indexer
-	indexerResults, err := s.indexer.FindSimilar(ctx, filePath, searchOptions)
+	indexerResults, err := s.indexer.FindSimilar(ctx, filePath, options)
 	if err != nil {
 		// Check if this is a "document not found" error
 		if strings.Contains(err.Error(), "document not found") {
@@ -397,18 +428,9 @@ func (s *Service) FindSimilar(ctx context.Context, filePath string, limit int) (
 	// Convert indexer results to API results
 	results := make([]SearchResult, len(indexerResults))
 	for i, r := range indexerResults {
-		results[i] = SearchResult{
-			ID:       fmt.Sprintf("similar-%d", i),
-			Path:     r.Path,
-			Title:    r.Title,
-			Excerpt:  extractExcerpt(r.Content, "", 150),
-			Content:  r.Content,
-			Score:    float32(r.Score),
-			Tags:     r.Tags,
-			Section:  r.Section,
-			Metadata: r.Metadata,
-		}
+		results[i] = toSearchResult(fmt.Sprintf("similar-%d", i), r)
 	}
+	s.addExcerpts(ctx, results, "", nil)
 
 	return results, nil
 }
@@ -430,8 +452,13 @@ func (s *Service) IndexFile(ctx context.Context, filePath string, force bool) er
 		return nil
 	}
 
-	// Delegate to the actual indexer service
-	err := s.indexer.IndexFile(ctx, filePath)
+	// Queue the file ahead of reindexing work and wait for it
+	err := s.indexer.Do(ctx, indexer2.Job{
+		Type:     indexer2.JobIndex,
+		Path:     filePath,
+		Force:    force,
+		Priority: indexer2.PriorityChange,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to index file %s: %w", filePath, err)
 	}
@@ -446,6 +473,19 @@ func (s *Service) IndexFile(ctx context.Context, filePath string, force bool) er
 	return nil
 }
 
+// GarbageCollect removes index points whose files no longer exist
+func (s *Service) GarbageCollect(ctx context.Context, dryRun bool) (*indexer2.GCResult, error) {
+	if s.indexer == nil {
+		return nil, errors.New("no indexer configured")
+	}
+
+	if s.indexer.IsIndexing() {
+		return nil, fmt.Errorf("indexing is already in progress")
+	}
+
+	return s.indexer.GarbageCollect(ctx, dryRun)
+}
+
 // resetCollection handles dropping and recreating a Qdrant collection
 func (s *Service) resetCollection(ctx context.Context) error {
 	// Get collection name from config
@@ -494,8 +534,14 @@ func (s *Service) backgroundReindexAll(force bool) {
 	if force {
 		if err := s.resetCollection(bgCtx); err != nil {
 			log.Error().Err(err).Msg("Failed to reset collection")
+			s.indexer.ReportFailure(err)
 			return
 		}
+
+		// The collection is empty now, so nothing recorded in the manifest is indexed anymore
+		if err := s.indexer.ResetManifest(); err != nil {
+			log.Error().Err(err).Msg("Failed to reset index manifest")
+		}
 	}
 
 	// Start the indexing process
@@ -528,6 +574,30 @@ func (s *Service) ReindexAll(ctx context.Context, force bool) error {
 	return nil
 }
 
+// CancelIndexing stops a running vault indexing run and reports whether there was one
+func (s *Service) CancelIndexing(ctx context.Context) (bool, error) {
+	if s.indexer == nil {
+		return false, errors.New("no indexer configured")
+	}
+
+	cancelled := s.indexer.CancelIndexing()
+	if cancelled {
+		log.Info().Msg("Indexing cancellation requested")
+	}
+	return cancelled, nil
+}
+
+// IndexEvents subscribes to the progress events of vault indexing runs.
+// The returned function ends the subscription.
+func (s *Service) IndexEvents() (<-chan indexer2.IndexEvent, func(), error) {
+	if s.indexer == nil {
+		return nil, nil, errors.New("no indexer configured")
+	}
+
+	events, unsubscribe := s.indexer.Subscribe()
+	return events, unsubscribe, nil
+}
+
 // getIndexingStatus creates a status object from the current service state
 // This is an internal function to prepare the status response
 
@@ -554,15 +624,21 @@ func (s *Service) GetIndexingStatus(ctx context.Context) (*IndexingStatus, error
 	// Get real indexing status from indexer
 	indexStats := s.indexer.GetStats()
 
-	// Determine percent complete
+	// Determine percent complete, unchanged and failed files count as processed
 	percentComplete := 0.0
 	if indexStats.TotalDocuments > 0 {
-		percentComplete = float64(indexStats.IndexedDocuments) / float64(indexStats.TotalDocuments) * 100
+		processed := indexStats.IndexedDocuments + indexStats.SkippedDocuments + indexStats.FailedDocuments
+		percentComplete = float64(processed) / float64(indexStats.TotalDocuments) * 100
 	}
 
 	// Find current and last indexed file
 	var currentFile, lastIndexedFile string
 
+	isIndexing := indexStats.Status == "indexing"
+	if isIndexing {
+		currentFile = s.indexer.Progress().Path
+	}
+
 	if len(indexStats.Documents) > 0 {
 		// Get the most recently indexed document
 		var mostRecent time.Time
@@ -575,7 +651,7 @@ func (s *Service) GetIndexingStatus(ctx context.Context) (*IndexingStatus, error
 	}
 
 	return &IndexingStatus{
-		IsIndexing:        indexStats.Status == "indexing",
+		IsIndexing:        isIndexing,
 		IndexedDocs:       indexStats.IndexedDocuments,
 		TotalDocs:         indexStats.TotalDocuments,
 		PercentComplete:   percentComplete,
@@ -58,6 +58,7 @@ type Config struct {
 		Port       int    `mapstructure:"port"`
 		APIKey     string `mapstructure:"api_key"`
 		Embedded   bool   `mapstructure:"embedded"`
+		BinaryPath string `mapstructure:"binary_path"` // Qdrant binary supervised in embedded mode, empty for the in-process store
 		DataPath   string `mapstructure:"data_path"`
 		Collection string `mapstructure:"collection"`
 		Distance   string `mapstructure:"distance"` // cosine, dot, or euclid
@@ -75,8 +76,30 @@ type Config struct {
 		BatchSize        int      `mapstructure:"batch_size"`
 		RescoreResults   bool     `mapstructure:"rescore_results"`
 		ReindexOnStartup bool     `mapstructure:"reindex_on_startup"`
+		Workers          int      `mapstructure:"workers"`          // notes read, parsed and chunked concurrently
+		EmbedWorkers     int      `mapstructure:"embed_workers"`    // embedding batches requested concurrently
+		JobMaxAttempts   int      `mapstructure:"job_max_attempts"` // tries of a failing job before it is dropped
+
+		// Reranking of search results, enabled by RescoreResults
+		RerankModel   string `mapstructure:"rerank_model"`      // Ollama generate model grading query/passage pairs
+		RerankTopK    int    `mapstructure:"rerank_top_k"`      // candidates graded per search
+		RerankTimeout int    `mapstructure:"rerank_timeout_ms"` // vector order is kept when grading takes longer
+
+		// Transclusion of ![[embeds]] into the text of the embedding note
+		TranscludeEmbeds  bool `mapstructure:"transclude_embeds"`
+		TransclusionDepth int  `mapstructure:"transclusion_depth"` // levels of embeds inside embedded notes
+
+		// IndexCodeBlocks adds a chunk for every fenced code block, with its language in the payload
+		IndexCodeBlocks bool `mapstructure:"index_code_blocks"`
 	} `mapstructure:"indexing"`
 
+	// Question answering settings for obsfind ask
+	Ask struct {
+		ModelName     string  `mapstructure:"model_name"`     // Ollama chat model, served by embedding.server_url
+		ContextChunks int     `mapstructure:"context_chunks"` // retrieved chunks given to the model
+		Temperature   float64 `mapstructure:"temperature"`
+	} `mapstructure:"ask"`
+
 	// FileWatcher settings
 	FileWatcher struct {
 		DebounceTime     int  `mapstructure:"debounce_time_ms"`
@@ -222,6 +245,20 @@ func DefaultConfig() Config {
 	config.Indexing.BatchSize = 50
 	config.Indexing.RescoreResults = true
 	config.Indexing.ReindexOnStartup = false
+	config.Indexing.Workers = 2
+	config.Indexing.EmbedWorkers = 1
+	config.Indexing.JobMaxAttempts = 3
+	config.Indexing.RerankModel = ""
+	config.Indexing.RerankTopK = 20
+	config.Indexing.RerankTimeout = 3000
+	config.Indexing.TranscludeEmbeds = false
+	config.Indexing.TransclusionDepth = 3
+	config.Indexing.IndexCodeBlocks = false
+
+	// Ask defaults
+	config.Ask.ModelName = "llama3.2"
+	config.Ask.ContextChunks = 8
+	config.Ask.Temperature = 0.2
 
 	// FileWatcher defaults
 	config.FileWatcher.DebounceTime = 500
@@ -274,6 +311,11 @@ func ValidateConfig(config *Config) error {
 		if err := os.MkdirAll(config.Qdrant.DataPath, 0755); err != nil {
 			return fmt.Errorf("failed to create Qdrant data directory: %w", err)
 		}
+
+		// A supervised Qdrant process serves gRPC on the port and REST on the one below it
+		if config.Qdrant.BinaryPath != "" && config.Qdrant.Port <= 1 {
+			return fmt.Errorf("qdrant port must be greater than 1 for a supervised Qdrant process")
+		}
 	} else {
 		// For external Qdrant, validate connection params
 		if config.Qdrant.Host == "" {
@@ -318,6 +360,11 @@ func (c *Config) GetEmbeddingTimeout() time.Duration {
 	return time.Duration(c.Embedding.Timeout) * time.Second
 }
 
+// GetRerankTimeout returns how long search waits for the reranker
+func (c *Config) GetRerankTimeout() time.Duration {
+	return time.Duration(c.Indexing.RerankTimeout) * time.Millisecond
+}
+
 // GetVaultPaths returns all vault paths from the configuration
 func (c *Config) GetVaultPaths() []string {
 	// If we have explicit vault paths, use those
@@ -411,6 +458,7 @@ func mapConfigToViper(config *Config) error {
 	viper.Set("qdrant.port", config.Qdrant.Port)
 	viper.Set("qdrant.api_key", config.Qdrant.APIKey)
 	viper.Set("qdrant.embedded", config.Qdrant.Embedded)
+	viper.Set("qdrant.binary_path", config.Qdrant.BinaryPath)
 	viper.Set("qdrant.data_path", config.Qdrant.DataPath)
 	viper.Set("qdrant.collection", config.Qdrant.Collection)
 	viper.Set("qdrant.distance", config.Qdrant.Distance)
@@ -426,6 +474,20 @@ func mapConfigToViper(config *Config) error {
 	viper.Set("indexing.batch_size", config.Indexing.BatchSize)
 	viper.Set("indexing.rescore_results", config.Indexing.RescoreResults)
 	viper.Set("indexing.reindex_on_startup", config.Indexing.ReindexOnStartup)
+	viper.Set("indexing.workers", config.Indexing.Workers)
+	viper.Set("indexing.embed_workers", config.Indexing.EmbedWorkers)
+	viper.Set("indexing.job_max_attempts", config.Indexing.JobMaxAttempts)
+	viper.Set("indexing.rerank_model", config.Indexing.RerankModel)
+	viper.Set("indexing.rerank_top_k", config.Indexing.RerankTopK)
+	viper.Set("indexing.rerank_timeout_ms", config.Indexing.RerankTimeout)
+	viper.Set("indexing.transclude_embeds", config.Indexing.TranscludeEmbeds)
+	viper.Set("indexing.transclusion_depth", config.Indexing.TransclusionDepth)
+	viper.Set("indexing.index_code_blocks", config.Indexing.IndexCodeBlocks)
+
+	// Ask settings
+	viper.Set("ask.model_name", config.Ask.ModelName)
+	viper.Set("ask.context_chunks", config.Ask.ContextChunks)
+	viper.Set("ask.temperature", config.Ask.Temperature)
 
 	// FileWatcher settings
 	viper.Set("file_watcher.debounce_time_ms", config.FileWatcher.DebounceTime)
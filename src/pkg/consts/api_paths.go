@@ -21,24 +21,38 @@ const (
 	APIIndexFile   = APIIndexPrefix + "/file"
 	APIIndexAll    = APIIndexPrefix + "/all"
 	APIIndexStatus = APIIndexPrefix + "/status"
+	APIIndexGC     = APIIndexPrefix + "/gc"
+	APIIndexEvents = APIIndexPrefix + "/events"
+
+	// Question answering endpoint
+	APIAsk = APIPrefix + "/ask"
+
+	// Note endpoints
+	APINotes = APIPrefix + "/notes"
+	APITags  = APIPrefix + "/tags"
+	APILinks = APIPrefix + "/links"
 )
 
 // Query parameter keys
 const (
 	// Common query parameters
-	QueryParamQuery      = "q"
-	QueryParamLimit      = "limit"
-	QueryParamOffset     = "offset"
-	QueryParamMinScore   = "min_score"
-	QueryParamTag        = "tag"
-	QueryParamPathPrefix = "path_prefix"
-	QueryParamFilter     = "filter"
-)
-
-// Filter prefixes
-const (
-	FilterPrefixPath = "path:"
-	FilterPrefixTags = "tags:"
+	QueryParamQuery       = "q"
+	QueryParamLimit       = "limit"
+	QueryParamOffset      = "offset"
+	QueryParamMinScore    = "min_score"
+	QueryParamTag         = "tag"
+	QueryParamPathPrefix  = "path_prefix"
+	QueryParamFilter      = "filter"
+	QueryParamMode        = "mode"
+	QueryParamWhere       = "where"
+	QueryParamGroupBy     = "group_by"
+	QueryParamGroupSize   = "group_size"
+	QueryParamDiversity   = "diversity"
+	QueryParamGraphBoost  = "graph_boost"
+	QueryParamExpandLinks = "expand_links"
+	QueryParamPath        = "path"
+	QueryParamStartLine   = "start_line"
+	QueryParamEndLine     = "end_line"
 )
 
 // Default values
@@ -10,8 +10,10 @@ import (
 	"obsfind/src/pkg/indexer"
 	model2 "obsfind/src/pkg/model"
 	"obsfind/src/pkg/qdrant"
+	"obsfind/src/pkg/vectorstore"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,7 +21,7 @@ import (
 // Service represents the daemon service
 type Service struct {
 	config      *config.Config
-	qdrant      *qdrant.Client
+	qdrant      model2.QdrantClient
 	embedder    model2.Embedder
 	indexer     *indexer.Service
 	fileWatcher *filewatcher.Watcher
@@ -96,24 +98,10 @@ func (s *Service) Start(ctx context.Context) error {
 func (s *Service) initialize(ctx context.Context) error {
 	var err error
 
-	// Initialize Qdrant client
-	qdrantCfg := &qdrant.Config{
-		Host:       s.config.Qdrant.Host,
-		Port:       s.config.Qdrant.Port,
-		APIKey:     s.config.Qdrant.APIKey,
-		Embedded:   s.config.Qdrant.Embedded,
-		DataPath:   s.config.Qdrant.DataPath,
-		Collection: s.config.Qdrant.Collection,
-	}
-
-	s.qdrant, err = qdrant.NewClient(qdrantCfg)
+	// Initialize the vector store
+	s.qdrant, err = s.openVectorStore(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create Qdrant client: %w", err)
-	}
-
-	// Connect to Qdrant
-	if err := s.qdrant.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to Qdrant: %w", err)
+		return err
 	}
 
 	// Apply schema
@@ -152,6 +140,23 @@ func (s *Service) initialize(ctx context.Context) error {
 	s.indexer = indexer.NewService(s.config, s.embedder, s.qdrant)
 	log.Printf("Indexer service initialized")
 
+	// Watcher events, file requests and reindexing runs are all processed by the workers
+	s.indexer.Start(ctx)
+
+	// Rerank search results if enabled, searches keep the vector order when it fails
+	if s.config.Indexing.RescoreResults && s.config.Indexing.RerankModel != "" {
+		reranker, err := model2.NewOllamaReranker(model2.OllamaRerankerConfig{
+			ModelName: s.config.Indexing.RerankModel,
+			ServerURL: s.config.Embedding.ServerURL,
+		})
+		if err != nil {
+			log.Printf("Reranking disabled: %v", err)
+		} else {
+			s.indexer.SetReranker(reranker)
+			log.Printf("Reranking search results with %s", reranker.Name())
+		}
+	}
+
 	// Set up file watcher
 	watcherCfg := &filewatcher.Config{
 		DebounceTime:     s.config.GetIndexingDebounceTime(),
@@ -182,11 +187,59 @@ func (s *Service) initialize(ctx context.Context) error {
 		s.config,
 	)
 
+	// Answer questions with a chat model, everything else works without it
+	chat, err := model2.NewOllamaChatModel(model2.OllamaChatConfig{
+		ModelName:   s.config.Ask.ModelName,
+		ServerURL:   s.config.Embedding.ServerURL,
+		Temperature: s.config.Ask.Temperature,
+	})
+	if err != nil {
+		log.Printf("Question answering disabled: %v", err)
+	} else {
+		s.apiService.SetChatModel(chat)
+	}
+
 	log.Printf("API service initialized with real components")
 
 	return nil
 }
 
+// openVectorStore opens the in-process store in embedded mode and connects to Qdrant otherwise.
+// With a Qdrant binary configured, embedded mode runs that binary as a supervised child process instead.
+func (s *Service) openVectorStore(ctx context.Context) (model2.QdrantClient, error) {
+	if s.config.Qdrant.Embedded && s.config.Qdrant.BinaryPath == "" {
+		store, err := vectorstore.Open(s.config.Qdrant.DataPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded vector store: %w", err)
+		}
+
+		log.Printf("Using embedded vector store at %s", s.config.Qdrant.DataPath)
+		return store, nil
+	}
+
+	qdrantCfg := &qdrant.Config{
+		Host:       s.config.Qdrant.Host,
+		Port:       s.config.Qdrant.Port,
+		APIKey:     s.config.Qdrant.APIKey,
+		Embedded:   s.config.Qdrant.Embedded,
+		BinaryPath: s.config.Qdrant.BinaryPath,
+		DataPath:   s.config.Qdrant.DataPath,
+		Collection: s.config.Qdrant.Collection,
+	}
+
+	client, err := qdrant.NewClient(qdrantCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Qdrant client: %w", err)
+	}
+
+	// Connect to Qdrant
+	if err := client.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to Qdrant: %w", err)
+	}
+
+	return client, nil
+}
+
 // handleFileEvents processes file events from the watcher
 func (s *Service) handleFileEvents(ctx context.Context) {
 	for {
@@ -213,43 +266,79 @@ func (s *Service) processFileEvent(ctx context.Context, evt filewatcher.Event) {
 		return
 	}
 
-	// Skip non-markdown files
-	if evt.Extension != ".md" {
+	// Skip non-markdown files, unless a note was renamed to another extension
+	renamedNote := evt.Type == filewatcher.EventRenamed && strings.ToLower(filepath.Ext(evt.OldPath)) == ".md"
+	if evt.Extension != ".md" && !renamedNote {
 		return
 	}
 
-	// Process based on event type
+	// Queue the change, the indexer's workers retry it if it fails
 	switch evt.Type {
 	case filewatcher.EventCreated, filewatcher.EventModified:
-		log.Printf("Indexing changed file: %s", evt.Path)
-		// In a real implementation, we would:
-		// 1. Read and process the file
-		// 2. Generate embeddings
-		// 3. Store in Qdrant
+		log.Printf("Queueing changed file for indexing: %s", evt.Path)
+
+		s.indexer.Enqueue(indexer.Job{
+			Type:     indexer.JobIndex,
+			Path:     evt.Path,
+			Priority: indexer.PriorityChange,
+		})
 
-		// For now, just update status
 		s.updateStatus(func() {
+			if evt.Type == filewatcher.EventCreated {
+				s.documentCount++
+			}
 			s.indexedDocs++
-			s.documentCount++
 			s.lastIndexTime = time.Now()
 		})
 
 	case filewatcher.EventDeleted:
-		log.Printf("Removing deleted file from index: %s", evt.Path)
-		// In a real implementation, we would:
-		// 1. Remove the file's entries from Qdrant
+		log.Printf("Queueing removal of deleted file: %s", evt.Path)
+
+		s.indexer.Enqueue(indexer.Job{
+			Type:     indexer.JobDelete,
+			Path:     evt.Path,
+			Priority: indexer.PriorityChange,
+		})
 
-		// For now, just update status
 		s.updateStatus(func() {
-			s.documentCount--
+			if s.documentCount > 0 {
+				s.documentCount--
+			}
+			s.lastIndexTime = time.Now()
 		})
 
 	case filewatcher.EventRenamed:
-		log.Printf("Updating renamed file in index: %s -> %s", evt.OldPath, evt.Path)
-		// In a real implementation, we would:
-		// 1. Update references in Qdrant
+		// Without the new name the file was moved out of the watched tree
+		if evt.OldPath == "" {
+			log.Printf("Queueing removal of moved file: %s", evt.Path)
+
+			s.indexer.Enqueue(indexer.Job{
+				Type:     indexer.JobDelete,
+				Path:     evt.Path,
+				Priority: indexer.PriorityChange,
+			})
+
+			s.updateStatus(func() {
+				if s.documentCount > 0 {
+					s.documentCount--
+				}
+				s.lastIndexTime = time.Now()
+			})
+			return
+		}
 
-		// For now, do nothing
+		log.Printf("Queueing move of renamed file: %s -> %s", evt.OldPath, evt.Path)
+
+		s.indexer.Enqueue(indexer.Job{
+			Type:     indexer.JobRename,
+			Path:     evt.Path,
+			OldPath:  evt.OldPath,
+			Priority: indexer.PriorityChange,
+		})
+
+		s.updateStatus(func() {
+			s.lastIndexTime = time.Now()
+		})
 	}
 }
 
@@ -345,6 +434,13 @@ func (s *Service) Stop(ctx context.Context) error {
 		}
 	}
 
+	// Stop the indexing workers, unfinished jobs are saved and resumed on the next start
+	if s.indexer != nil {
+		if err := s.indexer.Close(); err != nil {
+			log.Printf("Error saving indexing job queue: %v", err)
+		}
+	}
+
 	// Close the embedder
 	if s.embedder != nil {
 		if err := s.embedder.Close(); err != nil {
@@ -352,10 +448,10 @@ func (s *Service) Stop(ctx context.Context) error {
 		}
 	}
 
-	// Close Qdrant client
+	// Close the vector store
 	if s.qdrant != nil {
 		if err := s.qdrant.Close(); err != nil {
-			log.Printf("Error closing Qdrant client: %v", err)
+			log.Printf("Error closing vector store: %v", err)
 		}
 	}
 
@@ -71,9 +71,17 @@ type Watcher struct {
 	debounceMu   sync.Mutex
 	recentEvents map[string]time.Time
 	recentMu     sync.RWMutex
+	lastRename   pendingRename
 	done         chan struct{}
 }
 
+// pendingRename remembers the source of the most recent rename so it can be
+// paired with the create event fsnotify reports for the destination
+type pendingRename struct {
+	path string
+	at   time.Time
+}
+
 // NewWatcher creates a new file watcher
 func NewWatcher(config *Config) (*Watcher, error) {
 	// Create fsnotify watcher
@@ -208,6 +216,14 @@ func (w *Watcher) handleFsEvent(evt fsnotify.Event) {
 		if exists && isDir {
 			w.watchDirectory(evt.Name)
 		}
+
+		// A create right after a rename is the other half of the rename
+		if !isDir {
+			if oldPath := w.takePendingRename(); oldPath != "" && oldPath != evt.Name {
+				w.debounceEvent(EventRenamed, evt.Name, false, oldPath)
+				return
+			}
+		}
 	case evt.Op&fsnotify.Write == fsnotify.Write:
 		eventType = EventModified
 	case evt.Op&fsnotify.Remove == fsnotify.Remove:
@@ -219,6 +235,10 @@ func (w *Watcher) handleFsEvent(evt fsnotify.Event) {
 		eventType = EventRenamed
 		if isDir {
 			w.unwatchDirectory(evt.Name)
+		} else {
+			w.debounceMu.Lock()
+			w.lastRename = pendingRename{path: evt.Name, at: time.Now()}
+			w.debounceMu.Unlock()
 		}
 	default:
 		return // Ignore other events
@@ -228,6 +248,27 @@ func (w *Watcher) handleFsEvent(evt fsnotify.Event) {
 	w.debounceEvent(eventType, evt.Name, isDir, "")
 }
 
+// takePendingRename returns the source path of a rename that happened within
+// the debounce window and cancels its queued event
+func (w *Watcher) takePendingRename() string {
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+
+	pending := w.lastRename
+	w.lastRename = pendingRename{}
+
+	if pending.path == "" || time.Since(pending.at) > w.config.DebounceTime {
+		return ""
+	}
+
+	if timer, exists := w.debounceMap[pending.path]; exists {
+		timer.Stop()
+		delete(w.debounceMap, pending.path)
+	}
+
+	return pending.path
+}
+
 // shouldProcess determines if a file should be monitored
 func (w *Watcher) shouldProcess(path string, isDir bool) bool {
 	// Always process directories (for watching)
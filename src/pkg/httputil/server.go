@@ -12,6 +12,7 @@ import (
 
 	"obsfind/src/pkg/consts"
 	"obsfind/src/pkg/loggingutil"
+	query2 "obsfind/src/pkg/query"
 )
 
 // ErrorResponse represents an error response
@@ -56,6 +57,51 @@ func ParseIntQueryParameter(r *http.Request, paramName string, defaultValue int)
 	return value, nil
 }
 
+// ParseOffsetQueryParameter parses a paging offset from the request, which may be 0
+func ParseOffsetQueryParameter(r *http.Request, paramName string) (int, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if valueStr == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s parameter: must not be negative", paramName)
+	}
+
+	return value, nil
+}
+
+// ParseFloatQueryParameter parses a float query parameter within [min, max] from the request
+func ParseFloatQueryParameter(r *http.Request, paramName string, defaultValue, min, max float64) (float64, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("invalid %s parameter: expected a number between %g and %g", paramName, min, max)
+	}
+
+	return value, nil
+}
+
+// ParseBoolQueryParameter parses a boolean query parameter such as true or 1 from the request
+func ParseBoolQueryParameter(r *http.Request, paramName string, defaultValue bool) (bool, error) {
+	valueStr := r.URL.Query().Get(paramName)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: expected true or false", paramName)
+	}
+
+	return value, nil
+}
+
 // ParseJSONRequest parses the request body into the given target type
 func ParseJSONRequest(r *http.Request, target interface{}) error {
 	if err := json.NewDecoder(r.Body).Decode(target); err != nil {
@@ -96,21 +142,31 @@ func ParseSearchParameters(r *http.Request) (query string, limit int, filter str
 		}
 	}
 
-	// Get filter parameters
-	pathPrefix := r.URL.Query().Get(consts.QueryParamPathPrefix)
-	if pathPrefix != "" {
-		filter = consts.FilterPrefixPath + pathPrefix
-		return
+	// Combine the filter parameters into a filter in query syntax
+	var filters []string
+	if pathPrefix := r.URL.Query().Get(consts.QueryParamPathPrefix); pathPrefix != "" {
+		filters = append(filters, query2.Term(query2.FieldPath, pathPrefix))
+	}
+
+	// Tags can be repeated and match any of them
+	if tags := r.URL.Query()[consts.QueryParamTag]; len(tags) > 0 {
+		filters = append(filters, query2.Term(query2.FieldTag, tags...))
 	}
 
-	// Check for tag filters (can be multiple)
-	tags := r.URL.Query()[consts.QueryParamTag]
-	if len(tags) > 0 {
-		filter = consts.FilterPrefixTags + strings.Join(tags, ",")
-		return
+	// Frontmatter conditions like status=active or priority>=2, all have to match
+	for _, where := range r.URL.Query()[consts.QueryParamWhere] {
+		whereFilter, whereErr := query2.ParseWhere(where)
+		if whereErr != nil {
+			return "", 0, "", whereErr
+		}
+		filters = append(filters, whereFilter.String())
 	}
 
 	// Get generic filter if provided
-	filter = r.URL.Query().Get(consts.QueryParamFilter)
+	if generic := r.URL.Query().Get(consts.QueryParamFilter); generic != "" {
+		filters = append(filters, generic)
+	}
+
+	filter = strings.Join(filters, " ")
 	return
 }
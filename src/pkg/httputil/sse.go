@@ -0,0 +1,113 @@
+package httputil
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EventStreamContentType is the content type of server-sent event streams
+const EventStreamContentType = "text/event-stream"
+
+// Event is a single server-sent event
+type Event struct {
+	Name string
+	Data []byte
+}
+
+// Decode parses the JSON data of the event into target
+func (e Event) Decode(target interface{}) error {
+	if err := json.Unmarshal(e.Data, target); err != nil {
+		return fmt.Errorf("failed to parse %s event: %w", e.Name, err)
+	}
+	return nil
+}
+
+// EventWriter writes server-sent events with JSON data to a response
+type EventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewEventWriter starts an event stream on w. It fails if the connection can't be
+// flushed, in which case nothing has been written yet.
+func NewEventWriter(w http.ResponseWriter) (*EventWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported by the connection")
+	}
+
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventWriter{w: w, flusher: flusher}, nil
+}
+
+// Send writes an event with data encoded as JSON and flushes it to the client
+func (e *EventWriter) Send(name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", name, err)
+	}
+
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", name, payload); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// Comment writes a comment line, which clients ignore. Sent periodically it keeps
+// idle streams from being closed by proxies and lets the server notice gone clients.
+func (e *EventWriter) Comment(text string) error {
+	if _, err := fmt.Fprintf(e.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// ReadEvents reads server-sent events from r and calls handle for each of them
+// until the stream ends or handle returns an error. Comments and fields other
+// than event and data are skipped.
+func ReadEvents(r io.Reader, handle func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event Event
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if len(data) > 0 {
+				event.Data = []byte(strings.Join(data, "\n"))
+				if event.Name == "" {
+					event.Name = "message"
+				}
+				if err := handle(event); err != nil {
+					return err
+				}
+			}
+			event, data = Event{}, nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return scanner.Err()
+}
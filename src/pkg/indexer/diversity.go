@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"obsfind/src/pkg/model"
+)
+
+// diversify reorders results with maximal marginal relevance. Each pick maximizes
+//
+//	(1-diversity) * relevance - diversity * max similarity to the results picked before
+//
+// where relevance is the score relative to the best one, so it works for every
+// search mode, and similarity is the cosine of the chunk vectors. Scores are
+// kept, only the order changes. Results without a vector keep their place
+// relative to each other after the ones that have one.
+func (s *Service) diversify(ctx context.Context, results []SearchResult, diversity float32) ([]SearchResult, error) {
+	if len(results) < 2 {
+		return results, nil
+	}
+
+	if err := s.loadVectors(ctx, results); err != nil {
+		return nil, fmt.Errorf("failed to load vectors for diversification: %w", err)
+	}
+
+	maxScore := results[0].Score
+	for _, result := range results {
+		if result.Score > maxScore {
+			maxScore = result.Score
+		}
+	}
+	if maxScore <= 0 {
+		maxScore = 1
+	}
+
+	lambda := 1 - float64(diversity)
+
+	var pending, missing []SearchResult
+	for _, result := range results {
+		if len(result.vector) == 0 {
+			missing = append(missing, result)
+		} else {
+			pending = append(pending, result)
+		}
+	}
+
+	// closest holds each pending result's highest similarity to the picked ones
+	closest := make([]float64, len(pending))
+	picked := make([]SearchResult, 0, len(results))
+
+	for len(pending) > 0 {
+		best := 0
+		bestValue := 0.0
+		for i, candidate := range pending {
+			value := lambda*candidate.Score/maxScore - (1-lambda)*closest[i]
+			if i == 0 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		choice := pending[best]
+		picked = append(picked, choice)
+
+		pending = append(pending[:best], pending[best+1:]...)
+		closest = append(closest[:best], closest[best+1:]...)
+
+		for i, candidate := range pending {
+			if similarity := model.CosineSimilarity(choice.vector, candidate.vector); similarity > closest[i] {
+				closest[i] = similarity
+			}
+		}
+	}
+
+	return append(picked, missing...), nil
+}
+
+// loadVectors fetches the vectors of results that came without one, such as keyword matches
+func (s *Service) loadVectors(ctx context.Context, results []SearchResult) error {
+	var ids []string
+	for _, result := range results {
+		if len(result.vector) == 0 && result.id != "" {
+			ids = append(ids, result.id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, idFilter(ids), true)
+	if err != nil {
+		return err
+	}
+
+	vectors := make(map[string][]float32, len(points))
+	for _, point := range points {
+		vectors[point.GetId().GetUuid()] = point.GetVectors().GetVector().GetData()
+	}
+
+	for i := range results {
+		if len(results[i].vector) == 0 {
+			results[i].vector = vectors[results[i].id]
+		}
+	}
+
+	return nil
+}
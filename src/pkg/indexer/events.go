@@ -0,0 +1,255 @@
+package indexer
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened during a vault indexing run
+type EventType string
+
+const (
+	// EventStarted opens a run, Total is the number of notes found
+	EventStarted EventType = "started"
+	// EventFileStarted is sent before a note is indexed
+	EventFileStarted EventType = "file_started"
+	// EventFileIndexed is sent after a note was indexed, Chunks is its chunk count
+	EventFileIndexed EventType = "file_indexed"
+	// EventFileFailed is sent when a note couldn't be indexed, see Error
+	EventFileFailed EventType = "file_failed"
+	// EventProgress reports the counters without a file of its own, e.g. for
+	// skipped notes or to catch up subscribers joining a run
+	EventProgress EventType = "progress"
+	// EventCompleted closes a run, Cancelled is set if it was stopped early and
+	// Error if it failed to start
+	EventCompleted EventType = "completed"
+)
+
+// eventBufferSize is how many events a subscriber may fall behind before old ones are dropped
+const eventBufferSize = 256
+
+// progressInterval limits how often unchanged, skipped notes are reported
+const progressInterval = 200 * time.Millisecond
+
+// IndexEvent reports the progress of a vault indexing run
+type IndexEvent struct {
+	Type      EventType `json:"type"`
+	Path      string    `json:"path,omitempty"`
+	Chunks    int       `json:"chunks,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Cancelled bool      `json:"cancelled,omitempty"`
+	Time      time.Time `json:"time"`
+	StartTime time.Time `json:"start_time"`
+
+	// Counters of the run so far, Processed counts indexed, skipped and failed notes
+	Total       int `json:"total"`
+	Processed   int `json:"processed"`
+	Indexed     int `json:"indexed"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+	TotalChunks int `json:"total_chunks"`
+
+	// ETASeconds estimates the remaining time from the pace so far
+	ETASeconds float64 `json:"eta_seconds,omitempty"`
+}
+
+// eventBus fans indexing events out to subscribers
+type eventBus struct {
+	mutex       sync.Mutex
+	subscribers map[chan IndexEvent]struct{}
+}
+
+// subscribe registers a new subscriber
+func (b *eventBus) subscribe() chan IndexEvent {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan IndexEvent]struct{})
+	}
+	ch := make(chan IndexEvent, eventBufferSize)
+	b.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a subscriber and closes its channel
+func (b *eventBus) unsubscribe(ch chan IndexEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish sends an event to every subscriber without blocking the indexer.
+// A subscriber that fell behind loses its oldest event, so the latest counters
+// and the completion always arrive.
+func (b *eventBus) publish(event IndexEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// runProgress tracks the current vault indexing run, guarded by Service.mutex
+type runProgress struct {
+	current      IndexEvent
+	active       bool
+	lastProgress time.Time
+}
+
+// Subscribe returns a channel receiving the events of vault indexing runs and a
+// function to stop receiving them. If a run is in progress the channel starts
+// with a progress event carrying its counters.
+func (s *Service) Subscribe() (<-chan IndexEvent, func()) {
+	ch := s.events.subscribe()
+
+	s.mutex.RLock()
+	if s.progress.active {
+		snapshot := s.progress.current
+		snapshot.Type = EventProgress
+		snapshot.Time = time.Now()
+		ch <- snapshot
+	}
+	s.mutex.RUnlock()
+
+	return ch, func() { s.events.unsubscribe(ch) }
+}
+
+// Progress returns the latest event of the current or last indexing run
+func (s *Service) Progress() IndexEvent {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.progress.current
+}
+
+// ReportFailure tells subscribers that a requested run failed before it could start,
+// so clients following it stop waiting
+func (s *Service) ReportFailure(err error) {
+	s.events.publish(IndexEvent{
+		Type:  EventCompleted,
+		Error: err.Error(),
+		Time:  time.Now(),
+	})
+}
+
+// startProgress begins tracking a run over total notes
+func (s *Service) startProgress(total int) {
+	now := time.Now()
+
+	s.mutex.Lock()
+	s.progress = runProgress{
+		current: IndexEvent{
+			Type:      EventStarted,
+			Time:      now,
+			StartTime: now,
+			Total:     total,
+		},
+		active: true,
+	}
+	event := s.progress.current
+	s.mutex.Unlock()
+
+	s.events.publish(event)
+}
+
+// trackProgress updates the counters of the run and publishes the event.
+// update is called with the mutex held. Progress events are throttled.
+func (s *Service) trackProgress(eventType EventType, path string, update func(event *IndexEvent)) {
+	now := time.Now()
+
+	s.mutex.Lock()
+	current := &s.progress.current
+	current.Type = eventType
+	current.Path = path
+	current.Chunks = 0
+	current.Error = ""
+	current.Time = now
+	if update != nil {
+		update(current)
+	}
+	current.Processed = current.Indexed + current.Skipped + current.Failed
+	current.ETASeconds = estimateRemaining(*current)
+
+	if eventType == EventProgress {
+		if now.Sub(s.progress.lastProgress) < progressInterval && current.Processed < current.Total {
+			s.mutex.Unlock()
+			return
+		}
+		s.progress.lastProgress = now
+	}
+
+	event := *current
+	s.mutex.Unlock()
+
+	s.events.publish(event)
+}
+
+// finishProgress closes the run
+func (s *Service) finishProgress(cancelled bool) {
+	s.mutex.Lock()
+	current := &s.progress.current
+	current.Type = EventCompleted
+	current.Path = ""
+	current.Chunks = 0
+	current.Time = time.Now()
+	current.ETASeconds = 0
+	current.Error = ""
+	current.Cancelled = cancelled
+	s.progress.active = false
+	event := *current
+	s.mutex.Unlock()
+
+	s.events.publish(event)
+}
+
+// estimateRemaining extrapolates the time per processed note to the rest of the run
+func estimateRemaining(event IndexEvent) float64 {
+	if event.Processed == 0 || event.Processed >= event.Total {
+		return 0
+	}
+	elapsed := event.Time.Sub(event.StartTime).Seconds()
+	return elapsed / float64(event.Processed) * float64(event.Total-event.Processed)
+}
+
+// countNotes counts the markdown files of the vaults, calling visit for each
+// if it isn't nil. Unreadable directories are skipped.
+func countNotes(vaultPaths []string, visit func(vaultPath, path string)) int {
+	count := 0
+	for _, vaultPath := range vaultPaths {
+		_ = filepath.WalkDir(vaultPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() && strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
+				count++
+				if visit != nil {
+					visit(vaultPath, path)
+				}
+			}
+			return nil
+		})
+	}
+	return count
+}
@@ -0,0 +1,574 @@
+package indexer
+
+import (
+	"context"
+	model2 "obsfind/src/pkg/model"
+	"obsfind/src/pkg/query"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// pathDirs returns every ancestor directory of a vault relative path, starting
+// with the vault root ".", e.g. "projects/2024/plan.md" gives
+// [".", "projects", "projects/2024"].
+// Qdrant has no prefix match, so path prefix filters match against this list.
+func pathDirs(relPath string) []string {
+	var dirs []string
+	dir := path.Dir(filepath.ToSlash(relPath))
+	for dir != "." && dir != "/" && dir != "" {
+		dirs = append([]string{dir}, dirs...)
+		dir = path.Dir(dir)
+	}
+	return append([]string{"."}, dirs...)
+}
+
+// normalizePathPrefix converts a path prefix to the form stored in path_dirs
+func normalizePathPrefix(prefix string) string {
+	prefix = filepath.ToSlash(strings.TrimSpace(prefix))
+	prefix = strings.TrimPrefix(prefix, "./")
+	return strings.Trim(prefix, "/")
+}
+
+// buildSearchFilter translates the tag, path and vault options into a Qdrant filter.
+// It returns nil when the options don't restrict the results.
+func buildSearchFilter(options SearchOptions) *pb.Filter {
+	var must []*pb.Condition
+
+	if len(options.Tags) > 0 {
+		if options.TagsMatchAll {
+			for _, tag := range options.Tags {
+				must = append(must, keywordCondition("tags", tag))
+			}
+		} else {
+			must = append(must, keywordsCondition("tags", options.Tags))
+		}
+	}
+
+	if normalizePathPrefix(options.PathPrefix) != "" {
+		// A prefix names either a folder or a single note
+		must = append(must, pathCondition(options.PathPrefix))
+	}
+
+	if options.Vault != "" {
+		must = append(must, keywordCondition("vault_name", options.Vault))
+	}
+
+	if len(must) == 0 {
+		return nil
+	}
+	return &pb.Filter{Must: must}
+}
+
+// keywordCondition matches payload fields equal to value, or containing it for arrays
+func keywordCondition(key, value string) *pb.Condition {
+	return fieldCondition(key, &pb.Match{MatchValue: &pb.Match_Keyword{Keyword: value}})
+}
+
+// keywordsCondition matches payload fields equal to any of the values
+func keywordsCondition(key string, values []string) *pb.Condition {
+	return fieldCondition(key, &pb.Match{
+		MatchValue: &pb.Match_Keywords{
+			Keywords: &pb.RepeatedStrings{Strings: values},
+		},
+	})
+}
+
+// queryFilter translates the filters, phrases and exclusions of a parsed query.
+// It returns nil when the query has none.
+func queryFilter(q *query.Query) *pb.Filter {
+	filter := &pb.Filter{}
+
+	for _, f := range q.Filters {
+		cond := filterCondition(f)
+		if f.Negated {
+			filter.MustNot = append(filter.MustNot, cond)
+		} else {
+			filter.Must = append(filter.Must, cond)
+		}
+	}
+
+	for _, phrase := range q.Phrases {
+		filter.Must = append(filter.Must, textCondition("text", phrase))
+	}
+	for _, excluded := range q.Excluded {
+		filter.MustNot = append(filter.MustNot, textCondition("text", excluded))
+	}
+
+	if len(filter.Must) == 0 && len(filter.MustNot) == 0 {
+		return nil
+	}
+	return filter
+}
+
+// filterCondition translates a single query filter into a condition
+func filterCondition(f query.Filter) *pb.Condition {
+	switch {
+	case f.Field == query.FieldTag:
+		return keywordsCondition("tags", f.Values)
+	case f.Field == query.FieldVault:
+		return keywordsCondition("vault_name", f.Values)
+	case f.Field == query.FieldLanguage:
+		return keywordsCondition("language", f.Values)
+	case f.Field == query.FieldPath:
+		conds := make([]*pb.Condition, len(f.Values))
+		for i, v := range f.Values {
+			conds[i] = pathCondition(v)
+		}
+		return anyCondition(conds)
+	case f.Field == query.FieldTitle:
+		conds := make([]*pb.Condition, len(f.Values))
+		for i, v := range f.Values {
+			conds[i] = textCondition("title", v)
+		}
+		return anyCondition(conds)
+	case f.IsDate():
+		return dateCondition(f)
+	default:
+		return frontmatterCondition(f)
+	}
+}
+
+// pathCondition matches notes inside a folder or the note at a path
+func pathCondition(prefix string) *pb.Condition {
+	prefix = normalizePathPrefix(prefix)
+	return anyCondition([]*pb.Condition{
+		keywordCondition("path_dirs", prefix),
+		keywordCondition("path", filepath.FromSlash(prefix)),
+	})
+}
+
+// dateCondition compares the created or modified timestamp of notes.
+// Dates name a period, so created:2024-01 matches all of January and
+// created:>2024-01 starts in February.
+func dateCondition(f query.Filter) *pb.Condition {
+	// The parser validated the dates already
+	start, end, _ := query.ParseDate(f.Values[0])
+
+	r := &pb.Range{}
+	switch f.Op {
+	case query.OpLess:
+		r.Lt = unixSeconds(start)
+	case query.OpLessEqual:
+		r.Lt = unixSeconds(end)
+	case query.OpGreater:
+		r.Gte = unixSeconds(end)
+	case query.OpGreaterEqual:
+		r.Gte = unixSeconds(start)
+	case query.OpRange:
+		_, rangeEnd, _ := query.ParseDate(f.Values[1])
+		r.Gte = unixSeconds(start)
+		r.Lt = unixSeconds(rangeEnd)
+	default:
+		conds := make([]*pb.Condition, len(f.Values))
+		for i, v := range f.Values {
+			start, end, _ := query.ParseDate(v)
+			conds[i] = rangeCondition(f.Field, &pb.Range{Gte: unixSeconds(start), Lt: unixSeconds(end)})
+		}
+		return anyCondition(conds)
+	}
+
+	return rangeCondition(f.Field, r)
+}
+
+// frontmatterCondition compares a frontmatter value.
+// Values are matched as text and, where they look like one, as a number, boolean
+// or date since frontmatter keeps the type YAML gave it.
+func frontmatterCondition(f query.Filter) *pb.Condition {
+	key := "fm_" + f.FrontmatterKey()
+
+	if f.Op != query.OpMatch {
+		// The parser only lets numbers and dates through for comparisons
+		if _, err := strconv.ParseFloat(f.Values[0], 64); err == nil {
+			return rangeCondition(key, numericRange(f))
+		}
+		return datetimeCondition(key, datetimeRange(f))
+	}
+
+	var conds []*pb.Condition
+	for _, v := range f.Values {
+		conds = append(conds, keywordCondition(key, v))
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			conds = append(conds, fieldCondition(key, &pb.Match{MatchValue: &pb.Match_Integer{Integer: n}}))
+		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+			// Matches only take integers, decimals match as a range of one value
+			conds = append(conds, rangeCondition(key, &pb.Range{Gte: &f, Lte: &f}))
+		}
+		if b, err := strconv.ParseBool(v); err == nil {
+			conds = append(conds, fieldCondition(key, &pb.Match{MatchValue: &pb.Match_Boolean{Boolean: b}}))
+		}
+		// A date also matches timestamps within its period, e.g. due:2025-01-01 matches 2025-01-01T10:00
+		if start, end, err := query.ParseDate(v); err == nil {
+			conds = append(conds, datetimeCondition(key, &pb.DatetimeRange{
+				Gte: timestamppb.New(utcWallClock(start)),
+				Lt:  timestamppb.New(utcWallClock(end)),
+			}))
+		}
+	}
+	return anyCondition(conds)
+}
+
+// numericRange builds the range of a numeric frontmatter comparison
+func numericRange(f query.Filter) *pb.Range {
+	first, _ := strconv.ParseFloat(f.Values[0], 64)
+	r := &pb.Range{}
+	switch f.Op {
+	case query.OpLess:
+		r.Lt = &first
+	case query.OpLessEqual:
+		r.Lte = &first
+	case query.OpGreater:
+		r.Gt = &first
+	case query.OpGreaterEqual:
+		r.Gte = &first
+	case query.OpRange:
+		second, _ := strconv.ParseFloat(f.Values[1], 64)
+		r.Gte = &first
+		r.Lte = &second
+	}
+	return r
+}
+
+// datetimeRange builds the range of a date frontmatter comparison.
+// Like created and modified, dates name a period: due:<=2025-01 includes all of January.
+func datetimeRange(f query.Filter) *pb.DatetimeRange {
+	start, end, _ := query.ParseDate(f.Values[0])
+	r := &pb.DatetimeRange{}
+	switch f.Op {
+	case query.OpLess:
+		r.Lt = timestamppb.New(utcWallClock(start))
+	case query.OpLessEqual:
+		r.Lt = timestamppb.New(utcWallClock(end))
+	case query.OpGreater:
+		r.Gte = timestamppb.New(utcWallClock(end))
+	case query.OpGreaterEqual:
+		r.Gte = timestamppb.New(utcWallClock(start))
+	case query.OpRange:
+		_, rangeEnd, _ := query.ParseDate(f.Values[1])
+		r.Gte = timestamppb.New(utcWallClock(start))
+		r.Lt = timestamppb.New(utcWallClock(rangeEnd))
+	}
+	return r
+}
+
+// utcWallClock keeps the clock reading of a local time but moves it to UTC.
+// Qdrant reads frontmatter dates without a zone as UTC, so bounds have to match that.
+func utcWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// datetimeCondition matches payload fields holding a date within the range
+func datetimeCondition(key string, r *pb.DatetimeRange) *pb.Condition {
+	return &pb.Condition{
+		ConditionOneOf: &pb.Condition_Field{
+			Field: &pb.FieldCondition{
+				Key:           key,
+				DatetimeRange: r,
+			},
+		},
+	}
+}
+
+// unixSeconds converts a time into the float bound of a range
+func unixSeconds(t time.Time) *float64 {
+	v := float64(t.Unix())
+	return &v
+}
+
+// rangeCondition matches numeric payload fields within the range
+func rangeCondition(key string, r *pb.Range) *pb.Condition {
+	return &pb.Condition{
+		ConditionOneOf: &pb.Condition_Field{
+			Field: &pb.FieldCondition{
+				Key:   key,
+				Range: r,
+			},
+		},
+	}
+}
+
+// textCondition matches payload fields containing the text.
+// Fields with a full-text index match when all words occur, others need the exact text.
+func textCondition(key, text string) *pb.Condition {
+	return fieldCondition(key, &pb.Match{MatchValue: &pb.Match_Text{Text: text}})
+}
+
+// fieldCondition wraps a match on a payload field
+func fieldCondition(key string, match *pb.Match) *pb.Condition {
+	return &pb.Condition{
+		ConditionOneOf: &pb.Condition_Field{
+			Field: &pb.FieldCondition{
+				Key:   key,
+				Match: match,
+			},
+		},
+	}
+}
+
+// anyCondition matches when any of the conditions does
+func anyCondition(conds []*pb.Condition) *pb.Condition {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return &pb.Condition{
+		ConditionOneOf: &pb.Condition_Filter{
+			Filter: &pb.Filter{Should: conds},
+		},
+	}
+}
+
+// idFilter matches the points with the given IDs
+func idFilter(ids []string) *pb.Filter {
+	pointIDs := make([]*pb.PointId, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = &pb.PointId{
+			PointIdOptions: &pb.PointId_Uuid{Uuid: id},
+		}
+	}
+
+	return &pb.Filter{
+		Must: []*pb.Condition{
+			{
+				ConditionOneOf: &pb.Condition_HasId{
+					HasId: &pb.HasIdCondition{HasId: pointIDs},
+				},
+			},
+		},
+	}
+}
+
+// mergeFilters combines filters so that all of them have to match, nil filters are skipped
+func mergeFilters(filters ...*pb.Filter) *pb.Filter {
+	var parts []*pb.Filter
+	for _, f := range filters {
+		if f != nil {
+			parts = append(parts, f)
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return parts[0]
+	}
+
+	merged := &pb.Filter{}
+	for _, f := range parts {
+		if len(f.Should) > 0 || f.MinShould != nil {
+			// Should clauses only mean something within their own filter
+			merged.Must = append(merged.Must, &pb.Condition{
+				ConditionOneOf: &pb.Condition_Filter{Filter: f},
+			})
+			continue
+		}
+		merged.Must = append(merged.Must, f.Must...)
+		merged.MustNot = append(merged.MustNot, f.MustNot...)
+	}
+	return merged
+}
+
+// autoIndexThreshold is how many searches filter on a frontmatter field before it gets a payload index
+const autoIndexThreshold = 3
+
+// trackFieldUsage counts the frontmatter fields a query filters on and creates
+// a payload index for fields that are filtered often, typed after the filter
+func (s *Service) trackFieldUsage(q *query.Query) {
+	s.fieldUsageMutex.Lock()
+	defer s.fieldUsageMutex.Unlock()
+
+	if s.fieldUsage == nil {
+		s.fieldUsage = make(map[string]int)
+		s.indexedFields = make(map[string]bool)
+	}
+
+	for _, f := range q.Filters {
+		if !f.IsFrontmatter() {
+			continue
+		}
+
+		key := "fm_" + f.FrontmatterKey()
+		if s.indexedFields[key] {
+			continue
+		}
+
+		s.fieldUsage[key]++
+		if s.fieldUsage[key] < autoIndexThreshold {
+			continue
+		}
+
+		s.indexedFields[key] = true
+		fieldType := frontmatterIndexType(f)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			if err := s.qdrantClient.CreatePayloadIndex(ctx, s.config.Qdrant.Collection, key, fieldType); err != nil {
+				log.Warn().Err(err).Str("field", key).Msg("Failed to index frequently filtered field")
+				return
+			}
+			log.Info().Str("field", key).Msg("Indexed frequently filtered field")
+		}()
+	}
+}
+
+// frontmatterIndexType picks the payload index type that serves a filter
+func frontmatterIndexType(f query.Filter) int {
+	if f.Op == query.OpMatch {
+		return model2.PayloadIndexKeyword
+	}
+	if _, err := strconv.ParseInt(f.Values[0], 10, 64); err == nil {
+		return model2.PayloadIndexInteger
+	}
+	if _, err := strconv.ParseFloat(f.Values[0], 64); err == nil {
+		return model2.PayloadIndexFloat
+	}
+	return model2.PayloadIndexDatetime
+}
+
+// frontmatterPayload converts a frontmatter value for the payload. Dates become
+// RFC 3339 text of their local clock time in UTC, which is how date filters
+// read them, numbers, checkboxes, lists and nested properties keep their type.
+func frontmatterPayload(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return utcWallClock(v).Format(time.RFC3339)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = frontmatterPayload(item)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			object[k] = frontmatterPayload(item)
+		}
+		return object
+	default:
+		return v
+	}
+}
+
+// noteTimes returns the created and modified timestamps stored with a note.
+// The created date comes from the created or date frontmatter key when it
+// holds a date, otherwise both fall back to the file's modification time.
+func noteTimes(modTime time.Time, frontmatter map[string]interface{}) (created, modified int64) {
+	modified = modTime.Unix()
+	created = modified
+
+	for _, key := range []string{"created", "date"} {
+		switch v := frontmatter[key].(type) {
+		case time.Time:
+			return v.Unix(), modified
+		case string:
+			if start, _, err := query.ParseDate(v); err == nil {
+				return start.Unix(), modified
+			}
+		}
+	}
+
+	return created, modified
+}
+
+// ensurePayloadFields adds the fields used by filters to chunks stored before
+// they existed, so filters also find notes that haven't changed since then
+func (s *Service) ensurePayloadFields(ctx context.Context) error {
+	s.payloadFieldsMutex.Lock()
+	defer s.payloadFieldsMutex.Unlock()
+
+	if s.payloadFieldsReady {
+		return nil
+	}
+
+	filter := &pb.Filter{
+		Should: []*pb.Condition{
+			{
+				ConditionOneOf: &pb.Condition_IsEmpty{
+					IsEmpty: &pb.IsEmptyCondition{Key: "path_dirs"},
+				},
+			},
+			{
+				ConditionOneOf: &pb.Condition_IsEmpty{
+					IsEmpty: &pb.IsEmptyCondition{Key: "modified"},
+				},
+			},
+		},
+	}
+
+	points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, filter, true)
+	if err != nil {
+		return err
+	}
+
+	// Chunks of a note share the file, so stat it once
+	modTimes := make(map[string]time.Time)
+
+	updated := make([]*pb.PointStruct, 0, len(points))
+	for _, point := range points {
+		vector := point.GetVectors().GetVector()
+		if vector == nil {
+			continue
+		}
+
+		relPath, _ := model2.GetPayloadString(point.Payload, "path")
+		fullPath, _ := model2.GetPayloadString(point.Payload, "full_path")
+
+		modTime, ok := modTimes[fullPath]
+		if !ok {
+			if info, err := os.Stat(fullPath); err == nil {
+				modTime = info.ModTime()
+			} else {
+				// Deleted notes are left to the garbage collector, their time doesn't matter
+				modTime = time.Now()
+			}
+			modTimes[fullPath] = modTime
+		}
+
+		frontmatter := make(map[string]interface{})
+		for _, key := range []string{"created", "date"} {
+			if v, ok := model2.GetPayloadString(point.Payload, "fm_"+key); ok {
+				frontmatter[key] = v
+			}
+		}
+		created, modified := noteTimes(modTime, frontmatter)
+
+		payload := point.Payload
+		for k, v := range model2.StructToPayload(map[string]interface{}{
+			"path_dirs": pathDirs(relPath),
+			"created":   created,
+			"modified":  modified,
+		}) {
+			payload[k] = v
+		}
+
+		updated = append(updated, &pb.PointStruct{
+			Id: point.GetId(),
+			Vectors: &pb.Vectors{
+				VectorsOptions: &pb.Vectors_Vector{
+					Vector: &pb.Vector{
+						Data: vector.Data,
+					},
+				},
+			},
+			Payload: payload,
+		})
+	}
+
+	if len(updated) > 0 {
+		if err := s.qdrantClient.UpsertPoints(ctx, s.config.Qdrant.Collection, updated); err != nil {
+			return err
+		}
+		log.Info().Int("chunks", len(updated)).Msg("Added filter fields to indexed chunks")
+	}
+
+	s.payloadFieldsReady = true
+	return nil
+}
@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	model2 "obsfind/src/pkg/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// GCResult describes the outcome of a garbage collection sweep
+type GCResult struct {
+	ScannedPoints int      `json:"scanned_points"`
+	RemovedPoints int      `json:"removed_points"`
+	RemovedFiles  []string `json:"removed_files,omitempty"`
+	DryRun        bool     `json:"dry_run,omitempty"`
+}
+
+// GarbageCollect sweeps the whole collection for points whose file no longer
+// exists on disk and deletes them. With dryRun set nothing is deleted.
+func (s *Service) GarbageCollect(ctx context.Context, dryRun bool) (*GCResult, error) {
+	points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed points: %w", err)
+	}
+
+	result := &GCResult{
+		ScannedPoints: len(points),
+		DryRun:        dryRun,
+	}
+
+	// Group point IDs by the file they belong to so each file is checked once
+	idsByFile := make(map[string][]string)
+	for _, point := range points {
+		fullPath, ok := model2.GetPayloadString(point.Payload, "full_path")
+		if !ok || fullPath == "" {
+			continue
+		}
+
+		if id := point.GetId().GetUuid(); id != "" {
+			idsByFile[fullPath] = append(idsByFile[fullPath], id)
+		}
+	}
+
+	var staleIDs []string
+	for fullPath, ids := range idsByFile {
+		if _, err := os.Stat(fullPath); err == nil || !os.IsNotExist(err) {
+			continue
+		}
+
+		result.RemovedFiles = append(result.RemovedFiles, fullPath)
+		staleIDs = append(staleIDs, ids...)
+	}
+
+	sort.Strings(result.RemovedFiles)
+	result.RemovedPoints = len(staleIDs)
+
+	if dryRun || len(staleIDs) == 0 {
+		return result, nil
+	}
+
+	if err := s.qdrantClient.DeletePoints(ctx, s.config.Qdrant.Collection, staleIDs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageFailed, err)
+	}
+
+	s.keywords.Remove(staleIDs...)
+	for _, fullPath := range result.RemovedFiles {
+		s.manifest.Delete(fullPath)
+	}
+	s.saveIndexState()
+
+	log.Info().
+		Int("files", len(result.RemovedFiles)).
+		Int("points", result.RemovedPoints).
+		Msg("Garbage collection removed stale points")
+
+	return result, nil
+}
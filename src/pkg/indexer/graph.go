@@ -0,0 +1,262 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"obsfind/src/pkg/model"
+	"path/filepath"
+	"sort"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+const (
+	// graphSeedCount is how many of the best results count as top hits for link proximity
+	graphSeedCount = 5
+	// proximityWeight and hubWeight split the graph boost between being linked
+	// with a top hit and having many backlinks
+	proximityWeight = 0.7
+	hubWeight       = 0.3
+	// expandPerSeed limits the notes added for each top hit when expanding links
+	expandPerSeed = 3
+	// expandScoreFactor scales the score of a top hit for the notes added from its links
+	expandScoreFactor = 0.5
+)
+
+// noteNeighbors are the notes a note links to and those linking to it, by full path
+type noteNeighbors struct {
+	linked    map[string]bool
+	backlinks map[string]bool
+	headings  map[string]string // heading of the first link to each linked note
+}
+
+// boostByLinks raises the score of results by their place in the link graph:
+//
+//	score * (1 + graphBoost * (0.7 * proximity + 0.3 * hub))
+//
+// proximity is the relative score of the best top hit the result links to or
+// is linked from, and hub the result's number of linking notes on a log scale
+// relative to the best connected result. With expand, the notes linked to or
+// from the top hits that didn't match are added with part of the hit's score.
+func (s *Service) boostByLinks(ctx context.Context, results []SearchResult, filter *pb.Filter, graphBoost float32, expand bool) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	maxScore := results[0].Score
+	for _, result := range results {
+		if result.Score > maxScore {
+			maxScore = result.Score
+		}
+	}
+	if maxScore <= 0 {
+		maxScore = 1
+	}
+
+	seeds := topNotes(results, graphSeedCount)
+	neighbors := make(map[string]noteNeighbors)
+	for _, result := range results {
+		if _, ok := neighbors[result.fullPath]; !ok && result.fullPath != "" {
+			neighbors[result.fullPath] = s.noteNeighbors(result.fullPath)
+		}
+	}
+
+	if graphBoost > 0 {
+		maxHub := 0.0
+		for _, n := range neighbors {
+			maxHub = math.Max(maxHub, math.Log1p(float64(len(n.backlinks))))
+		}
+
+		for i := range results {
+			n, ok := neighbors[results[i].fullPath]
+			if !ok {
+				continue
+			}
+
+			proximity := 0.0
+			for _, seed := range seeds {
+				if seed.fullPath != results[i].fullPath && (n.linked[seed.fullPath] || n.backlinks[seed.fullPath]) {
+					proximity = math.Max(proximity, seed.Score/maxScore)
+				}
+			}
+
+			hub := 0.0
+			if maxHub > 0 {
+				hub = math.Log1p(float64(len(n.backlinks))) / maxHub
+			}
+
+			results[i].Score *= 1 + float64(graphBoost)*(proximityWeight*proximity+hubWeight*hub)
+		}
+	}
+
+	if expand {
+		expanded, err := s.expandLinks(ctx, results, seeds, neighbors, filter)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, expanded...)
+	}
+
+	// Stable sort keeps the previous order for equal scores
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+// expandLinks returns a result for each note linked to or from the seeds that
+// isn't among the results yet and matches the filter. Notes are represented by
+// the section a seed links to, or by their first chunk.
+func (s *Service) expandLinks(ctx context.Context, results, seeds []SearchResult, neighbors map[string]noteNeighbors, filter *pb.Filter) ([]SearchResult, error) {
+	present := make(map[string]bool, len(results))
+	for _, result := range results {
+		present[result.fullPath] = true
+	}
+
+	// Candidate notes with the seed that leads to them, best seeds first
+	from := make(map[string]SearchResult)
+	var paths []string
+	for _, seed := range seeds {
+		n := neighbors[seed.fullPath]
+		added := 0
+		for _, path := range sortedPaths(n.linked, n.backlinks) {
+			if added >= expandPerSeed {
+				break
+			}
+			if present[path] {
+				continue
+			}
+			if _, ok := from[path]; ok {
+				continue
+			}
+			from[path] = seed
+			paths = append(paths, path)
+			added++
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	notesFilter := &pb.Filter{Must: []*pb.Condition{keywordsCondition("full_path", paths)}}
+	points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, mergeFilters(filter, notesFilter), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load linked notes: %w", err)
+	}
+
+	// Pick the chunk under the linked heading, otherwise the first one
+	best := make(map[string]*pb.RetrievedPoint)
+	for _, point := range points {
+		path, _ := model.GetPayloadString(point.Payload, "full_path")
+		seed, ok := from[path]
+		if !ok {
+			continue
+		}
+
+		heading := neighbors[seed.fullPath].headings[path]
+		if current, ok := best[path]; !ok || betterLinkedChunk(point, current, heading) {
+			best[path] = point
+		}
+	}
+
+	var expanded []SearchResult
+	for _, path := range paths {
+		point, ok := best[path]
+		if !ok {
+			continue
+		}
+
+		seed := from[path]
+		result := payloadToResult(point.Payload, seed.Score*expandScoreFactor)
+		result.id = point.GetId().GetUuid()
+		result.Metadata["linked_from"] = seed.Path
+		if len(seed.Sections) > 0 {
+			// Grouped results are notes with their sections
+			result.BestScore = result.Score
+			result.Sections = []SearchResult{result}
+		}
+		expanded = append(expanded, result)
+	}
+
+	return expanded, nil
+}
+
+// betterLinkedChunk reports whether chunk a represents a linked note better than
+// chunk b: being under the linked heading comes first, then coming first in the note
+func betterLinkedChunk(a, b *pb.RetrievedPoint, heading string) bool {
+	sectionA, _ := model.GetPayloadString(a.Payload, "section")
+	sectionB, _ := model.GetPayloadString(b.Payload, "section")
+	if heading != "" && (sectionA == heading) != (sectionB == heading) {
+		return sectionA == heading
+	}
+
+	indexA, _ := model.GetPayloadInt(a.Payload, "chunk_index")
+	indexB, _ := model.GetPayloadInt(b.Payload, "chunk_index")
+	return indexA < indexB
+}
+
+// noteNeighbors looks up the links of a note in both directions
+func (s *Service) noteNeighbors(fullPath string) noteNeighbors {
+	vaultPath := s.findBaseVaultPath(fullPath)
+	n := noteNeighbors{
+		linked:    make(map[string]bool),
+		backlinks: make(map[string]bool),
+		headings:  make(map[string]string),
+	}
+
+	for _, link := range s.links.Links(fullPath) {
+		if link.Resolved == "" {
+			continue
+		}
+		target := filepath.Join(vaultPath, filepath.FromSlash(link.Resolved))
+		if target == fullPath {
+			continue
+		}
+		if !n.linked[target] {
+			n.linked[target] = true
+			n.headings[target] = link.Heading
+		}
+	}
+
+	for _, backlink := range s.links.Backlinks(fullPath) {
+		n.backlinks[filepath.Join(vaultPath, filepath.FromSlash(backlink.Source))] = true
+	}
+
+	return n
+}
+
+// topNotes returns the first results of distinct notes, up to count
+func topNotes(results []SearchResult, count int) []SearchResult {
+	var top []SearchResult
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if len(top) >= count {
+			break
+		}
+		if result.fullPath == "" || seen[result.fullPath] {
+			continue
+		}
+		seen[result.fullPath] = true
+		top = append(top, result)
+	}
+	return top
+}
+
+// sortedPaths returns the paths of both sets once, in alphabetical order
+func sortedPaths(sets ...map[string]bool) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, set := range sets {
+		for path := range set {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
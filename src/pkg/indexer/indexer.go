@@ -39,7 +39,11 @@ type Stats struct {
 	TotalDocuments   int                `json:"total_documents"`
 	IndexedDocuments int                `json:"indexed_documents"`
 	FailedDocuments  int                `json:"failed_documents"`
-	Status           string             `json:"status"` // "idle", "indexing", "error"
+	SkippedDocuments int                `json:"skipped_documents"`
+	RemovedDocuments int                `json:"removed_documents"`
+	QueuedJobs       int                `json:"queued_jobs"`
+	Status           string             `json:"status"`              // "idle", "indexing", "error"
+	Cancelled        bool               `json:"cancelled,omitempty"` // The last run was cancelled, the counts are partial
 	Documents        []DocumentStatus   `json:"documents,omitempty"`
 	LastError        string             `json:"last_error,omitempty"`
 	LastRun          time.Time          `json:"last_run,omitempty"`
@@ -57,21 +61,89 @@ type Service struct {
 	indexingCtx    context.Context
 	cancelIndexing context.CancelFunc
 	stats          Stats
+	manifest       *Manifest
+	keywords       *KeywordIndex
+	links          *LinkGraph
+
+	// reranker grades the best search candidates once more, nil disables reranking
+	reranker model2.Reranker
+
+	// events publishes the progress of vault indexing runs, see events.go
+	events   eventBus
+	progress runProgress
+
+	// queue holds the indexing work processed by the workers, see jobs.go
+	queue        *JobQueue
+	workers      sync.WaitGroup
+	workersCtx   context.Context
+	stopWorkers  context.CancelFunc
+	run          *indexRun
+	jobsMutex    sync.Mutex
+	waiters      map[uint64][]chan error
+	jobsFinished int
+
+	// pipeline embeds and stores the notes prepared by the workers, see pipeline.go
+	pipeline *pipeline
+
+	// payloadFieldsReady is set once chunks from older versions have all filter fields
+	payloadFieldsMutex sync.Mutex
+	payloadFieldsReady bool
+
+	// fieldUsage counts searches per filtered frontmatter field, see trackFieldUsage
+	fieldUsageMutex sync.Mutex
+	fieldUsage      map[string]int
+	indexedFields   map[string]bool
 }
 
 // NewService creates a new indexer service
 func NewService(cfg *config.Config, embedder model2.Embedder, qdrantClient model2.QdrantClient) *Service {
+	manifest, err := LoadManifest(filepath.Join(cfg.General.DataDir, ManifestFileName))
+	if err != nil {
+		// A broken manifest only costs a full reindex, so start over
+		log.Warn().Err(err).Msg("Ignoring unreadable index manifest")
+	}
+
+	keywords, err := LoadKeywordIndex(filepath.Join(cfg.General.DataDir, KeywordIndexFileName))
+	if err != nil {
+		// The keyword index is rebuilt from the stored chunks on first use
+		log.Warn().Err(err).Msg("Ignoring unreadable keyword index")
+	}
+
+	links, err := LoadLinkGraph(filepath.Join(cfg.General.DataDir, LinkGraphFileName))
+	if err != nil {
+		// Links are read again from the notes by the next reindex
+		log.Warn().Err(err).Msg("Ignoring unreadable link graph")
+	}
+
+	queue, err := LoadJobQueue(filepath.Join(cfg.General.DataDir, QueueFileName))
+	if err != nil {
+		// Lost jobs are caught up by the next reindex, which skips unchanged notes
+		log.Warn().Err(err).Msg("Ignoring unreadable indexing job queue")
+	}
+
 	return &Service{
 		config:       cfg,
 		embedder:     embedder,
 		qdrantClient: qdrantClient,
 		parser:       markdown.NewParser(),
+		manifest:     manifest,
+		keywords:     keywords,
+		links:        links,
+		queue:        queue,
+		waiters:      make(map[uint64][]chan error),
 		stats: Stats{
 			Status: "idle",
 		},
 	}
 }
 
+// SetReranker enables reranking of search results with the given reranker, nil disables it
+func (s *Service) SetReranker(reranker model2.Reranker) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reranker = reranker
+}
+
 // GetStats returns the current indexing statistics
 func (s *Service) GetStats() Stats {
 	s.mutex.RLock()
@@ -86,7 +158,9 @@ func (s *Service) GetStats() Stats {
 		s.stats.CollectionInfo = collInfo
 	}
 
-	return s.stats
+	stats := s.stats
+	stats.QueuedJobs = s.queue.Len()
+	return stats
 }
 
 // IsIndexing returns true if an indexing operation is in progress
@@ -96,37 +170,74 @@ func (s *Service) IsIndexing() bool {
 	return s.isIndexing
 }
 
-// IndexVault indexes the entire vault
+// IndexVault indexes the entire vault. Changed notes are queued for the workers
+// and the call returns once they are all processed or the run is cancelled.
 func (s *Service) IndexVault(ctx context.Context) error {
 	s.mutex.Lock()
 	if s.isIndexing {
 		s.mutex.Unlock()
 		return ErrIndexingInProgress
 	}
+	if s.workersCtx == nil || s.workersCtx.Err() != nil {
+		s.mutex.Unlock()
+		return ErrWorkersStopped
+	}
+	workersCtx := s.workersCtx
 
 	s.isIndexing = true
 	s.indexingCtx, s.cancelIndexing = context.WithCancel(ctx)
+	run := &indexRun{id: time.Now().UnixNano(), ctx: s.indexingCtx}
+	s.run = run
 	s.stats.Status = "indexing"
 	s.stats.LastRun = time.Now()
 	s.stats.Documents = []DocumentStatus{}
+	s.stats.TotalDocuments = 0
+	s.stats.IndexedDocuments = 0
+	s.stats.FailedDocuments = 0
+	s.stats.SkippedDocuments = 0
+	s.stats.RemovedDocuments = 0
+	s.stats.Cancelled = false
 	s.mutex.Unlock()
 
+	// Chunks indexed before the keyword index existed have to be added once
+	if err := s.ensureKeywordIndex(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to rebuild keyword index")
+	}
+
+	// Get all vault paths
+	vaultPaths := s.config.GetVaultPaths()
+
+	// Counting first gives followers a total to measure progress against, and
+	// registering the notes lets links resolve to notes that aren't indexed yet
+	s.startProgress(countNotes(vaultPaths, s.links.AddNote))
+
 	defer func() {
+		s.saveIndexState()
+
+		cancelled := s.indexingCtx.Err() != nil
+
 		s.mutex.Lock()
 		s.isIndexing = false
+		s.run = nil
+		s.stats.Cancelled = cancelled
 		if s.stats.FailedDocuments > 0 {
 			s.stats.Status = "error"
 		} else {
 			s.stats.Status = "idle"
 		}
 		s.mutex.Unlock()
-	}()
 
-	// Get all vault paths
-	vaultPaths := s.config.GetVaultPaths()
+		s.finishProgress(cancelled)
+
+		if cancelled {
+			log.Info().Msg("Indexing cancelled, the remaining notes are indexed on the next run")
+		}
+	}()
 
 	// Process each vault path
 	for _, vaultPath := range vaultPaths {
+		seen := make(map[string]bool)
+
 		// Walk the vault directory
 		err := filepath.WalkDir(vaultPath, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
@@ -145,61 +256,164 @@ func (s *Service) IndexVault(ctx context.Context) error {
 				return nil
 			}
 
-			// Index the file
-			docStatus := DocumentStatus{
-				Path:      path,
-				UpdatedAt: time.Now(),
-			}
+			seen[path] = true
 
 			s.mutex.Lock()
 			s.stats.TotalDocuments++
 			s.mutex.Unlock()
 
-			if err := s.indexFile(s.indexingCtx, path, vaultPath); err != nil {
-				docStatus.Error = err.Error()
+			// Skip files that haven't changed since they were last indexed
+			if info, err := d.Info(); err == nil && s.isUnchanged(path, info) {
+				// Notes indexed before links were recorded only need their links read
+				if !s.links.Has(path) {
+					if err := s.updateLinks(vaultPath, path); err != nil {
+						log.Warn().Err(err).Str("path", path).Msg("Failed to read links")
+					}
+				}
 
 				s.mutex.Lock()
-				s.stats.FailedDocuments++
+				s.stats.SkippedDocuments++
 				s.mutex.Unlock()
 
-				log.Error().Err(err).Str("path", path).Msg("Failed to index file")
-			} else {
-				docStatus.Indexed = true
-
-				s.mutex.Lock()
-				s.stats.IndexedDocuments++
-				s.mutex.Unlock()
-
-				log.Debug().Str("path", path).Msg("Indexed file successfully")
+				s.trackProgress(EventProgress, path, func(event *IndexEvent) {
+					event.Skipped++
+				})
+				return nil
 			}
 
-			s.mutex.Lock()
-			s.stats.Documents = append(s.stats.Documents, docStatus)
-			s.mutex.Unlock()
-
+			s.queueRunJob(run, Job{Type: JobIndex, Path: path, Priority: PriorityReindex})
 			return nil
 		})
 
+		if s.indexingCtx.Err() != nil {
+			break
+		}
+
 		if err != nil {
 			// Log the error but continue with other vault paths
 			log.Error().Err(err).Str("vaultPath", vaultPath).Msg("Error indexing vault path")
+			continue
+		}
+
+		// Only a complete walk tells us which files are gone
+		s.removeVanishedFiles(run, vaultPath, seen)
+	}
+
+	// Wait for the workers to get through the queued notes
+	finished := make(chan struct{})
+	go func() {
+		run.jobs.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-workersCtx.Done():
+		// Shutting down, the queued notes are indexed after the next start
+		return nil
+	case <-s.indexingCtx.Done():
+		dropped, detached := s.queue.DropRun(run.id)
+		for _, job := range dropped {
+			s.finishJob(job, run, false, s.indexingCtx.Err(), true)
+		}
+		// Jobs carrying single changes stay queued, but no longer count for the run
+		for range detached {
+			run.jobs.Done()
+		}
+
+		// Notes being indexed stop before their next embedding batch
+		select {
+		case <-finished:
+		case <-workersCtx.Done():
 		}
 	}
 
 	return nil
 }
 
-// IndexFile indexes a single file
-func (s *Service) IndexFile(ctx context.Context, path string) error {
+// removeVanishedFiles queues the removal of recorded files in the vault that
+// were not seen during the last walk
+func (s *Service) removeVanishedFiles(run *indexRun, vaultPath string, seen map[string]bool) {
+	for _, path := range s.manifest.Paths() {
+		if seen[path] || s.findBaseVaultPath(path) != vaultPath {
+			continue
+		}
+
+		s.queueRunJob(run, Job{Type: JobDelete, Path: path, Priority: PriorityReindex})
+		log.Debug().Str("path", path).Msg("Queued removal of vanished file")
+	}
+
+	s.links.Prune(vaultPath, seen)
+}
+
+// isUnchanged reports whether a file still matches its manifest entry.
+// Size and modification time are checked first, the content hash is only
+// computed when they differ.
+func (s *Service) isUnchanged(path string, info fs.FileInfo) bool {
+	entry, ok := s.manifest.Get(path)
+	if !ok || entry.EmbeddingModel != s.embedder.Name() || entry.Size != info.Size() {
+		return false
+	}
+
+	if entry.ModTime.Equal(info.ModTime()) {
+		return true
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || hashContent(content) != entry.Hash {
+		return false
+	}
+
+	// Touched but not edited, remember the new time so the next run skips the read
+	entry.ModTime = info.ModTime()
+	s.manifest.Set(entry)
+	return true
+}
+
+// ResetManifest forgets all indexed files so the next run indexes everything
+func (s *Service) ResetManifest() error {
+	s.manifest.Reset()
+	s.keywords.Reset()
+
+	if err := s.keywords.Save(); err != nil {
+		return err
+	}
+	return s.manifest.Save()
+}
+
+// saveIndexState persists the manifest and the link graph. The keyword index
+// saves itself after a delay and is flushed by Close.
+func (s *Service) saveIndexState() {
+	if err := s.manifest.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to save index manifest")
+	}
+	if err := s.links.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to save link graph")
+	}
+}
+
+// IndexFile indexes a single file.
+// Unless force is set, files that haven't changed since they were last indexed are skipped.
+func (s *Service) IndexFile(ctx context.Context, path string, force bool) error {
 	if !strings.HasSuffix(strings.ToLower(path), ".md") {
 		return fmt.Errorf("%w: not a markdown file", ErrInvalidPath)
 	}
 
 	// Check if file exists
-	if _, err := os.Stat(path); err != nil {
+	info, err := os.Stat(path)
+	if err != nil {
 		return fmt.Errorf("%w: %v", ErrInvalidPath, err)
 	}
 
+	if !force && s.isUnchanged(path, info) {
+		log.Debug().Str("path", path).Msg("File unchanged, skipping")
+		return nil
+	}
+
+	defer func() {
+		s.saveIndexState()
+	}()
+
 	// Determine the base vault path for this file
 	basePath := s.findBaseVaultPath(path)
 
@@ -244,76 +458,358 @@ func (s *Service) findBaseVaultPath(path string) string {
 	return ""
 }
 
-// CancelIndexing cancels an ongoing indexing operation
-func (s *Service) CancelIndexing() {
+// CancelIndexing cancels an ongoing indexing operation and reports whether there was one.
+// The run stops before the next note or embedding batch, keeping what it indexed so far.
+func (s *Service) CancelIndexing() bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if s.isIndexing && s.cancelIndexing != nil {
-		s.cancelIndexing()
-		s.stats.Status = "idle"
+	if !s.isIndexing || s.cancelIndexing == nil {
+		return false
 	}
+
+	s.cancelIndexing()
+	return true
 }
 
-// indexFile indexes a single file (internal implementation)
-func (s *Service) indexFile(ctx context.Context, path string, basePath string) error {
-	// Read the file
-	content, err := os.ReadFile(path)
+// RemoveFile deletes all points belonging to the given file from the index
+func (s *Service) RemoveFile(ctx context.Context, path string) error {
+	// Looked up while the note still resolves
+	s.reindexEmbedders(path)
+
+	points, err := s.filePoints(ctx, path, false)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return err
 	}
 
-	// Parse the markdown
-	doc, err := s.parser.Parse(string(content))
+	if len(points) == 0 {
+		s.manifest.Delete(path)
+		s.links.Remove(path)
+		return nil
+	}
+
+	ids := make([]string, 0, len(points))
+	for _, point := range points {
+		if id := point.GetId().GetUuid(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	if err := s.qdrantClient.DeletePoints(ctx, s.config.Qdrant.Collection, ids); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageFailed, err)
+	}
+
+	s.keywords.Remove(ids...)
+	s.manifest.Delete(path)
+	s.links.Remove(path)
+	s.saveIndexState()
+
+	log.Debug().Str("path", path).Int("points", len(ids)).Msg("Removed file from index")
+	return nil
+}
+
+// RenameFile moves the points of a renamed file to its new path.
+// The stored vectors are reused, so the note is not re-embedded.
+func (s *Service) RenameFile(ctx context.Context, oldPath, newPath string) error {
+	if !strings.HasSuffix(strings.ToLower(newPath), ".md") {
+		// Renamed to something we don't index, so just drop the old entries
+		return s.RemoveFile(ctx, oldPath)
+	}
+
+	points, err := s.filePoints(ctx, oldPath, true)
 	if err != nil {
-		return fmt.Errorf("failed to parse markdown: %w", err)
+		return err
 	}
 
-	// Choose chunking strategy based on config
-	var chunks []*markdown.Chunk
-	switch s.config.Indexing.ChunkStrategy {
-	case "header":
-		chunks = s.parser.ChunkByHeaders(doc)
-	case "sliding_window":
-		chunks = s.parser.ChunkBySlidingWindow(doc, s.config.Indexing.MaxChunkSize, s.config.Indexing.WindowOverlap)
-	case "hybrid":
-		chunks = s.parser.ChunkHybrid(doc, s.config.Indexing.MaxChunkSize, s.config.Indexing.WindowOverlap)
-	default:
-		chunks = s.parser.ChunkHybrid(doc, s.config.Indexing.MaxChunkSize, s.config.Indexing.WindowOverlap)
+	// Nothing indexed under the old name, index the file from scratch
+	if len(points) == 0 {
+		return s.IndexFile(ctx, newPath, true)
+	}
+
+	basePath := s.findBaseVaultPath(newPath)
+	relPath, err := filepath.Rel(basePath, newPath)
+	if err != nil {
+		relPath = newPath
+	}
+	vaultName := filepath.Base(basePath)
+
+	// Only the location fields change, everything else is carried over
+	locationPayload := model2.StructToPayload(map[string]interface{}{
+		"path":       relPath,
+		"path_dirs":  pathDirs(relPath),
+		"full_path":  newPath,
+		"vault_path": basePath,
+		"vault_name": vaultName,
+	})
+
+	newPoints := make([]*pb.PointStruct, 0, len(points))
+	oldIDs := make([]string, 0, len(points))
+
+	for _, point := range points {
+		vector := point.GetVectors().GetVector()
+		if vector == nil {
+			// Without a stored vector we can't move the point, fall back to reindexing
+			log.Warn().Str("path", oldPath).Msg("Missing vector for renamed file, reindexing")
+			if err := s.RemoveFile(ctx, oldPath); err != nil {
+				return err
+			}
+			return s.IndexFile(ctx, newPath, true)
+		}
+
+		chunkIndex, _ := model2.GetPayloadInt(point.Payload, "chunk_index")
+		newID := chunkPointID(vaultName, relPath, chunkIndex)
+
+		payload := point.Payload
+		for k, v := range locationPayload {
+			payload[k] = v
+		}
+
+		newPoints = append(newPoints, &pb.PointStruct{
+			Id: &pb.PointId{
+				PointIdOptions: &pb.PointId_Uuid{
+					Uuid: newID,
+				},
+			},
+			Vectors: &pb.Vectors{
+				VectorsOptions: &pb.Vectors_Vector{
+					Vector: &pb.Vector{
+						Data: vector.Data,
+					},
+				},
+			},
+			Payload: payload,
+		})
+
+		if id := point.GetId().GetUuid(); id != "" {
+			oldIDs = append(oldIDs, id)
+		}
+	}
+
+	// Write the new points before deleting the old ones so the note never disappears from search
+	if err := s.qdrantClient.UpsertPoints(ctx, s.config.Qdrant.Collection, newPoints); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageFailed, err)
+	}
+
+	if err := s.qdrantClient.DeletePoints(ctx, s.config.Qdrant.Collection, oldIDs); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageFailed, err)
+	}
+
+	s.keywords.Remove(oldIDs...)
+	for _, point := range newPoints {
+		s.keywords.Add(point.GetId().GetUuid(), keywordText(point.Payload))
+	}
+
+	// Carry the manifest entry over so the renamed file isn't re-embedded on the next run
+	if entry, ok := s.manifest.Get(oldPath); ok {
+		s.manifest.Delete(oldPath)
+		if info, err := os.Stat(newPath); err == nil {
+			entry.Path = newPath
+			entry.ModTime = info.ModTime()
+			s.manifest.Set(entry)
+		}
+	}
+	s.links.Rename(basePath, oldPath, newPath)
+	s.saveIndexState()
+
+	log.Debug().Str("from", oldPath).Str("to", newPath).Int("points", len(newPoints)).Msg("Moved file in index")
+	return nil
+}
+
+// filePoints returns the stored points for a file, identified by its full path,
+// with their vectors if withVectors is set
+func (s *Service) filePoints(ctx context.Context, path string, withVectors bool) ([]*pb.RetrievedPoint, error) {
+	basePath := s.findBaseVaultPath(path)
+	relPath, err := filepath.Rel(basePath, path)
+	if err != nil {
+		relPath = path
 	}
 
-	if len(chunks) == 0 {
-		log.Warn().Str("path", path).Msg("No chunks generated for file")
+	points, err := s.qdrantClient.GetPointsByPath(ctx, s.config.Qdrant.Collection, relPath, withVectors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up indexed points: %w", err)
+	}
+
+	// The relative path can collide across vaults, so narrow down by full path
+	result := make([]*pb.RetrievedPoint, 0, len(points))
+	for _, point := range points {
+		fullPath, ok := model2.GetPayloadString(point.Payload, "full_path")
+		if ok && fullPath != path {
+			continue
+		}
+		result = append(result, point)
+	}
+
+	return result, nil
+}
+
+// purgeStaleChunks deletes the points of a file that are not in the keep set
+func (s *Service) purgeStaleChunks(ctx context.Context, path string, keep map[string]bool) error {
+	points, err := s.filePoints(ctx, path, false)
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, point := range points {
+		if id := point.GetId().GetUuid(); id != "" && !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+
+	if len(stale) == 0 {
 		return nil
 	}
 
-	// Prepare texts for embedding
-	texts := make([]string, len(chunks))
-	for i, chunk := range chunks {
+	if err := s.qdrantClient.DeletePoints(ctx, s.config.Qdrant.Collection, stale); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageFailed, err)
+	}
+
+	s.keywords.Remove(stale...)
+
+	log.Debug().Str("path", path).Int("points", len(stale)).Msg("Purged stale chunks")
+	return nil
+}
+
+// chunkPointID returns the deterministic point ID of a chunk.
+// The vault name is included to avoid collisions between vaults.
+func chunkPointID(vaultName, relPath string, chunkIndex int) string {
+	return model2.HashString(fmt.Sprintf("%s:%s#%d", vaultName, relPath, chunkIndex))
+}
+
+// preparedFile is a note read, parsed and chunked, ready to be embedded
+type preparedFile struct {
+	path      string
+	basePath  string
+	relPath   string
+	vaultName string
+	content   []byte
+	doc       *markdown.Document
+	chunks    []*markdown.Chunk
+	created   int64
+	modified  int64
+}
+
+// texts returns the chunk contents to embed
+func (f *preparedFile) texts() []string {
+	texts := make([]string, len(f.chunks))
+	for i, chunk := range f.chunks {
 		texts[i] = chunk.Content
 	}
+	return texts
+}
 
+// indexFile indexes a single file (internal implementation)
+func (s *Service) indexFile(ctx context.Context, path string, basePath string) error {
+	file, err := s.prepareFile(path, basePath)
+	if err != nil {
+		return err
+	}
+
+	if len(file.chunks) == 0 {
+		return s.storeEmptyFile(ctx, file)
+	}
+	return s.storeFile(ctx, file)
+}
+
+// storeFile embeds and stores the chunks of a prepared note on its own
+func (s *Service) storeFile(ctx context.Context, file *preparedFile) error {
 	// Generate embeddings
-	embeddings, err := s.embedder.EmbedBatch(ctx, texts)
+	embeddings, err := s.embedder.EmbedBatch(ctx, file.texts())
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
 	}
 
 	log.Printf("Found %d embeddings", len(embeddings))
 
-	if len(embeddings) != len(chunks) {
+	if len(embeddings) != len(file.chunks) {
 		return fmt.Errorf("%w: expected %d embeddings, got %d",
-			ErrEmbeddingFailed, len(chunks), len(embeddings))
+			ErrEmbeddingFailed, len(file.chunks), len(embeddings))
+	}
+
+	points := s.chunkPoints(file, embeddings)
+
+	// Store in Qdrant
+	err = s.qdrantClient.UpsertPoints(ctx, s.config.Qdrant.Collection, points)
+	log.Print("Upsert points ok")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageFailed, err)
 	}
 
-	// Prepare points for Qdrant
-	points := make([]*pb.PointStruct, len(chunks))
+	return s.finishFile(ctx, file, points)
+}
+
+// prepareFile reads, parses and chunks a note
+func (s *Service) prepareFile(path string, basePath string) (*preparedFile, error) {
+	// Read the file
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Parse the markdown
+	doc, err := s.parser.Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse markdown: %w", err)
+	}
+	if doc.FrontmatterError != nil {
+		log.Warn().Err(doc.FrontmatterError).Str("path", path).Msg("Indexing note without its frontmatter")
+	}
 
 	// If no base path was provided, try to determine it
 	if basePath == "" {
 		basePath = s.findBaseVaultPath(path)
 	}
 
+	// Embedded notes are chunked as part of the note, while its own links and
+	// tags keep describing it
+	chunkDoc := doc
+	var transclusion *markdown.Transclusion
+	if s.config.Indexing.TranscludeEmbeds {
+		transclusion = s.transclude(basePath, path, string(content))
+		if len(transclusion.Embeds) > 0 {
+			chunkDoc, err = s.parser.Parse(transclusion.Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse transcluded markdown: %w", err)
+			}
+			chunkDoc.Title = doc.Title
+			chunkDoc.Tags = doc.Tags
+		} else {
+			transclusion = nil
+		}
+	}
+
+	// Choose chunking strategy based on config
+	var chunks []*markdown.Chunk
+	switch s.config.Indexing.ChunkStrategy {
+	case "header":
+		chunks = s.parser.ChunkByHeaders(chunkDoc)
+	case "sliding_window":
+		chunks = s.parser.ChunkBySlidingWindow(chunkDoc, s.config.Indexing.MaxChunkSize, s.config.Indexing.WindowOverlap)
+	case "hybrid":
+		chunks = s.parser.ChunkHybrid(chunkDoc, s.config.Indexing.MaxChunkSize, s.config.Indexing.WindowOverlap)
+	default:
+		chunks = s.parser.ChunkHybrid(chunkDoc, s.config.Indexing.MaxChunkSize, s.config.Indexing.WindowOverlap)
+	}
+
+	// Code blocks are also chunked on their own, located separately since
+	// they repeat text of the chunks before them
+	var codeChunks []*markdown.Chunk
+	if s.config.Indexing.IndexCodeBlocks {
+		codeChunks = s.parser.ChunkCodeBlocks(chunkDoc)
+	}
+
+	// Line numbers let search results point at the exact spot in the note
+	located := string(content)
+	if transclusion != nil {
+		located = transclusion.Content
+	}
+	markdown.LocateChunks(located, chunks)
+	markdown.LocateChunks(located, codeChunks)
+	chunks = append(chunks, codeChunks...)
+	if transclusion != nil {
+		transclusion.LocateEmbeds(chunks)
+	}
+
 	// Get relative path from the vault base
 	relPath, err := filepath.Rel(basePath, path)
 	if err != nil {
@@ -321,31 +817,92 @@ func (s *Service) indexFile(ctx context.Context, path string, basePath string) e
 		relPath = path
 	}
 
-	// Store the vault path for proper attribution
-	vaultName := filepath.Base(basePath)
+	// Links are resolved now for the payload, the link graph resolves them again when read
+	s.links.ResolveLinks(basePath, path, doc.Links)
 
-	for i, chunk := range chunks {
+	// Timestamps for date filters
+	modTime := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	created, modified := noteTimes(modTime, doc.Frontmatter)
+
+	return &preparedFile{
+		path:      path,
+		basePath:  basePath,
+		relPath:   relPath,
+		vaultName: filepath.Base(basePath), // Store the vault path for proper attribution
+		content:   content,
+		doc:       doc,
+		chunks:    chunks,
+		created:   created,
+		modified:  modified,
+	}, nil
+}
+
+// storeEmptyFile records a note without chunks, dropping whatever was indexed for it before
+func (s *Service) storeEmptyFile(ctx context.Context, file *preparedFile) error {
+	log.Warn().Str("path", file.path).Msg("No chunks generated for file")
+
+	if err := s.purgeStaleChunks(ctx, file.path, nil); err != nil {
+		return err
+	}
+
+	s.links.Set(file.basePath, file.path, file.doc.Links)
+	s.recordIndexed(file.path, file.content, 0)
+	return nil
+}
+
+// chunkPoints builds the points of a note's chunks from their embeddings
+func (s *Service) chunkPoints(file *preparedFile, embeddings [][]float32) []*pb.PointStruct {
+	points := make([]*pb.PointStruct, len(file.chunks))
+	links := markdown.ResolvedTargets(file.doc.Links)
+
+	for i, chunk := range file.chunks {
 		// Get a unique ID for the chunk - include vault name to avoid collisions
-		id := model2.HashString(fmt.Sprintf("%s:%s#%d", vaultName, relPath, i))
+		id := chunkPointID(file.vaultName, file.relPath, i)
 
 		// Create payload with metadata
 		payload := map[string]interface{}{
-			"path":         relPath,
-			"full_path":    path,
-			"vault_path":   basePath,
-			"vault_name":   vaultName,
+			"path":         file.relPath,
+			"path_dirs":    pathDirs(file.relPath),
+			"full_path":    file.path,
+			"vault_path":   file.basePath,
+			"vault_name":   file.vaultName,
 			"text":         chunk.Content,
 			"content":      chunk.ContentOnly,
-			"title":        doc.Title,
+			"title":        file.doc.Title,
 			"section":      chunk.Section,
-			"tags":         doc.Tags,
+			"tags":         file.doc.Tags,
+			"aliases":      file.doc.Aliases,
+			"links":        links,
 			"chunk_index":  i,
-			"total_chunks": len(chunks),
+			"total_chunks": len(file.chunks),
+			"start_line":   chunk.StartLine,
+			"end_line":     chunk.EndLine,
+			"created":      file.created,
+			"modified":     file.modified,
 		}
 
-		// Add frontmatter to payload
-		for k, v := range doc.Frontmatter {
-			payload["fm_"+k] = v
+		// Inlined embeds record where their text comes from
+		if len(chunk.Embeds) > 0 {
+			payload["embeds"] = embedPayload(chunk.Embeds)
+		}
+		if chunk.Origin != nil {
+			payload["origin_path"] = chunk.Origin.Path
+			payload["origin_start_line"] = chunk.Origin.StartLine
+			payload["origin_end_line"] = chunk.Origin.EndLine
+		}
+
+		// Code blocks chunked on their own carry their language for lang: filters
+		if chunk.Type == markdown.BlockCode {
+			payload["block_type"] = string(chunk.Type)
+			payload["language"] = chunk.Language
+		}
+
+		// Add frontmatter to payload, keeping numbers, checkboxes and lists typed
+		for k, v := range file.doc.Frontmatter {
+			payload["fm_"+k] = frontmatterPayload(v)
 		}
 
 		// Keep vectors as float32 (they already are from EmbedBatch)
@@ -368,16 +925,55 @@ func (s *Service) indexFile(ctx context.Context, path string, basePath string) e
 		}
 	}
 
-	// Store in Qdrant
-	err = s.qdrantClient.UpsertPoints(ctx, s.config.Qdrant.Collection, points)
-	log.Print("Upsert points ok")
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrStorageFailed, err)
+	return points
+}
+
+// finishFile completes a note whose points were stored
+func (s *Service) finishFile(ctx context.Context, file *preparedFile, points []*pb.PointStruct) error {
+	pointIDs := make(map[string]bool, len(points))
+	for _, point := range points {
+		pointIDs[point.GetId().GetUuid()] = true
+	}
+
+	// Remove chunks left over from a longer version of the note
+	if err := s.purgeStaleChunks(ctx, file.path, pointIDs); err != nil {
+		return err
+	}
+
+	// Keep the keyword index in step with the stored chunks
+	for _, point := range points {
+		s.keywords.Add(point.GetId().GetUuid(), keywordText(point.Payload))
 	}
 
+	s.links.Set(file.basePath, file.path, file.doc.Links)
+	s.recordIndexed(file.path, file.content, len(file.chunks))
+
 	return nil
 }
 
+// recordIndexed stores the manifest entry for a freshly indexed file
+func (s *Service) recordIndexed(path string, content []byte, chunkCount int) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	hash := hashContent(content)
+	if previous, ok := s.manifest.Get(path); ok && previous.Hash != hash {
+		s.reindexEmbedders(path)
+	}
+
+	s.manifest.Set(ManifestEntry{
+		Path:           path,
+		ModTime:        info.ModTime(),
+		Size:           info.Size(),
+		Hash:           hash,
+		ChunkCount:     chunkCount,
+		EmbeddingModel: s.embedder.Name(),
+		IndexedAt:      time.Now(),
+	})
+}
+
 // recordError records an error in the stats
 func (s *Service) recordError(errMsg string) {
 	s.mutex.Lock()
@@ -0,0 +1,368 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrWorkersStopped is returned for work that needs the indexing workers while they aren't running
+var ErrWorkersStopped = errors.New("indexing workers are not running")
+
+const (
+	// defaultWorkers is the size of the worker pool if the config doesn't set one
+	defaultWorkers = 2
+	// defaultJobAttempts is how often a failing job is tried if the config doesn't say
+	defaultJobAttempts = 3
+	// retryBaseDelay is the wait before the first retry, doubled for each further one
+	retryBaseDelay = 5 * time.Second
+)
+
+// indexRun tracks the jobs queued by a full reindexing run
+type indexRun struct {
+	id   int64
+	ctx  context.Context
+	jobs sync.WaitGroup
+}
+
+// Start launches the pool of workers processing the job queue until ctx is done
+// or Close is called. Jobs left over from an earlier daemon are resumed first.
+func (s *Service) Start(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.workersCtx != nil {
+		return
+	}
+
+	workers := s.config.Indexing.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	s.workersCtx, s.stopWorkers = context.WithCancel(ctx)
+	s.pipeline = newPipeline(s, s.workersCtx, &s.workers)
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		go s.work(s.workersCtx)
+	}
+
+	if queued := s.queue.Len(); queued > 0 {
+		log.Info().Int("jobs", queued).Msg("Resuming queued indexing jobs")
+	}
+	log.Info().Int("workers", workers).Msg("Indexing workers started")
+}
+
+// Close stops the workers and saves the queue and the index state. Jobs that
+// were interrupted stay queued and are resumed by the next Start.
+func (s *Service) Close() error {
+	s.mutex.Lock()
+	stop := s.stopWorkers
+	s.mutex.Unlock()
+
+	if stop != nil {
+		stop()
+		s.workers.Wait()
+	}
+
+	s.saveIndexState()
+	if err := s.keywords.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to save keyword index")
+	}
+	return s.queue.Save()
+}
+
+// Enqueue queues a job for the workers and returns it as stored
+func (s *Service) Enqueue(job Job) Job {
+	return s.queue.Push(job)
+}
+
+// Do queues a job and waits for its outcome. Without running workers the job
+// is carried out directly.
+func (s *Service) Do(ctx context.Context, job Job) error {
+	s.mutex.RLock()
+	running := s.workersCtx != nil && s.workersCtx.Err() == nil
+	s.mutex.RUnlock()
+
+	if !running {
+		_, err := s.runJob(ctx, &job)
+		s.saveIndexState()
+		return err
+	}
+
+	// Registered under the lock so the job can't finish before anyone listens
+	done := make(chan error, 1)
+	s.jobsMutex.Lock()
+	stored := s.queue.Push(job)
+	s.waiters[stored.ID] = append(s.waiters[stored.ID], done)
+	s.jobsMutex.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work processes jobs until ctx is done
+func (s *Service) work(ctx context.Context) {
+	defer s.workers.Done()
+
+	for {
+		job, err := s.queue.Next(ctx)
+		if err != nil {
+			return
+		}
+		s.process(ctx, job)
+	}
+}
+
+// process carries out a job. Index jobs are only prepared here and handed to
+// the pipeline, which completes them once their note is stored.
+func (s *Service) process(ctx context.Context, job *Job) {
+	run := s.jobRun(job)
+
+	// Jobs of a run stop when it is cancelled, all jobs stop on shutdown
+	base := ctx
+	if run != nil {
+		base = run.ctx
+	}
+	jobCtx, cancel := context.WithCancel(base)
+	stop := context.AfterFunc(ctx, cancel)
+	release := func() {
+		stop()
+		cancel()
+	}
+
+	if run != nil && job.Type == JobIndex {
+		s.trackProgress(EventFileStarted, job.Path, nil)
+	}
+
+	if job.Type == JobIndex {
+		file, skipped, err := s.prepareJob(jobCtx, job)
+		if err == nil && file != nil {
+			// The pipeline embeds and stores the note together with others while
+			// this worker moves on, the job completes once it is stored
+			err = s.pipeline.submit(jobCtx, file, func(err error) {
+				release()
+				s.complete(ctx, job, run, false, err)
+			})
+			if err == nil {
+				return
+			}
+		}
+		release()
+		s.complete(ctx, job, run, skipped, err)
+		return
+	}
+
+	_, err := s.runJob(jobCtx, job)
+	release()
+	s.complete(ctx, job, run, false, err)
+}
+
+// complete decides whether a processed job is finished, retried or left for the next start
+func (s *Service) complete(ctx context.Context, job *Job, run *indexRun, skipped bool, err error) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// Shutting down, the job stays in the saved queue
+		return
+	case err != nil && run != nil && run.ctx.Err() != nil:
+		// The note of a cancelled run isn't recorded, so the next run picks it up
+		s.queue.Done(job)
+		s.finishJob(job, run, false, err, true)
+		return
+	case err != nil && job.Attempts+1 < s.jobAttempts() && !errors.Is(err, ErrInvalidPath):
+		delay := retryBaseDelay << job.Attempts
+		if s.queue.Retry(job, err, delay) {
+			log.Warn().Err(err).Str("path", job.Path).Str("type", string(job.Type)).
+				Dur("retry_in", delay).Msg("Indexing job failed, retrying")
+			return
+		}
+		// A newer job for the note replaced this one
+	default:
+		s.queue.Done(job)
+	}
+
+	s.finishJob(job, run, skipped, err, false)
+}
+
+// runJob carries out a job, reporting whether an index job found the note unchanged
+func (s *Service) runJob(ctx context.Context, job *Job) (bool, error) {
+	switch job.Type {
+	case JobIndex:
+		file, skipped, err := s.prepareJob(ctx, job)
+		if err != nil || file == nil {
+			return skipped, err
+		}
+		return false, s.storeFile(ctx, file)
+	case JobDelete:
+		return false, s.RemoveFile(ctx, job.Path)
+	case JobRename:
+		err := s.RenameFile(ctx, job.OldPath, job.Path)
+		if err != nil || !job.Force || !strings.HasSuffix(strings.ToLower(job.Path), ".md") {
+			return false, err
+		}
+		// The note changed after the move, the moved points are out of date
+		return false, s.IndexFile(ctx, job.Path, true)
+	default:
+		return false, fmt.Errorf("unknown job type %q", job.Type)
+	}
+}
+
+// prepareJob reads, parses and chunks the note of an index job. It reports
+// unchanged notes as skipped and stores notes without chunks right away, in
+// both cases there is no file left to embed.
+func (s *Service) prepareJob(ctx context.Context, job *Job) (*preparedFile, bool, error) {
+	if !strings.HasSuffix(strings.ToLower(job.Path), ".md") {
+		return nil, false, fmt.Errorf("%w: not a markdown file", ErrInvalidPath)
+	}
+
+	info, err := os.Stat(job.Path)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+
+	if !job.Force && s.isUnchanged(job.Path, info) {
+		return nil, true, nil
+	}
+
+	file, err := s.prepareFile(job.Path, s.findBaseVaultPath(job.Path))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(file.chunks) == 0 {
+		return nil, false, s.storeEmptyFile(ctx, file)
+	}
+	return file, false, nil
+}
+
+// finishJob accounts a job that won't run again to its run, wakes whoever waits
+// for it and saves the index state regularly
+func (s *Service) finishJob(job *Job, run *indexRun, skipped bool, err error, cancelled bool) {
+	if err != nil && !cancelled {
+		log.Error().Err(err).Str("path", job.Path).Str("type", string(job.Type)).Msg("Indexing job failed")
+	}
+
+	if run != nil {
+		if !cancelled {
+			s.accountRunJob(job, skipped, err)
+		}
+		run.jobs.Done()
+	}
+
+	s.jobsMutex.Lock()
+	for _, done := range s.waiters[job.ID] {
+		done <- err
+	}
+	delete(s.waiters, job.ID)
+	s.jobsFinished++
+	save := s.jobsFinished%manifestSaveInterval == 0
+	s.jobsMutex.Unlock()
+
+	// Persist progress regularly so an interrupted run doesn't start over
+	if save || s.queue.Len() == 0 {
+		s.saveIndexState()
+	}
+}
+
+// accountRunJob updates the stats and progress events of a run with a finished job
+func (s *Service) accountRunJob(job *Job, skipped bool, err error) {
+	if job.Type != JobIndex {
+		if job.Type == JobDelete && err == nil {
+			s.mutex.Lock()
+			s.stats.RemovedDocuments++
+			s.mutex.Unlock()
+		}
+		return
+	}
+
+	if skipped {
+		s.mutex.Lock()
+		s.stats.SkippedDocuments++
+		s.mutex.Unlock()
+
+		s.trackProgress(EventProgress, job.Path, func(event *IndexEvent) {
+			event.Skipped++
+		})
+		return
+	}
+
+	docStatus := DocumentStatus{
+		Path:      job.Path,
+		UpdatedAt: time.Now(),
+	}
+
+	if err != nil {
+		docStatus.Error = err.Error()
+
+		s.mutex.Lock()
+		s.stats.FailedDocuments++
+		s.stats.Documents = append(s.stats.Documents, docStatus)
+		s.mutex.Unlock()
+
+		s.trackProgress(EventFileFailed, job.Path, func(event *IndexEvent) {
+			event.Failed++
+			event.Error = docStatus.Error
+		})
+		return
+	}
+
+	docStatus.Indexed = true
+
+	s.mutex.Lock()
+	s.stats.IndexedDocuments++
+	s.stats.Documents = append(s.stats.Documents, docStatus)
+	s.mutex.Unlock()
+
+	log.Debug().Str("path", job.Path).Msg("Indexed file successfully")
+
+	chunks := 0
+	if entry, ok := s.manifest.Get(job.Path); ok {
+		chunks = entry.ChunkCount
+	}
+	s.trackProgress(EventFileIndexed, job.Path, func(event *IndexEvent) {
+		event.Indexed++
+		event.Chunks = chunks
+		event.TotalChunks += chunks
+	})
+}
+
+// queueRunJob queues a job on behalf of a reindexing run
+func (s *Service) queueRunJob(run *indexRun, job Job) {
+	job.RunID = run.id
+	run.jobs.Add(1)
+	s.queue.Push(job)
+}
+
+// jobRun returns the running reindexing run a job belongs to, nil for single
+// changes and for jobs of runs from before a restart
+func (s *Service) jobRun(job *Job) *indexRun {
+	if job.RunID == 0 {
+		return nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.run != nil && s.run.id == job.RunID {
+		return s.run
+	}
+	return nil
+}
+
+// jobAttempts returns how often a failing job is tried
+func (s *Service) jobAttempts() int {
+	if s.config.Indexing.JobMaxAttempts > 0 {
+		return s.config.Indexing.JobMaxAttempts
+	}
+	return defaultJobAttempts
+}
@@ -0,0 +1,341 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+)
+
+// KeywordIndexFileName is the name of the keyword index file inside the data directory
+const KeywordIndexFileName = "keyword_index.json"
+
+// keywordSaveDelay bounds how long changes to the keyword index stay unsaved.
+// Rewriting the whole index on every edit is too slow for large vaults.
+const keywordSaveDelay = 30 * time.Second
+
+// keywordDirtySuffix names the marker file that exists while the index has
+// unsaved changes, so an index left behind by a crash gets rebuilt
+const keywordDirtySuffix = ".dirty"
+
+// BM25 parameters, using the usual defaults
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// KeywordHit is a chunk matched by a keyword search
+type KeywordHit struct {
+	ID    string
+	Score float64
+}
+
+// KeywordIndex is a BM25 inverted index over chunk text, keyed by point ID
+type KeywordIndex struct {
+	path     string
+	mutex    sync.RWMutex
+	docs     map[string]keywordDoc
+	postings map[string]map[string]int
+	totalLen int
+
+	dirty     bool
+	saveTimer *time.Timer
+
+	// needsRebuild is set when no index file existed, so chunks stored before
+	// the keyword index was introduced still have to be added
+	needsRebuild bool
+}
+
+// keywordDoc holds the term frequencies of one chunk
+type keywordDoc struct {
+	Length int            `json:"length"`
+	Terms  map[string]int `json:"terms"`
+}
+
+// keywordIndexFile is the on-disk representation of the keyword index
+type keywordIndexFile struct {
+	Version int                   `json:"version"`
+	Docs    map[string]keywordDoc `json:"docs"`
+}
+
+// LoadKeywordIndex reads the keyword index at the given path. A missing file
+// or one with unsaved changes results in an index that is marked for rebuilding.
+func LoadKeywordIndex(path string) (*KeywordIndex, error) {
+	idx := &KeywordIndex{
+		path:     path,
+		docs:     make(map[string]keywordDoc),
+		postings: make(map[string]map[string]int),
+	}
+
+	if _, err := os.Stat(path + keywordDirtySuffix); err == nil {
+		idx.needsRebuild = true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		idx.needsRebuild = true
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return idx, fmt.Errorf("failed to read keyword index: %w", err)
+	}
+
+	var file keywordIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		idx.needsRebuild = true
+		return idx, fmt.Errorf("failed to parse keyword index: %w", err)
+	}
+
+	for id, doc := range file.Docs {
+		idx.addDoc(id, doc)
+	}
+
+	return idx, nil
+}
+
+// Add indexes the text of a chunk, replacing any earlier version
+func (k *KeywordIndex) Add(id, text string) {
+	terms := make(map[string]int)
+	tokens := tokenize(text)
+	for _, token := range tokens {
+		terms[token]++
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.removeDoc(id)
+	k.addDoc(id, keywordDoc{Length: len(tokens), Terms: terms})
+	k.changed()
+}
+
+// Remove drops chunks from the index
+func (k *KeywordIndex) Remove(ids ...string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	for _, id := range ids {
+		if k.removeDoc(id) {
+			k.changed()
+		}
+	}
+}
+
+// Reset removes all chunks
+func (k *KeywordIndex) Reset() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.docs = make(map[string]keywordDoc)
+	k.postings = make(map[string]map[string]int)
+	k.totalLen = 0
+	k.needsRebuild = false
+	k.changed()
+}
+
+// NeedsRebuild reports whether the index has to be filled from the stored chunks
+func (k *KeywordIndex) NeedsRebuild() bool {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.needsRebuild
+}
+
+// Search ranks chunks against the query with BM25 and returns the best hits
+func (k *KeywordIndex) Search(query string, limit int) []KeywordHit {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	if len(k.docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(k.docs))
+	avgLen := float64(k.totalLen) / n
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	// Count every query term once, repeating a word shouldn't change the ranking
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := k.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for id, tf := range postings {
+			docLen := float64(k.docs[id].Length)
+			freq := float64(tf)
+			scores[id] += idf * freq * (bm25K1 + 1) / (freq + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+		}
+	}
+
+	hits := make([]KeywordHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, KeywordHit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// changed marks the index as modified and schedules a save. Must be called with the lock held.
+func (k *KeywordIndex) changed() {
+	if !k.dirty {
+		k.dirty = true
+		err := os.MkdirAll(filepath.Dir(k.path), 0755)
+		if err == nil {
+			err = os.WriteFile(k.path+keywordDirtySuffix, nil, 0644)
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to mark keyword index as modified")
+		}
+	}
+
+	if k.saveTimer == nil {
+		k.saveTimer = time.AfterFunc(keywordSaveDelay, func() {
+			if err := k.Save(); err != nil {
+				log.Error().Err(err).Msg("Failed to save keyword index")
+			}
+		})
+	}
+}
+
+// Save writes the index to disk if it has changed since the last save.
+// Changes are saved by themselves after a delay, Save flushes them right away.
+func (k *KeywordIndex) Save() error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.saveTimer != nil {
+		k.saveTimer.Stop()
+		k.saveTimer = nil
+	}
+	if !k.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(keywordIndexFile{
+		Version: 1,
+		Docs:    k.docs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode keyword index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return fmt.Errorf("failed to create keyword index directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated index
+	tmpPath := k.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, k.path); err != nil {
+		return fmt.Errorf("failed to replace keyword index: %w", err)
+	}
+
+	k.dirty = false
+	if err := os.Remove(k.path + keywordDirtySuffix); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear keyword index marker: %w", err)
+	}
+	return nil
+}
+
+// addDoc adds a chunk to the postings, the caller must hold the lock
+func (k *KeywordIndex) addDoc(id string, doc keywordDoc) {
+	k.docs[id] = doc
+	k.totalLen += doc.Length
+
+	for term, tf := range doc.Terms {
+		postings, ok := k.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			k.postings[term] = postings
+		}
+		postings[id] = tf
+	}
+}
+
+// removeDoc removes a chunk from the postings, the caller must hold the lock
+func (k *KeywordIndex) removeDoc(id string) bool {
+	doc, ok := k.docs[id]
+	if !ok {
+		return false
+	}
+
+	for term := range doc.Terms {
+		delete(k.postings[term], id)
+		if len(k.postings[term]) == 0 {
+			delete(k.postings, term)
+		}
+	}
+
+	k.totalLen -= doc.Length
+	delete(k.docs, id)
+	return true
+}
+
+// tokenize splits text into lowercase terms.
+// Compound words such as ticket IDs (PROJ-123), identifiers (parse_config)
+// and dotted names (os.ReadFile) are kept whole and also split into their parts.
+func tokenize(text string) []string {
+	var tokens []string
+
+	emit := func(word string) {
+		word = strings.Trim(word, "-_.")
+		if word == "" {
+			return
+		}
+
+		tokens = append(tokens, word)
+
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+		if len(parts) > 1 {
+			tokens = append(tokens, parts...)
+		}
+	}
+
+	var current strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			current.WriteRune(r)
+			continue
+		}
+		emit(current.String())
+		current.Reset()
+	}
+	emit(current.String())
+
+	return tokens
+}
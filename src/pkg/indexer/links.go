@@ -0,0 +1,373 @@
+package indexer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"obsfind/src/pkg/markdown"
+)
+
+// LinkGraphFileName is the name of the link graph file inside the data directory
+const LinkGraphFileName = "link_graph.json"
+
+// NoteLinks lists the links of a note in both directions
+type NoteLinks struct {
+	Path      string          `json:"path"` // relative to the vault
+	FullPath  string          `json:"full_path"`
+	Links     []markdown.Link `json:"links"`
+	Backlinks []Backlink      `json:"backlinks"`
+}
+
+// Backlink is a link to a note from another note
+type Backlink struct {
+	Source string `json:"source"` // linking note, relative to the vault
+	markdown.Link
+}
+
+// LinkGraph records the outgoing links of every note in the vaults. Links are
+// resolved when they are read, so they follow notes being added and removed.
+type LinkGraph struct {
+	path  string
+	mutex sync.RWMutex
+	notes map[string]*linkNode // notes by full path
+	dirty bool
+
+	// Derived from notes, nil until needed after a change
+	resolvers map[string]*markdown.LinkResolver // by vault path
+	backlinks map[string][]Backlink             // by full path of the linked note
+}
+
+// linkNode is a note of the graph
+type linkNode struct {
+	Path   string          `json:"path"`
+	Vault  string          `json:"vault"`
+	Parsed bool            `json:"parsed,omitempty"` // Links is known, not just the note
+	Links  []markdown.Link `json:"links,omitempty"`
+}
+
+// linkGraphFile is the on-disk representation of the link graph
+type linkGraphFile struct {
+	Version int         `json:"version"`
+	Notes   []*linkNode `json:"notes"`
+}
+
+// LoadLinkGraph reads the link graph at the given path.
+// A missing file results in an empty graph.
+func LoadLinkGraph(path string) (*LinkGraph, error) {
+	g := &LinkGraph{
+		path:  path,
+		notes: make(map[string]*linkNode),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return g, nil
+		}
+		return g, fmt.Errorf("failed to read link graph: %w", err)
+	}
+
+	var file linkGraphFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return g, fmt.Errorf("failed to parse link graph: %w", err)
+	}
+
+	for _, node := range file.Notes {
+		g.notes[node.Path] = node
+	}
+
+	return g, nil
+}
+
+// AddNote registers a note as a link target, keeping its links if it is known already
+func (g *LinkGraph) AddNote(vaultPath, path string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.notes[path]; ok {
+		return
+	}
+	g.notes[path] = &linkNode{Path: path, Vault: vaultPath}
+	g.notesChanged()
+}
+
+// Has reports whether the links of a note are recorded
+func (g *LinkGraph) Has(path string) bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	node, ok := g.notes[path]
+	return ok && node.Parsed
+}
+
+// Set records the outgoing links of a note
+func (g *LinkGraph) Set(vaultPath, path string, links []markdown.Link) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.notes[path]; !ok {
+		g.notesChanged()
+	}
+	g.notes[path] = &linkNode{Path: path, Vault: vaultPath, Parsed: true, Links: links}
+	g.linksChanged()
+}
+
+// Remove drops a note and its links
+func (g *LinkGraph) Remove(path string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.notes[path]; ok {
+		delete(g.notes, path)
+		g.notesChanged()
+	}
+}
+
+// Rename moves a note and its links to a new path
+func (g *LinkGraph) Rename(vaultPath, oldPath, newPath string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	node, ok := g.notes[oldPath]
+	if !ok {
+		node = &linkNode{}
+	}
+	delete(g.notes, oldPath)
+	node.Path = newPath
+	node.Vault = vaultPath
+	g.notes[newPath] = node
+	g.notesChanged()
+}
+
+// Prune drops the notes of a vault that are not in seen
+func (g *LinkGraph) Prune(vaultPath string, seen map[string]bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for path, node := range g.notes {
+		if node.Vault == vaultPath && !seen[path] {
+			delete(g.notes, path)
+			g.notesChanged()
+		}
+	}
+}
+
+// ResolveLinks resolves the links of a note against the notes of its vault
+func (g *LinkGraph) ResolveLinks(vaultPath, path string, links []markdown.Link) {
+	g.mutex.Lock()
+	resolver := g.resolver(vaultPath)
+	g.mutex.Unlock()
+
+	resolver.ResolveLinks(links, vaultRelative(vaultPath, path))
+}
+
+// Resolve returns the note of a vault a link from source points at, or "" if
+// there is none. Both paths are relative to the vault.
+func (g *LinkGraph) Resolve(vaultPath string, link markdown.Link, source string) string {
+	g.mutex.Lock()
+	resolver := g.resolver(vaultPath)
+	g.mutex.Unlock()
+
+	return resolver.Resolve(link, source)
+}
+
+// Lookup finds a note by a link target such as its name, trying the vaults in order
+func (g *LinkGraph) Lookup(vaultPaths []string, target string) (string, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, vaultPath := range vaultPaths {
+		if note := g.resolver(vaultPath).Resolve(markdown.Link{Target: target}, ""); note != "" {
+			return filepath.Join(vaultPath, filepath.FromSlash(note)), true
+		}
+	}
+	return "", false
+}
+
+// Links returns the outgoing links of a note, resolved against the current notes
+func (g *LinkGraph) Links(path string) []markdown.Link {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	node, ok := g.notes[path]
+	if !ok {
+		return []markdown.Link{}
+	}
+
+	links := make([]markdown.Link, len(node.Links))
+	copy(links, node.Links)
+	g.resolver(node.Vault).ResolveLinks(links, vaultRelative(node.Vault, path))
+	return links
+}
+
+// Backlinks returns the links from other notes to a note, ordered by linking note and line
+func (g *LinkGraph) Backlinks(path string) []Backlink {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.backlinks == nil {
+		g.backlinks = make(map[string][]Backlink)
+		for source, node := range g.notes {
+			links := make([]markdown.Link, len(node.Links))
+			copy(links, node.Links)
+			sourceRel := vaultRelative(node.Vault, source)
+			g.resolver(node.Vault).ResolveLinks(links, sourceRel)
+
+			for _, link := range links {
+				if link.Resolved == "" || link.Resolved == sourceRel {
+					continue
+				}
+				target := filepath.Join(node.Vault, filepath.FromSlash(link.Resolved))
+				g.backlinks[target] = append(g.backlinks[target], Backlink{Source: sourceRel, Link: link})
+			}
+		}
+
+		for _, backlinks := range g.backlinks {
+			sort.Slice(backlinks, func(i, j int) bool {
+				if backlinks[i].Source != backlinks[j].Source {
+					return backlinks[i].Source < backlinks[j].Source
+				}
+				return backlinks[i].Line < backlinks[j].Line
+			})
+		}
+	}
+
+	backlinks := make([]Backlink, len(g.backlinks[path]))
+	copy(backlinks, g.backlinks[path])
+	return backlinks
+}
+
+// resolver returns the link resolver of a vault. Must be called with the mutex held.
+func (g *LinkGraph) resolver(vaultPath string) *markdown.LinkResolver {
+	if g.resolvers == nil {
+		g.resolvers = make(map[string]*markdown.LinkResolver)
+	}
+
+	if resolver, ok := g.resolvers[vaultPath]; ok {
+		return resolver
+	}
+
+	var notes []string
+	for path, node := range g.notes {
+		if node.Vault == vaultPath {
+			notes = append(notes, vaultRelative(vaultPath, path))
+		}
+	}
+	resolver := markdown.NewLinkResolver(notes)
+	g.resolvers[vaultPath] = resolver
+	return resolver
+}
+
+// notesChanged drops everything derived from the set of notes. Must be called with the mutex held.
+func (g *LinkGraph) notesChanged() {
+	g.resolvers = nil
+	g.linksChanged()
+}
+
+// linksChanged drops everything derived from the links. Must be called with the mutex held.
+func (g *LinkGraph) linksChanged() {
+	g.backlinks = nil
+	g.dirty = true
+}
+
+// Save writes the link graph to disk if it has changed since the last save
+func (g *LinkGraph) Save() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.dirty {
+		return nil
+	}
+
+	file := linkGraphFile{
+		Version: 1,
+		Notes:   make([]*linkNode, 0, len(g.notes)),
+	}
+	for _, node := range g.notes {
+		file.Notes = append(file.Notes, node)
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode link graph: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+		return fmt.Errorf("failed to create link graph directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated graph
+	tmpPath := g.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write link graph: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, g.path); err != nil {
+		return fmt.Errorf("failed to replace link graph: %w", err)
+	}
+
+	g.dirty = false
+	return nil
+}
+
+// vaultRelative returns the path of a note relative to its vault, separated by slashes
+func vaultRelative(vaultPath, path string) string {
+	rel, err := filepath.Rel(vaultPath, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// NoteLinks returns the outgoing links and the backlinks of a note. The note is
+// given like for ResolveNote or by a link target such as its name.
+func (s *Service) NoteLinks(path string) (*NoteLinks, error) {
+	fullPath, err := s.ResolveNote(path)
+	if errors.Is(err, ErrNoteNotFound) {
+		var ok bool
+		if fullPath, ok = s.links.Lookup(s.config.GetVaultPaths(), path); ok {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vaultPath := s.findBaseVaultPath(fullPath)
+
+	// Notes that weren't indexed yet are parsed for their links now
+	if !s.links.Has(fullPath) {
+		if err := s.updateLinks(vaultPath, fullPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return &NoteLinks{
+		Path:      vaultRelative(vaultPath, fullPath),
+		FullPath:  fullPath,
+		Links:     s.links.Links(fullPath),
+		Backlinks: s.links.Backlinks(fullPath),
+	}, nil
+}
+
+// updateLinks reads a note and records its links without indexing it
+func (s *Service) updateLinks(vaultPath, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+
+	doc, err := s.parser.Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse markdown: %w", err)
+	}
+
+	s.links.ResolveLinks(vaultPath, path, doc.Links)
+	s.links.Set(vaultPath, path, doc.Links)
+	return nil
+}
@@ -0,0 +1,167 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest file inside the data directory
+const ManifestFileName = "index_manifest.json"
+
+// manifestSaveInterval is the number of indexed files between manifest saves
+const manifestSaveInterval = 50
+
+// ManifestEntry records what was indexed for a single file
+type ManifestEntry struct {
+	Path           string    `json:"path"`
+	ModTime        time.Time `json:"mtime"`
+	Size           int64     `json:"size"`
+	Hash           string    `json:"hash"`
+	ChunkCount     int       `json:"chunk_count"`
+	EmbeddingModel string    `json:"embedding_model"`
+	IndexedAt      time.Time `json:"indexed_at"`
+}
+
+// Manifest keeps track of indexed files so unchanged notes can be skipped
+type Manifest struct {
+	path    string
+	mutex   sync.RWMutex
+	entries map[string]ManifestEntry
+	dirty   bool
+}
+
+// manifestFile is the on-disk representation of the manifest
+type manifestFile struct {
+	Version int             `json:"version"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// LoadManifest reads the manifest at the given path.
+// A missing file results in an empty manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{
+		path:    path,
+		entries: make(map[string]ManifestEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return m, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var file manifestFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return m, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	for _, entry := range file.Files {
+		m.entries[entry.Path] = entry
+	}
+
+	return m, nil
+}
+
+// Get returns the entry for a file
+func (m *Manifest) Get(path string) (ManifestEntry, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entry, ok := m.entries[path]
+	return entry, ok
+}
+
+// Set records or replaces the entry for a file
+func (m *Manifest) Set(entry ManifestEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries[entry.Path] = entry
+	m.dirty = true
+}
+
+// Delete removes the entry for a file
+func (m *Manifest) Delete(path string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.entries[path]; ok {
+		delete(m.entries, path)
+		m.dirty = true
+	}
+}
+
+// Paths returns the paths of all recorded files
+func (m *Manifest) Paths() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	paths := make([]string, 0, len(m.entries))
+	for path := range m.entries {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// Reset removes all entries
+func (m *Manifest) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries = make(map[string]ManifestEntry)
+	m.dirty = true
+}
+
+// Save writes the manifest to disk if it has changed since the last save
+func (m *Manifest) Save() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.dirty {
+		return nil
+	}
+
+	file := manifestFile{
+		Version: 1,
+		Files:   make([]ManifestEntry, 0, len(m.entries)),
+	}
+	for _, entry := range m.entries {
+		file.Files = append(file.Files, entry)
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated manifest
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return fmt.Errorf("failed to replace manifest: %w", err)
+	}
+
+	m.dirty = false
+	return nil
+}
+
+// hashContent returns the hex encoded SHA-256 of the content
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
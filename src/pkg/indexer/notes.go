@@ -0,0 +1,102 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	model2 "obsfind/src/pkg/model"
+)
+
+// ErrNoteNotFound is returned by ResolveNote for notes that don't exist in any vault
+var ErrNoteNotFound = errors.New("note not found")
+
+// TagCount is a tag and the number of indexed notes carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ListTags returns the tags of all indexed notes, most used first
+func (s *Service) ListTags(ctx context.Context) ([]TagCount, error) {
+	points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed points: %w", err)
+	}
+
+	// Every chunk carries the tags of its note, count each note once
+	notes := make(map[string]map[string]bool)
+	for _, point := range points {
+		tags, ok := model2.GetPayloadStringSlice(point.Payload, "tags")
+		if !ok {
+			continue
+		}
+
+		note, _ := model2.GetPayloadString(point.Payload, "full_path")
+		for _, tag := range tags {
+			if notes[tag] == nil {
+				notes[tag] = make(map[string]bool)
+			}
+			notes[tag][note] = true
+		}
+	}
+
+	counts := make([]TagCount, 0, len(notes))
+	for tag, paths := range notes {
+		counts = append(counts, TagCount{Tag: tag, Count: len(paths)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+
+	return counts, nil
+}
+
+// ResolveNote returns the file of a note given its path relative to one of the
+// vaults or an absolute path. Paths outside the vaults are rejected with
+// ErrInvalidPath, a relative path is looked up in every vault in turn.
+func (s *Service) ResolveNote(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+
+	vaultPaths := s.config.GetVaultPaths()
+
+	if filepath.IsAbs(path) {
+		path = filepath.Clean(path)
+		for _, vaultPath := range vaultPaths {
+			if insideDir(vaultPath, path) {
+				return path, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s is not inside a vault", ErrInvalidPath, path)
+	}
+
+	for _, vaultPath := range vaultPaths {
+		candidate := filepath.Join(vaultPath, filepath.FromSlash(path))
+		if !insideDir(vaultPath, candidate) {
+			return "", fmt.Errorf("%w: %s is not inside a vault", ErrInvalidPath, path)
+		}
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrNoteNotFound, path)
+}
+
+// insideDir reports whether path is dir or inside it
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
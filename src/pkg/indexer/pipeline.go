@@ -0,0 +1,353 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultEmbedWorkers is the number of concurrent embedding requests if the config doesn't set one
+	defaultEmbedWorkers = 1
+	// defaultEmbedBatch and defaultUpsertBatch size batches if the config doesn't
+	defaultEmbedBatch  = 8
+	defaultUpsertBatch = 50
+	// batchLinger is how long a partial batch waits for more notes before it is sent anyway
+	batchLinger = 50 * time.Millisecond
+)
+
+// pipeline embeds and stores prepared notes in batches spanning notes, so the
+// embedding model is kept busy while the workers parse and chunk the next notes.
+//
+//	workers (parse, chunk) -> embed batcher -> embed workers -> upsert batcher
+//
+// Every stage is connected by a bounded channel, so a slow stage holds back the
+// ones before it instead of piling up notes in memory.
+type pipeline struct {
+	s          *Service
+	ctx        context.Context
+	embedSize  int
+	upsertSize int
+
+	files    chan *pipelineFile // prepared notes from the workers
+	batches  chan []chunkRef    // chunks to embed together
+	embedded chan *pipelineFile // notes with all chunks embedded
+}
+
+// pipelineFile is a note travelling through the pipeline
+type pipelineFile struct {
+	*preparedFile
+	ctx        context.Context
+	embeddings [][]float32
+
+	mutex     sync.Mutex
+	remaining int
+	finished  bool
+	done      func(error)
+}
+
+// chunkRef is a chunk of a note waiting to be embedded
+type chunkRef struct {
+	file  *pipelineFile
+	index int
+}
+
+// newPipeline starts the stages of a pipeline running until ctx is done, tracked by workers
+func newPipeline(s *Service, ctx context.Context, workers *sync.WaitGroup) *pipeline {
+	embedWorkers := s.config.Indexing.EmbedWorkers
+	if embedWorkers <= 0 {
+		embedWorkers = defaultEmbedWorkers
+	}
+
+	p := &pipeline{
+		s:          s,
+		ctx:        ctx,
+		embedSize:  s.config.Embedding.BatchSize,
+		upsertSize: s.config.Indexing.BatchSize,
+	}
+	if p.embedSize <= 0 {
+		p.embedSize = defaultEmbedBatch
+	}
+	if p.upsertSize <= 0 {
+		p.upsertSize = defaultUpsertBatch
+	}
+
+	p.files = make(chan *pipelineFile, embedWorkers)
+	p.batches = make(chan []chunkRef, embedWorkers)
+	p.embedded = make(chan *pipelineFile, p.upsertSize)
+
+	workers.Add(2 + embedWorkers)
+	go func() {
+		defer workers.Done()
+		p.batchChunks()
+	}()
+	for i := 0; i < embedWorkers; i++ {
+		go func() {
+			defer workers.Done()
+			p.embedBatches()
+		}()
+	}
+	go func() {
+		defer workers.Done()
+		p.upsertFiles()
+	}()
+
+	return p
+}
+
+// submit hands a prepared note to the pipeline, waiting while it is full.
+// done is called once the note is stored or failed, but not if the pipeline
+// shuts down first.
+func (p *pipeline) submit(ctx context.Context, file *preparedFile, done func(error)) error {
+	f := &pipelineFile{
+		preparedFile: file,
+		ctx:          ctx,
+		embeddings:   make([][]float32, len(file.chunks)),
+		remaining:    len(file.chunks),
+		done:         done,
+	}
+
+	select {
+	case p.files <- f:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// batchChunks collects the chunks of incoming notes into embedding batches
+func (p *pipeline) batchChunks() {
+	var batch []chunkRef
+	var linger <-chan time.Time
+
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		select {
+		case p.batches <- batch:
+			batch, linger = nil, nil
+			return true
+		case <-p.ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-linger:
+			if !flush() {
+				return
+			}
+		case f := <-p.files:
+			for i := range f.chunks {
+				batch = append(batch, chunkRef{file: f, index: i})
+				if len(batch) >= p.embedSize && !flush() {
+					return
+				}
+			}
+			if len(batch) > 0 && linger == nil {
+				linger = time.After(batchLinger)
+			}
+		}
+	}
+}
+
+// embedBatches embeds batches of chunks and passes on notes once all their chunks are embedded
+func (p *pipeline) embedBatches() {
+	for {
+		var batch []chunkRef
+		select {
+		case <-p.ctx.Done():
+			return
+		case batch = <-p.batches:
+		}
+
+		// Chunks of notes that failed or were cancelled meanwhile are left out
+		refs := batch[:0]
+		for _, ref := range batch {
+			if err := ref.file.ctx.Err(); err != nil {
+				p.finish(ref.file, err)
+			}
+			if !ref.file.isFinished() {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+
+		texts := make([]string, len(refs))
+		for i, ref := range refs {
+			texts[i] = ref.file.chunks[ref.index].Content
+		}
+
+		ctx, cancel := p.batchContext(refs)
+		embeddings, err := p.s.embedder.EmbedBatch(ctx, texts)
+		cancel()
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err == nil && len(embeddings) != len(refs) {
+			err = fmt.Errorf("expected %d embeddings, got %d", len(refs), len(embeddings))
+		}
+		if err != nil {
+			for _, ref := range refs {
+				p.finish(ref.file, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err))
+			}
+			continue
+		}
+
+		for i, ref := range refs {
+			if ref.file.embedded(ref.index, embeddings[i]) {
+				select {
+				case p.embedded <- ref.file:
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// batchContext returns a context for embedding a batch that is cancelled once
+// every note in it is, so cancelling a run doesn't wait for the embedding model
+func (p *pipeline) batchContext(refs []chunkRef) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(p.ctx)
+
+	files := make(map[*pipelineFile]bool)
+	for _, ref := range refs {
+		files[ref.file] = true
+	}
+
+	var mutex sync.Mutex
+	live := len(files)
+	stops := make([]func() bool, 0, len(files))
+	for f := range files {
+		stops = append(stops, context.AfterFunc(f.ctx, func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if live--; live == 0 {
+				cancel()
+			}
+		}))
+	}
+
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+// upsertFiles stores embedded notes in batches of points and completes them
+func (p *pipeline) upsertFiles() {
+	var files []*pipelineFile
+	var points []*pb.PointStruct
+	var linger <-chan time.Time
+
+	flush := func() {
+		p.store(files, points)
+		files, points, linger = nil, nil, nil
+	}
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-linger:
+			flush()
+		case f := <-p.embedded:
+			files = append(files, f)
+			points = append(points, p.s.chunkPoints(f.preparedFile, f.embeddings)...)
+			if len(points) >= p.upsertSize {
+				flush()
+			} else if linger == nil {
+				linger = time.After(batchLinger)
+			}
+		}
+	}
+}
+
+// store upserts the points of a batch of notes and completes them
+func (p *pipeline) store(files []*pipelineFile, points []*pb.PointStruct) {
+	// Points of notes cancelled meanwhile are left out
+	kept := points[:0]
+	offset := 0
+	live := files[:0]
+	for _, f := range files {
+		count := len(f.chunks)
+		if err := f.ctx.Err(); err != nil {
+			p.finish(f, err)
+		} else {
+			kept = append(kept, points[offset:offset+count]...)
+			live = append(live, f)
+		}
+		offset += count
+	}
+
+	for start := 0; start < len(kept); start += p.upsertSize {
+		end := start + p.upsertSize
+		if end > len(kept) {
+			end = len(kept)
+		}
+
+		if err := p.s.qdrantClient.UpsertPoints(p.ctx, p.s.config.Qdrant.Collection, kept[start:end]); err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
+			for _, f := range live {
+				p.finish(f, fmt.Errorf("%w: %v", ErrStorageFailed, err))
+			}
+			return
+		}
+	}
+
+	log.Debug().Int("notes", len(live)).Int("points", len(kept)).Msg("Stored batch of notes")
+
+	offset = 0
+	for _, f := range live {
+		count := len(f.chunks)
+		p.finish(f, p.s.finishFile(f.ctx, f.preparedFile, kept[offset:offset+count]))
+		offset += count
+	}
+}
+
+// finish completes a note once
+func (p *pipeline) finish(f *pipelineFile, err error) {
+	f.mutex.Lock()
+	if f.finished {
+		f.mutex.Unlock()
+		return
+	}
+	f.finished = true
+	f.mutex.Unlock()
+
+	f.done(err)
+}
+
+// embedded stores the embedding of a chunk and reports whether it was the note's last one
+func (f *pipelineFile) embedded(index int, embedding []float32) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.embeddings[index] = embedding
+	f.remaining--
+	return f.remaining == 0 && !f.finished
+}
+
+// isFinished reports whether the note was completed already
+func (f *pipelineFile) isFinished() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.finished
+}
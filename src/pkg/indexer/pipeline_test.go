@@ -0,0 +1,146 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"obsfind/src/pkg/config"
+	"obsfind/src/pkg/vectorstore"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// benchNotes is the number of notes indexed per benchmark iteration, two chunks each
+	benchNotes = 200
+	// benchCallLatency and benchTextLatency make up the time of a fake embedding request
+	benchCallLatency = 20 * time.Millisecond
+	benchTextLatency = 2 * time.Millisecond
+)
+
+// slowEmbedder is an embedder whose requests take a fixed time per call and per
+// text and run one at a time, like a local model
+type slowEmbedder struct {
+	mutex sync.Mutex
+	calls int
+}
+
+func (e *slowEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (e *slowEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.calls++
+	time.Sleep(benchCallLatency + time.Duration(len(texts))*benchTextLatency)
+
+	embeddings := make([][]float32, len(texts))
+	for i := range embeddings {
+		embeddings[i] = []float32{1, 0, 0}
+	}
+	return embeddings, nil
+}
+
+func (e *slowEmbedder) Dimensions() int { return 3 }
+func (e *slowEmbedder) Name() string    { return "slow" }
+func (e *slowEmbedder) Close() error    { return nil }
+
+// newBenchService creates a service over a vault of benchNotes notes with an
+// in-process vector store, and returns the paths of the notes
+func newBenchService(b *testing.B, embedder *slowEmbedder) (*Service, []string) {
+	b.Helper()
+
+	dir := b.TempDir()
+	vault := filepath.Join(dir, "vault")
+	if err := os.MkdirAll(vault, 0755); err != nil {
+		b.Fatal(err)
+	}
+
+	paths := make([]string, benchNotes)
+	for i := range paths {
+		paths[i] = filepath.Join(vault, fmt.Sprintf("note-%03d.md", i))
+		content := fmt.Sprintf("# Note %d\n\nFirst section of note %d.\n\n## Details\n\nSecond section of note %d.\n", i, i, i)
+		if err := os.WriteFile(paths[i], []byte(content), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.General.DataDir = filepath.Join(dir, "data")
+	cfg.Paths.VaultPaths = []string{vault}
+	cfg.Indexing.ChunkStrategy = "header"
+
+	store, err := vectorstore.Open(filepath.Join(dir, "store"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { store.Close() })
+
+	if err := store.CreateCollection(context.Background(), cfg.Qdrant.Collection, 3, pb.Distance_Cosine); err != nil {
+		b.Fatal(err)
+	}
+
+	return NewService(&cfg, embedder, store), paths
+}
+
+// BenchmarkIndexSerial indexes notes one at a time, embedding each note on its own
+func BenchmarkIndexSerial(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	defer zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	ctx := context.Background()
+	embedder := &slowEmbedder{}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s, paths := newBenchService(b, embedder)
+		b.StartTimer()
+
+		for _, path := range paths {
+			if err := s.indexFile(ctx, path, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.ReportMetric(float64(embedder.calls)/float64(b.N), "embed-calls/op")
+}
+
+// BenchmarkIndexPipeline indexes notes through the workers and the pipeline,
+// which embeds chunks of several notes together
+func BenchmarkIndexPipeline(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	defer zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	ctx := context.Background()
+	embedder := &slowEmbedder{}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s, _ := newBenchService(b, embedder)
+		s.Start(ctx)
+		b.StartTimer()
+
+		if err := s.IndexVault(ctx); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		if err := s.Close(); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+	}
+
+	b.ReportMetric(float64(embedder.calls)/float64(b.N), "embed-calls/op")
+}
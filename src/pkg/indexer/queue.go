@@ -0,0 +1,415 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// QueueFileName is the name of the job queue file inside the data directory
+const QueueFileName = "index_queue.json"
+
+// queueSaveDelay bounds how long changes to the queue stay unsaved. Losing them
+// in a crash only repeats finished jobs, which skip unchanged notes.
+const queueSaveDelay = time.Second
+
+// JobType is the kind of work a job does
+type JobType string
+
+const (
+	// JobIndex indexes the note at Path
+	JobIndex JobType = "index"
+	// JobDelete removes the note at Path from the index
+	JobDelete JobType = "delete"
+	// JobRename moves the indexed note from OldPath to Path
+	JobRename JobType = "rename"
+)
+
+// Job priorities, higher runs first
+const (
+	// PriorityReindex is used for the notes of full reindexing runs
+	PriorityReindex = 0
+	// PriorityChange is used for watcher events and single file requests
+	PriorityChange = 10
+)
+
+// Job is a unit of indexing work for a single note
+type Job struct {
+	ID        uint64    `json:"id"`
+	Type      JobType   `json:"type"`
+	Path      string    `json:"path"`
+	OldPath   string    `json:"old_path,omitempty"`
+	Force     bool      `json:"force,omitempty"`
+	Priority  int       `json:"priority"`
+	Attempts  int       `json:"attempts,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	NotBefore time.Time `json:"not_before,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// RunID ties the job to the reindexing run that queued it, zero for single changes
+	RunID int64 `json:"run_id,omitempty"`
+	// Changed marks a job of a run that a single change was merged into, so
+	// cancelling the run keeps it
+	Changed bool `json:"changed,omitempty"`
+}
+
+// JobQueue is a durable queue of indexing jobs. There is at most one waiting job
+// per note and jobs touching a note that is being worked on wait for it.
+type JobQueue struct {
+	path    string
+	mutex   sync.Mutex
+	nextID  uint64
+	pending map[string]*Job // waiting jobs by path
+	running map[string]*Job // jobs being worked on by every path they touch
+	notify  chan struct{}
+
+	dirty     bool
+	saveTimer *time.Timer
+}
+
+// queueFile is the on-disk representation of the queue
+type queueFile struct {
+	Version int    `json:"version"`
+	NextID  uint64 `json:"next_id"`
+	Jobs    []*Job `json:"jobs"`
+}
+
+// LoadJobQueue reads the queue at the given path. Jobs that were running when
+// it was last saved are queued again. A missing file results in an empty queue.
+func LoadJobQueue(path string) (*JobQueue, error) {
+	q := &JobQueue{
+		path:    path,
+		nextID:  1,
+		pending: make(map[string]*Job),
+		running: make(map[string]*Job),
+		notify:  make(chan struct{}, 1),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return q, nil
+		}
+		return q, fmt.Errorf("failed to read job queue: %w", err)
+	}
+
+	var file queueFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return q, fmt.Errorf("failed to parse job queue: %w", err)
+	}
+
+	for _, job := range file.Jobs {
+		if job.ID >= q.nextID {
+			q.nextID = job.ID + 1
+		}
+	}
+	if file.NextID > q.nextID {
+		q.nextID = file.NextID
+	}
+
+	// A note can have a running job and a newer waiting one, such as a delete
+	// queued during indexing. Folding them in queuing order keeps the newer one.
+	sort.Slice(file.Jobs, func(i, j int) bool {
+		return file.Jobs[i].ID < file.Jobs[j].ID
+	})
+	for _, job := range file.Jobs {
+		if existing, ok := q.pending[job.Path]; ok {
+			q.merge(existing, *job)
+			continue
+		}
+		q.pending[job.Path] = job
+	}
+
+	return q, nil
+}
+
+// Push queues a job and returns it as stored. A job for a note that already has
+// a waiting job is merged into it, keeping its ID, position and the higher priority.
+func (q *JobQueue) Push(job Job) Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.push(job)
+}
+
+// push queues a job. Must be called with the mutex held.
+func (q *JobQueue) push(job Job) Job {
+	if existing, ok := q.pending[job.Path]; ok {
+		q.merge(existing, job)
+		q.changed()
+		return *existing
+	}
+
+	job.ID = q.nextID
+	q.nextID++
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	q.pending[job.Path] = &job
+	q.changed()
+	return job
+}
+
+// merge folds a newer job for the same note into a waiting one. Must be called
+// with the mutex held.
+func (q *JobQueue) merge(existing *Job, job Job) {
+	switch {
+	case existing.Type == JobRename && job.Type == JobIndex:
+		// The note changed after the move, which is kept so the old name's points,
+		// manifest entry and links go, and reindexed after it
+		existing.Force = true
+	case existing.Type == JobRename && job.OldPath != existing.OldPath:
+		// Deleted or replaced by another note after the move, so nothing moves
+		// from the old name any more and it has to be removed on its own
+		q.push(Job{Type: JobDelete, Path: existing.OldPath, Priority: existing.Priority})
+		existing.Type = job.Type
+		existing.OldPath = job.OldPath
+		existing.Force = existing.Force || job.Force
+	case existing.Type == JobDelete && job.Type == JobRename:
+		// Moved onto a deleted note, reindexing after the move drops its leftover chunks
+		existing.Type = job.Type
+		existing.OldPath = job.OldPath
+		existing.Force = true
+	default:
+		existing.Type = job.Type
+		existing.OldPath = job.OldPath
+		existing.Force = existing.Force || job.Force
+	}
+
+	existing.Attempts = 0
+	existing.LastError = ""
+	existing.NotBefore = time.Time{}
+	if job.Priority > existing.Priority {
+		existing.Priority = job.Priority
+	}
+
+	// A single change merged into a run's job, or a run adopting a waiting
+	// change, has to outlive the run being cancelled
+	if (job.RunID == 0) != (existing.RunID == 0) {
+		existing.Changed = true
+	}
+	if job.RunID != 0 {
+		existing.RunID = job.RunID
+	}
+}
+
+// Next waits for the most urgent job that is ready to run and marks it as running.
+// Jobs run by priority, then in the order they were queued.
+func (q *JobQueue) Next(ctx context.Context) (*Job, error) {
+	for {
+		q.mutex.Lock()
+		job, wait := q.pick(time.Now())
+		if job != nil {
+			delete(q.pending, job.Path)
+			q.running[job.Path] = job
+			if job.OldPath != "" {
+				q.running[job.OldPath] = job
+			}
+			// Pass the wake-up on, other workers may find work too
+			if len(q.pending) > 0 {
+				q.wake()
+			}
+			q.mutex.Unlock()
+			return job, nil
+		}
+		q.mutex.Unlock()
+
+		var timer *time.Timer
+		var delayed <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			delayed = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, ctx.Err()
+		case <-q.notify:
+		case <-delayed:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// pick returns the most urgent runnable job, or how long until a delayed job
+// becomes runnable. Must be called with the mutex held.
+func (q *JobQueue) pick(now time.Time) (*Job, time.Duration) {
+	var best *Job
+	var wait time.Duration
+	for _, job := range q.pending {
+		if q.running[job.Path] != nil || job.OldPath != "" && q.running[job.OldPath] != nil {
+			continue
+		}
+		if job.NotBefore.After(now) {
+			if until := job.NotBefore.Sub(now); wait == 0 || until < wait {
+				wait = until
+			}
+			continue
+		}
+		if best == nil || job.Priority > best.Priority || job.Priority == best.Priority && job.ID < best.ID {
+			best = job
+		}
+	}
+	return best, wait
+}
+
+// Done removes a finished job
+func (q *JobQueue) Done(job *Job) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.release(job)
+	q.changed()
+}
+
+// Retry records the failure of a job and queues it again after delay, unless a
+// newer job for the same note was queued meanwhile. It reports whether the job was queued.
+func (q *JobQueue) Retry(job *Job, failure error, delay time.Duration) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.release(job)
+	defer q.changed()
+
+	if _, ok := q.pending[job.Path]; ok {
+		return false
+	}
+	job.Attempts++
+	job.LastError = failure.Error()
+	job.NotBefore = time.Now().Add(delay)
+	q.pending[job.Path] = job
+	return true
+}
+
+// release clears the running marks of a job. Must be called with the mutex held.
+func (q *JobQueue) release(job *Job) {
+	if q.running[job.Path] == job {
+		delete(q.running, job.Path)
+	}
+	if job.OldPath != "" && q.running[job.OldPath] == job {
+		delete(q.running, job.OldPath)
+	}
+}
+
+// DropRun removes the waiting jobs of a reindexing run and returns them. Jobs
+// that single changes were merged into stay queued as single changes and are
+// returned as detached, they no longer belong to the run.
+func (q *JobQueue) DropRun(runID int64) (dropped, detached []*Job) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for path, job := range q.pending {
+		if job.RunID != runID {
+			continue
+		}
+		if job.Changed {
+			job.RunID = 0
+			job.Changed = false
+			detached = append(detached, job)
+			continue
+		}
+		delete(q.pending, path)
+		dropped = append(dropped, job)
+	}
+	if len(dropped) > 0 || len(detached) > 0 {
+		q.changed()
+	}
+	return dropped, detached
+}
+
+// Len returns the number of waiting and running jobs
+func (q *JobQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	running := make(map[*Job]bool, len(q.running))
+	for _, job := range q.running {
+		running[job] = true
+	}
+	return len(q.pending) + len(running)
+}
+
+// changed wakes up waiting workers and schedules a save. Must be called with the mutex held.
+func (q *JobQueue) changed() {
+	q.wake()
+
+	q.dirty = true
+	if q.saveTimer == nil {
+		q.saveTimer = time.AfterFunc(queueSaveDelay, func() {
+			if err := q.Save(); err != nil {
+				log.Error().Err(err).Msg("Failed to save job queue")
+			}
+		})
+	}
+}
+
+// wake signals a waiting worker without blocking
+func (q *JobQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Save writes the waiting and running jobs to disk if the queue has changed since the last save
+func (q *JobQueue) Save() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.saveTimer != nil {
+		q.saveTimer.Stop()
+		q.saveTimer = nil
+	}
+	if !q.dirty {
+		return nil
+	}
+
+	file := queueFile{
+		Version: 1,
+		NextID:  q.nextID,
+		Jobs:    make([]*Job, 0, len(q.pending)+len(q.running)),
+	}
+	saved := make(map[*Job]bool)
+	for _, jobs := range []map[string]*Job{q.pending, q.running} {
+		for _, job := range jobs {
+			if !saved[job] {
+				saved[job] = true
+				file.Jobs = append(file.Jobs, job)
+			}
+		}
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode job queue: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("failed to create job queue directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated queue
+	tmpPath := q.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job queue: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		return fmt.Errorf("failed to replace job queue: %w", err)
+	}
+
+	q.dirty = false
+	return nil
+}
@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"obsfind/src/pkg/model"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// currentReranker returns the reranker set with SetReranker, if any
+func (s *Service) currentReranker() model.Reranker {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.reranker
+}
+
+// rerank grades the best results with the reranker and orders them by grade.
+// At least needed results are graded so the requested page comes out of the
+// reranked ones alone, the rest is dropped. Graded results score the reranker's
+// relevance and keep their previous score as vector_score in their metadata.
+// If the reranker fails or takes longer than the configured timeout the results
+// are returned as they were.
+func (s *Service) rerank(ctx context.Context, query string, results []SearchResult, needed int) []SearchResult {
+	reranker := s.currentReranker()
+	if reranker == nil || len(results) < 2 {
+		return results
+	}
+
+	topK := s.config.Indexing.RerankTopK
+	if topK < needed {
+		topK = needed
+	}
+	if topK > len(results) {
+		topK = len(results)
+	}
+
+	passages := make([]string, topK)
+	for i := range passages {
+		passages[i] = rerankPassage(results[i])
+	}
+
+	if timeout := s.config.GetRerankTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	scores, err := reranker.Rerank(ctx, query, passages)
+	if err == nil && len(scores) != len(passages) {
+		err = fmt.Errorf("reranker returned %d scores for %d passages", len(scores), len(passages))
+	}
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("model", reranker.Name()).
+			Dur("elapsed", time.Since(start)).
+			Msg("Reranking failed, keeping vector order")
+		return results
+	}
+
+	reranked := make([]SearchResult, topK)
+	for i := range reranked {
+		result := results[i]
+		metadata := make(map[string]interface{}, len(result.Metadata)+1)
+		for k, v := range result.Metadata {
+			metadata[k] = v
+		}
+		metadata["vector_score"] = result.Score
+		result.Metadata = metadata
+		result.Score = scores[i]
+		reranked[i] = result
+	}
+
+	// Stable sort keeps the vector order among equal grades
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	log.Debug().
+		Str("model", reranker.Name()).
+		Int("candidates", topK).
+		Dur("elapsed", time.Since(start)).
+		Msg("Reranked search results")
+
+	return reranked
+}
+
+// rerankPassage returns the text the reranker grades, all sections of a grouped note
+func rerankPassage(result SearchResult) string {
+	if len(result.Sections) == 0 {
+		if result.Text != "" {
+			return result.Text
+		}
+		return result.Content
+	}
+
+	texts := make([]string, 0, len(result.Sections))
+	for _, section := range result.Sections {
+		if section.Text != "" {
+			texts = append(texts, section.Text)
+		} else {
+			texts = append(texts, section.Content)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
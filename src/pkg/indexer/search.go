@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"obsfind/src/pkg/model"
+	"obsfind/src/pkg/query"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	pb "github.com/qdrant/go-client/qdrant"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,15 +24,122 @@ type SearchResult struct {
 	Score      float64                `json:"score"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 	ChunkIndex int                    `json:"chunk_index"`
+
+	// StartLine and EndLine are the lines of the chunk in the note, 0 if unknown
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+
+	// Text is the chunk with its markup, for excerpts that map back to note lines
+	Text string `json:"-"`
+
+	// id and vector identify the chunk's point for reranking
+	id     string
+	vector []float32
+
+	// fullPath identifies the note in the link graph
+	fullPath string
+
+	// BestScore and Sections are only set when results are grouped by document.
+	// Score is then the combined score of the note and BestScore that of its best chunk.
+	BestScore float64        `json:"best_score,omitempty"`
+	Sections  []SearchResult `json:"sections,omitempty"`
+}
+
+// SearchMode selects how search results are ranked
+type SearchMode string
+
+const (
+	// SearchModeSemantic ranks chunks by vector similarity only
+	SearchModeSemantic SearchMode = "semantic"
+	// SearchModeKeyword ranks chunks by BM25 keyword relevance only
+	SearchModeKeyword SearchMode = "keyword"
+	// SearchModeHybrid fuses the semantic and keyword rankings
+	SearchModeHybrid SearchMode = "hybrid"
+)
+
+// ParseSearchMode validates a search mode, an empty string selects hybrid search
+func ParseSearchMode(mode string) (SearchMode, error) {
+	switch SearchMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", SearchModeHybrid:
+		return SearchModeHybrid, nil
+	case SearchModeSemantic:
+		return SearchModeSemantic, nil
+	case SearchModeKeyword:
+		return SearchModeKeyword, nil
+	default:
+		return "", fmt.Errorf("invalid search mode %q: expected semantic, keyword or hybrid", mode)
+	}
+}
+
+// GroupBy selects whether search results are chunks or whole notes
+type GroupBy string
+
+const (
+	// GroupByChunk returns every matching chunk as its own result
+	GroupByChunk GroupBy = "chunk"
+	// GroupByDocument returns one result per note with its best matching sections
+	GroupByDocument GroupBy = "document"
+)
+
+// ParseGroupBy validates a grouping, an empty string selects chunks
+func ParseGroupBy(groupBy string) (GroupBy, error) {
+	switch GroupBy(strings.ToLower(strings.TrimSpace(groupBy))) {
+	case "", GroupByChunk:
+		return GroupByChunk, nil
+	case GroupByDocument:
+		return GroupByDocument, nil
+	default:
+		return "", fmt.Errorf("invalid grouping %q: expected chunk or document", groupBy)
+	}
 }
 
+// DefaultGroupSize is the number of sections shown per note when grouping by document
+const DefaultGroupSize = 3
+
+// groupCandidateFactor is how many chunks per requested note are ranked before grouping
+const groupCandidateFactor = 5
+
+// sectionBonus weighs the further matching sections of a note in its combined score
+const sectionBonus = 0.1
+
+// rrfK is the rank offset of reciprocal rank fusion; 60 is the value from the original paper
+const rrfK = 60
+
+// hybridCandidateFactor is how many candidates per requested result each ranking contributes
+const hybridCandidateFactor = 3
+
 // SearchOptions provides options for search operations
 type SearchOptions struct {
-	Limit      int      `json:"limit"`
-	Offset     int      `json:"offset"`
-	MinScore   float32  `json:"min_score,omitempty"`
-	Tags       []string `json:"tags,omitempty"`
-	PathPrefix string   `json:"path_prefix,omitempty"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+	MinScore   float32    `json:"min_score,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	PathPrefix string     `json:"path_prefix,omitempty"`
+	Mode       SearchMode `json:"mode,omitempty"`
+
+	// TagsMatchAll requires every tag instead of any of them
+	TagsMatchAll bool `json:"tags_match_all,omitempty"`
+
+	// Vault restricts results to the vault with this directory name
+	Vault string `json:"vault,omitempty"`
+
+	// GroupBy document returns one result per note with up to GroupSize sections
+	GroupBy   GroupBy `json:"group_by,omitempty"`
+	GroupSize int     `json:"group_size,omitempty"`
+
+	// Strategy selects how FindSimilar compares the chunks of the source note
+	Strategy SimilarStrategy `json:"strategy,omitempty"`
+
+	// Diversity between 0 and 1 trades relevance for variety with maximal
+	// marginal relevance, 0 keeps the plain ranking
+	Diversity float32 `json:"diversity,omitempty"`
+
+	// GraphBoost between 0 and 1 raises results linked with the top hits and
+	// notes many others link to, 0 keeps the plain ranking
+	GraphBoost float32 `json:"graph_boost,omitempty"`
+
+	// ExpandLinks adds the notes linked to or from the top hits, one link away
+	ExpandLinks bool `json:"expand_links,omitempty"`
 }
 
 // DefaultSearchOptions returns the default search options
@@ -38,103 +148,170 @@ func DefaultSearchOptions() SearchOptions {
 		Limit:    10,
 		Offset:   0,
 		MinScore: 0.6, // Reasonable default threshold
+		Mode:     SearchModeHybrid,
 	}
 }
 
-// Search performs a semantic search using the given query
-func (s *Service) Search(ctx context.Context, query string, options SearchOptions) ([]SearchResult, error) {
-	// Generate embedding for the query
-	embeddings, err := s.embedder.EmbedBatch(ctx, []string{query})
+// Search finds chunks matching the query using the ranking selected by options.Mode.
+// The query may contain filters in the query language, see package query.
+// MinScore only applies to semantic similarity; keyword scores are BM25 values and
+// hybrid scores are fused ranks scaled so that 1.0 means first in both rankings.
+// Grouped by document, limit and offset count notes instead of chunks.
+func (s *Service) Search(ctx context.Context, input string, options SearchOptions) ([]SearchResult, error) {
+	mode, err := ParseSearchMode(string(options.Mode))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embedding for query: %w", err)
+		return nil, err
 	}
 
-	if len(embeddings) == 0 {
-		return nil, errors.New("empty embedding generated for query")
+	groupBy, err := ParseGroupBy(string(options.GroupBy))
+	if err != nil {
+		return nil, err
 	}
 
-	// Use the embedding directly
-	queryVector := embeddings[0]
+	parsed, err := query.Parse(input)
+	if err != nil {
+		return nil, err
+	}
 
-	// Set up limit and offset
-	limit := uint64(options.Limit)
-	if limit <= 0 {
-		limit = 10
+	text := parsed.Text()
+	if text == "" {
+		return nil, &query.Error{Pos: -1, Msg: "no search terms, only filters"}
 	}
 
-	offset := uint64(options.Offset)
+	if options.Limit <= 0 {
+		options.Limit = 10
+	}
 
-	// Add proper filtering and vector name handling
-	// TODO: Implement more advanced filtering once API is stabilized
-	searchPoints, err := s.qdrantClient.Search(
-		ctx,
-		s.config.Qdrant.Collection,
-		queryVector,
-		limit,
-		offset,
-		nil, // filter
-		nil, // search params
-	)
+	if options.Diversity < 0 || options.Diversity > 1 {
+		return nil, fmt.Errorf("invalid diversity %g: expected a value between 0 and 1", options.Diversity)
+	}
+
+	if options.GraphBoost < 0 || options.GraphBoost > 1 {
+		return nil, fmt.Errorf("invalid graph boost %g: expected a value between 0 and 1", options.GraphBoost)
+	}
+
+	if err := s.ensurePayloadFields(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare filters: %w", err)
+	}
+
+	filter := mergeFilters(buildSearchFilter(options), queryFilter(parsed))
+	s.trackFieldUsage(parsed)
 
+	if groupBy == GroupByDocument || options.Diversity > 0 || options.GraphBoost > 0 || options.ExpandLinks || s.currentReranker() != nil {
+		return s.rerankedSearch(ctx, mode, text, filter, groupBy, options)
+	}
+	return s.chunkSearch(ctx, mode, text, filter, options)
+}
+
+// chunkSearch ranks chunks with the given mode
+func (s *Service) chunkSearch(ctx context.Context, mode SearchMode, text string, filter *pb.Filter, options SearchOptions) ([]SearchResult, error) {
+	switch mode {
+	case SearchModeSemantic:
+		return s.semanticSearch(ctx, text, filter, options)
+	case SearchModeKeyword:
+		return s.keywordSearch(ctx, text, filter, options)
+	default:
+		return s.hybridSearch(ctx, text, filter, options)
+	}
+}
+
+// rerankedSearch ranks extra chunk candidates, then groups them by note, grades
+// the best ones with the reranker, boosts them by their links and diversifies
+// them as requested before returning the page.
+// A note's combined score is the score of its best chunk plus a small bonus for
+// every further matching chunk, so notes that match in several places rank higher
+// than notes with a single equally good match.
+func (s *Service) rerankedSearch(ctx context.Context, mode SearchMode, text string, filter *pb.Filter, groupBy GroupBy, options SearchOptions) ([]SearchResult, error) {
+	// Rank enough chunks that the requested page still fills up
+	candidates := options
+	candidates.Offset = 0
+	candidates.Limit = (options.Offset + options.Limit) * groupCandidateFactor
+
+	chunks, err := s.chunkSearch(ctx, mode, text, filter, candidates)
 	if err != nil {
-		return nil, fmt.Errorf("search failed: %w", err)
+		return nil, err
 	}
 
-	// Convert to search results
-	results := make([]SearchResult, 0, len(searchPoints))
-	for _, point := range searchPoints {
-		// Skip results below the minimum score threshold
-		if options.MinScore > 0 && point.Score < options.MinScore {
-			continue
+	results := groupResults(chunks, groupBy, options.GroupSize)
+	results = s.rerank(ctx, text, results, options.Offset+options.Limit)
+
+	if options.GraphBoost > 0 || options.ExpandLinks {
+		results, err = s.boostByLinks(ctx, results, filter, options.GraphBoost, options.ExpandLinks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Diversity > 0 {
+		results, err = s.diversify(ctx, results, options.Diversity)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		payload := point.Payload
+	log.Debug().
+		Int("chunks", len(chunks)).
+		Int("results", len(results)).
+		Str("group_by", string(groupBy)).
+		Float32("diversity", options.Diversity).
+		Float32("graph_boost", options.GraphBoost).
+		Bool("expand_links", options.ExpandLinks).
+		Msg("Reranked search results")
 
-		// Extract fields from payload
-		path, _ := model.GetPayloadString(payload, "path")
-		content, _ := model.GetPayloadString(payload, "content")
-		title, _ := model.GetPayloadString(payload, "title")
-		section, _ := model.GetPayloadString(payload, "section")
-		tags, _ := model.GetPayloadStringSlice(payload, "tags")
-		chunkIndex, _ := model.GetPayloadInt(payload, "chunk_index")
+	return pageResults(results, options.Offset, options.Limit), nil
+}
 
-		// Apply path prefix filter if specified
-		if options.PathPrefix != "" && !strings.HasPrefix(path, options.PathPrefix) {
+// groupByDocument merges ranked chunks into one result per note.
+// The best chunk provides the note's fields and the first groupSize chunks become its sections.
+func groupByDocument(chunks []SearchResult, groupSize int) []SearchResult {
+	var groups []SearchResult
+	index := make(map[string]int)
+	matches := make(map[string]int)
+
+	for _, chunk := range chunks {
+		// Relative paths repeat across vaults, so notes are told apart by their full path
+		key := chunk.fullPath
+		if key == "" {
+			key = chunk.Path
+		}
+
+		i, ok := index[key]
+		if !ok {
+			group := chunk
+			group.BestScore = chunk.Score
+			group.Sections = []SearchResult{chunk}
+			index[key] = len(groups)
+			matches[key] = 1
+			groups = append(groups, group)
 			continue
 		}
 
-		// Apply tag filter if specified
-		if len(options.Tags) > 0 {
-			matched := false
-			for _, tag := range options.Tags {
-				for _, docTag := range tags {
-					if tag == docTag {
-						matched = true
-						break
-					}
-				}
-				if matched {
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+		// Chunks arrive best first, so later ones add a decreasing bonus
+		matches[key]++
+		groups[i].Score += sectionBonus * chunk.Score / float64(matches[key])
+		if len(groups[i].Sections) < groupSize {
+			groups[i].Sections = append(groups[i].Sections, chunk)
 		}
+	}
+
+	// Stable sort keeps the rank of the best chunk for equal scores
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Score > groups[j].Score
+	})
 
-		// Simplify metadata handling for now
-		metadata := make(map[string]interface{})
+	return groups
+}
+
+// semanticSearch ranks chunks by the similarity of their embedding to the query
+func (s *Service) semanticSearch(ctx context.Context, text string, filter *pb.Filter, options SearchOptions) ([]SearchResult, error) {
+	hits, err := s.semanticHits(ctx, text, uint64(options.Limit), uint64(options.Offset), filter, options.MinScore)
+	if err != nil {
+		return nil, err
+	}
 
-		results = append(results, SearchResult{
-			Path:       path,
-			Section:    section,
-			Title:      title,
-			Content:    content,
-			Tags:       tags,
-			Score:      float64(point.Score),
-			Metadata:   metadata,
-			ChunkIndex: chunkIndex,
-		})
+	results := make([]SearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = hit.result
 	}
 
 	// Sort by score (highest first)
@@ -145,130 +322,275 @@ func (s *Service) Search(ctx context.Context, query string, options SearchOption
 	return results, nil
 }
 
-// FindSimilar finds documents similar to the referenced path
-func (s *Service) FindSimilar(ctx context.Context, path string, options SearchOptions) ([]SearchResult, error) {
-	// Read the file content
-	content, err := s.qdrantClient.GetPointsByPath(ctx, s.config.Qdrant.Collection, path)
+// rankedResult is a search result together with the point it came from
+type rankedResult struct {
+	id     string
+	result SearchResult
+}
+
+// semanticHits runs a vector search and returns the matching results in rank order
+func (s *Service) semanticHits(ctx context.Context, text string, limit, offset uint64, filter *pb.Filter, minScore float32) ([]rankedResult, error) {
+	// Generate embedding for the query
+	embeddings, err := s.embedder.EmbedBatch(ctx, []string{text})
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve document: %w", err)
+		return nil, fmt.Errorf("failed to generate embedding for query: %w", err)
 	}
 
-	if len(content) == 0 {
-		return nil, fmt.Errorf("document not found in index: %s", path)
+	if len(embeddings) == 0 {
+		return nil, errors.New("empty embedding generated for query")
 	}
 
-	// Get all chunk vectors for the document
-	vectors := make([][]float32, 0, len(content))
-	for _, point := range content {
-		if point.Vectors != nil && point.Vectors.GetVector() != nil {
-			vectors = append(vectors, point.Vectors.GetVector().Data)
-		}
+	return s.vectorHits(ctx, embeddings[0], limit, offset, filter, minScore)
+}
+
+// vectorHits runs a vector search and returns the matching results in rank order
+func (s *Service) vectorHits(ctx context.Context, vector []float32, limit, offset uint64, filter *pb.Filter, minScore float32) ([]rankedResult, error) {
+	// Filters and the score threshold run inside the store so limit and offset apply to the matches
+	searchPoints, err := s.qdrantClient.Search(
+		ctx,
+		s.config.Qdrant.Collection,
+		vector,
+		limit,
+		offset,
+		filter,
+		nil, // search params
+		scoreThreshold(minScore),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
-	if len(vectors) == 0 {
-		return nil, fmt.Errorf("no vectors found for document: %s", path)
+	hits := make([]rankedResult, 0, len(searchPoints))
+	for _, point := range searchPoints {
+		result := payloadToResult(point.Payload, float64(point.Score))
+		result.id = point.GetId().GetUuid()
+		result.vector = point.GetVectors().GetVector().GetData()
+		hits = append(hits, rankedResult{id: result.id, result: result})
 	}
 
-	// For each vector, find similar documents
-	allResults := make([]SearchResult, 0)
+	return hits, nil
+}
 
-	// Set up limit and offset
-	limit := uint64(options.Limit)
-	if limit <= 0 {
-		limit = 10
+// keywordSearch ranks chunks by BM25 relevance to the query terms
+func (s *Service) keywordSearch(ctx context.Context, text string, filter *pb.Filter, options SearchOptions) ([]SearchResult, error) {
+	hits, err := s.keywordHits(ctx, text, options.Offset+options.Limit, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	offset := uint64(options.Offset)
+	results := make([]SearchResult, 0, options.Limit)
+	for i := options.Offset; i < len(hits) && len(results) < options.Limit; i++ {
+		results = append(results, hits[i].result)
+	}
+
+	return results, nil
+}
 
-	// Use the first vector to find similar documents
-	// This is a simplification - in a more advanced implementation,
-	// we might want to combine results from all vectors
-	if len(vectors) > 0 {
-		log.Debug().Int("vector_count", len(vectors)).Msg("Finding similar documents")
+// keywordHits returns up to limit keyword matches in rank order, with the
+// payload of each chunk loaded from the vector store
+func (s *Service) keywordHits(ctx context.Context, text string, limit int, filter *pb.Filter) ([]rankedResult, error) {
+	if err := s.ensureKeywordIndex(ctx); err != nil {
+		return nil, fmt.Errorf("keyword index unavailable: %w", err)
+	}
 
-		// Perform search with proper vector name handling
-		searchPoints, err := s.qdrantClient.Search(
-			ctx,
-			s.config.Qdrant.Collection,
-			vectors[0],
-			limit,
-			offset,
-			nil, // filter
-			nil, // search params
-		)
+	// Every match is ranked, filters are applied to them in batches by score
+	// until enough pass, so filtering doesn't cut the results short
+	matches := s.keywords.Search(text, 0)
+	batchSize := limit * hybridCandidateFactor
+
+	hits := make([]rankedResult, 0, limit)
+	for start := 0; start < len(matches) && len(hits) < limit; start += batchSize {
+		batch := matches[start:min(start+batchSize, len(matches))]
+		ids := make([]string, len(batch))
+		for i, match := range batch {
+			ids[i] = match.ID
+		}
 
+		points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, mergeFilters(idFilter(ids), filter), false)
 		if err != nil {
-			return nil, fmt.Errorf("search failed: %w", err)
+			return nil, fmt.Errorf("failed to load keyword matches: %w", err)
+		}
+
+		payloads := make(map[string]map[string]*pb.Value, len(points))
+		for _, point := range points {
+			payloads[point.GetId().GetUuid()] = point.Payload
 		}
 
-		// Convert to search results, excluding the queried path
-		for _, point := range searchPoints {
-			// Skip results below the minimum score threshold
-			if options.MinScore > 0 && point.Score < options.MinScore {
+		for _, match := range batch {
+			payload, ok := payloads[match.ID]
+			if !ok {
+				// Filtered out, or gone from the store and the keyword index will catch up on the next update
 				continue
 			}
 
-			payload := point.Payload
+			result := payloadToResult(payload, match.Score)
+			result.id = match.ID
+			hits = append(hits, rankedResult{id: match.ID, result: result})
+			if len(hits) == limit {
+				break
+			}
+		}
+	}
 
-			// Extract fields from payload
-			pointPath, _ := model.GetPayloadString(payload, "path")
+	return hits, nil
+}
 
-			// Skip self matches
-			if pointPath == path {
-				continue
-			}
+// hybridSearch fuses the semantic and keyword rankings with reciprocal rank fusion
+func (s *Service) hybridSearch(ctx context.Context, text string, filter *pb.Filter, options SearchOptions) ([]SearchResult, error) {
+	candidates := (options.Offset + options.Limit) * hybridCandidateFactor
 
-			// Apply path prefix filter if specified
-			if options.PathPrefix != "" && !strings.HasPrefix(pointPath, options.PathPrefix) {
-				continue
-			}
+	semantic, err := s.semanticHits(ctx, text, uint64(candidates), 0, filter, options.MinScore)
+	if err != nil {
+		return nil, err
+	}
 
-			// Apply tag filter if specified
-			if len(options.Tags) > 0 {
-				tags, _ := model.GetPayloadStringSlice(payload, "tags")
-				matched := false
-				for _, tag := range options.Tags {
-					for _, docTag := range tags {
-						if tag == docTag {
-							matched = true
-							break
-						}
-					}
-					if matched {
-						break
-					}
-				}
-				if !matched {
-					continue
-				}
-			}
+	keyword, err := s.keywordHits(ctx, text, candidates, filter)
+	if err != nil {
+		// Semantic results are still useful on their own
+		log.Warn().Err(err).Msg("Keyword search failed, using semantic results only")
+		keyword = nil
+	}
+
+	fused := fuseRankings(semantic, keyword)
+
+	results := make([]SearchResult, 0, options.Limit)
+	for i := options.Offset; i < len(fused) && len(results) < options.Limit; i++ {
+		results = append(results, fused[i])
+	}
+
+	log.Debug().
+		Int("semantic", len(semantic)).
+		Int("keyword", len(keyword)).
+		Int("fused", len(fused)).
+		Msg("Hybrid search completed")
+
+	return results, nil
+}
 
-			content, _ := model.GetPayloadString(payload, "content")
-			title, _ := model.GetPayloadString(payload, "title")
-			section, _ := model.GetPayloadString(payload, "section")
-			tags, _ := model.GetPayloadStringSlice(payload, "tags")
-			chunkIndex, _ := model.GetPayloadInt(payload, "chunk_index")
-
-			// Simplify metadata handling for now
-			metadata := make(map[string]interface{})
-
-			allResults = append(allResults, SearchResult{
-				Path:       pointPath,
-				Section:    section,
-				Title:      title,
-				Content:    content,
-				Tags:       tags,
-				Score:      float64(point.Score),
-				Metadata:   metadata,
-				ChunkIndex: chunkIndex,
-			})
+// fuseRankings combines rankings with reciprocal rank fusion.
+// Scores are scaled so that a chunk ranked first in every ranking scores 1.0.
+func fuseRankings(rankings ...[]rankedResult) []SearchResult {
+	scores := make(map[string]float64)
+	results := make(map[string]SearchResult)
+	var order []string
+
+	for _, ranking := range rankings {
+		for rank, hit := range ranking {
+			if _, ok := results[hit.id]; !ok {
+				results[hit.id] = hit.result
+				order = append(order, hit.id)
+			}
+			scores[hit.id] += 1.0 / float64(rrfK+rank+1)
 		}
 	}
 
-	// Sort by score (highest first)
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Score > allResults[j].Score
+	maxScore := float64(len(rankings)) / float64(rrfK+1)
+
+	fused := make([]SearchResult, len(order))
+	for i, id := range order {
+		result := results[id]
+		result.Score = scores[id] / maxScore
+		fused[i] = result
+	}
+
+	// Stable sort keeps the semantic order for equal scores
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
 	})
 
-	return allResults, nil
+	return fused
+}
+
+// payloadToResult converts a stored chunk payload into a search result
+func payloadToResult(payload map[string]*pb.Value, score float64) SearchResult {
+	// Extract fields from payload
+	path, _ := model.GetPayloadString(payload, "path")
+	content, _ := model.GetPayloadString(payload, "content")
+	title, _ := model.GetPayloadString(payload, "title")
+	section, _ := model.GetPayloadString(payload, "section")
+	tags, _ := model.GetPayloadStringSlice(payload, "tags")
+	chunkIndex, _ := model.GetPayloadInt(payload, "chunk_index")
+	startLine, _ := model.GetPayloadInt(payload, "start_line")
+	endLine, _ := model.GetPayloadInt(payload, "end_line")
+	text, _ := model.GetPayloadString(payload, "text")
+	fullPath, _ := model.GetPayloadString(payload, "full_path")
+
+	// Simplify metadata handling for now
+	metadata := make(map[string]interface{})
+
+	// Text inlined from an embedded note is reported at that note
+	if origin, ok := model.GetPayloadString(payload, "origin_path"); ok && origin != "" {
+		metadata["embedded_in"] = path
+		vaultPath, _ := model.GetPayloadString(payload, "vault_path")
+		fullPath = filepath.Join(vaultPath, filepath.FromSlash(origin))
+		path = origin
+		startLine, _ = model.GetPayloadInt(payload, "origin_start_line")
+		endLine, _ = model.GetPayloadInt(payload, "origin_end_line")
+	} else if embeds := embedPaths(payload); len(embeds) > 0 {
+		metadata["embeds"] = embeds
+	}
+
+	if blockType, ok := model.GetPayloadString(payload, "block_type"); ok && blockType != "" {
+		language, _ := model.GetPayloadString(payload, "language")
+		metadata["block_type"] = blockType
+		metadata["language"] = language
+	}
+
+	return SearchResult{
+		Path:       path,
+		Section:    section,
+		Title:      title,
+		Content:    content,
+		Tags:       tags,
+		Score:      score,
+		Metadata:   metadata,
+		ChunkIndex: chunkIndex,
+		StartLine:  startLine,
+		EndLine:    endLine,
+		Text:       text,
+		fullPath:   fullPath,
+	}
+}
+
+// scoreThreshold converts a minimum score option into the store's score threshold
+func scoreThreshold(minScore float32) *float32 {
+	if minScore <= 0 {
+		return nil
+	}
+	return &minScore
+}
+
+// keywordText returns the text of a chunk that goes into the keyword index.
+// Aliases are included so searching any name of a note finds it.
+func keywordText(payload map[string]*pb.Value) string {
+	title, _ := model.GetPayloadString(payload, "title")
+	aliases, _ := model.GetPayloadStringSlice(payload, "aliases")
+	text, _ := model.GetPayloadString(payload, "text")
+	return title + "\n" + strings.Join(aliases, "\n") + "\n" + text
+}
+
+// ensureKeywordIndex fills the keyword index from the stored chunks if it
+// was never built, e.g. for collections indexed by an older version
+func (s *Service) ensureKeywordIndex(ctx context.Context) error {
+	if !s.keywords.NeedsRebuild() {
+		return nil
+	}
+
+	points, err := s.qdrantClient.ScrollPoints(ctx, s.config.Qdrant.Collection, nil, false)
+	if err != nil {
+		return err
+	}
+
+	s.keywords.Reset()
+	for _, point := range points {
+		s.keywords.Add(point.GetId().GetUuid(), keywordText(point.Payload))
+	}
+
+	if err := s.keywords.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to save keyword index")
+	}
+
+	log.Info().Int("chunks", len(points)).Msg("Rebuilt keyword index")
+	return nil
 }
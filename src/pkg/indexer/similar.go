@@ -0,0 +1,289 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"obsfind/src/pkg/model"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/rs/zerolog/log"
+)
+
+// SimilarStrategy selects how the chunks of a note are compared when looking for similar notes
+type SimilarStrategy string
+
+const (
+	// SimilarMax searches with every chunk and scores each match by its best similarity
+	SimilarMax SimilarStrategy = "max"
+	// SimilarCentroid searches once with the mean of the chunk vectors
+	SimilarCentroid SimilarStrategy = "centroid"
+	// SimilarSection searches with every chunk and returns the matches of each section separately
+	SimilarSection SimilarStrategy = "section"
+)
+
+// ParseSimilarStrategy validates a similarity strategy, an empty string selects max
+func ParseSimilarStrategy(strategy string) (SimilarStrategy, error) {
+	switch SimilarStrategy(strings.ToLower(strings.TrimSpace(strategy))) {
+	case "", SimilarMax:
+		return SimilarMax, nil
+	case SimilarCentroid:
+		return SimilarCentroid, nil
+	case SimilarSection:
+		return SimilarSection, nil
+	default:
+		return "", fmt.Errorf("invalid similarity strategy %q: expected max, centroid or section", strategy)
+	}
+}
+
+// maxSimilarQueries caps the searches per note, long notes are sampled evenly
+const maxSimilarQueries = 32
+
+// sourceChunk is a chunk of the note FindSimilar compares against
+type sourceChunk struct {
+	vector    []float32
+	section   string
+	index     int
+	startLine int
+}
+
+// FindSimilar finds chunks or notes similar to the note at path, which is either
+// relative to its vault or absolute. The note itself is never part of the results.
+// With the section strategy limit and offset apply to the matches of every section,
+// which are annotated with source_section and source_chunk in their metadata.
+func (s *Service) FindSimilar(ctx context.Context, path string, options SearchOptions) ([]SearchResult, error) {
+	strategy, err := ParseSimilarStrategy(string(options.Strategy))
+	if err != nil {
+		return nil, err
+	}
+
+	groupBy, err := ParseGroupBy(string(options.GroupBy))
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Limit <= 0 {
+		options.Limit = 10
+	}
+	if options.GroupSize <= 0 {
+		options.GroupSize = DefaultGroupSize
+	}
+
+	points, err := s.notePoints(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve document: %w", err)
+	}
+
+	if len(points) == 0 {
+		return nil, fmt.Errorf("document not found in index: %s", path)
+	}
+
+	// Get all chunk vectors for the document in note order
+	sources := make([]sourceChunk, 0, len(points))
+	for _, point := range points {
+		if point.Vectors == nil || point.Vectors.GetVector() == nil {
+			continue
+		}
+		section, _ := model.GetPayloadString(point.Payload, "section")
+		chunkIndex, _ := model.GetPayloadInt(point.Payload, "chunk_index")
+		startLine, _ := model.GetPayloadInt(point.Payload, "start_line")
+		sources = append(sources, sourceChunk{
+			vector:    point.Vectors.GetVector().Data,
+			section:   section,
+			index:     chunkIndex,
+			startLine: startLine,
+		})
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no vectors found for document: %s", path)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].index < sources[j].index
+	})
+
+	if err := s.ensurePayloadFields(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare filters: %w", err)
+	}
+
+	// Leave out the note itself, by full path since relative paths repeat across vaults
+	filter := buildSearchFilter(options)
+	if filter == nil {
+		filter = &pb.Filter{}
+	}
+	if fullPath, ok := model.GetPayloadString(points[0].Payload, "full_path"); ok && fullPath != "" {
+		filter.MustNot = append(filter.MustNot, keywordCondition("full_path", fullPath))
+	} else {
+		relPath, _ := model.GetPayloadString(points[0].Payload, "path")
+		filter.MustNot = append(filter.MustNot, keywordCondition("path", relPath))
+	}
+
+	// Rank enough chunks that grouping still fills the page
+	candidates := options.Offset + options.Limit
+	if groupBy == GroupByDocument {
+		candidates *= groupCandidateFactor
+	}
+
+	log.Debug().
+		Str("path", path).
+		Str("strategy", string(strategy)).
+		Int("chunks", len(sources)).
+		Msg("Finding similar documents")
+
+	switch strategy {
+	case SimilarCentroid:
+		hits, err := s.vectorHits(ctx, centroid(sources), uint64(candidates), 0, filter, options.MinScore)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		return pageResults(groupResults(rankedResults(hits), groupBy, options.GroupSize), options.Offset, options.Limit), nil
+
+	case SimilarSection:
+		var results []SearchResult
+		for _, source := range sampleSources(sources) {
+			hits, err := s.vectorHits(ctx, source.vector, uint64(candidates), 0, filter, options.MinScore)
+			if err != nil {
+				return nil, fmt.Errorf("search failed: %w", err)
+			}
+
+			matches := pageResults(groupResults(rankedResults(hits), groupBy, options.GroupSize), options.Offset, options.Limit)
+			for i := range matches {
+				annotateSource(&matches[i], source)
+			}
+			results = append(results, matches...)
+		}
+		return results, nil
+
+	default:
+		// Every match keeps the score of the chunk of the note it resembles most
+		best := make(map[string]rankedResult)
+		var order []string
+		for _, source := range sampleSources(sources) {
+			hits, err := s.vectorHits(ctx, source.vector, uint64(candidates), 0, filter, options.MinScore)
+			if err != nil {
+				return nil, fmt.Errorf("search failed: %w", err)
+			}
+
+			for _, hit := range hits {
+				current, ok := best[hit.id]
+				if !ok {
+					order = append(order, hit.id)
+				}
+				if !ok || hit.result.Score > current.result.Score {
+					annotateSource(&hit.result, source)
+					best[hit.id] = hit
+				}
+			}
+		}
+
+		merged := make([]SearchResult, len(order))
+		for i, id := range order {
+			merged[i] = best[id].result
+		}
+		sort.SliceStable(merged, func(i, j int) bool {
+			return merged[i].Score > merged[j].Score
+		})
+
+		if len(merged) > candidates {
+			merged = merged[:candidates]
+		}
+		return pageResults(groupResults(merged, groupBy, options.GroupSize), options.Offset, options.Limit), nil
+	}
+}
+
+// notePoints returns the stored chunks of a note given its vault-relative or absolute path
+func (s *Service) notePoints(ctx context.Context, path string) ([]*pb.RetrievedPoint, error) {
+	if filepath.IsAbs(path) {
+		return s.filePoints(ctx, filepath.Clean(path), true)
+	}
+
+	points, err := s.qdrantClient.GetPointsByPath(ctx, s.config.Qdrant.Collection, filepath.ToSlash(filepath.Clean(path)), true)
+	if err != nil || len(points) == 0 {
+		return points, err
+	}
+
+	// The same relative path can exist in several vaults, stick to the first one
+	fullPath, _ := model.GetPayloadString(points[0].Payload, "full_path")
+	note := points[:0]
+	for _, point := range points {
+		if p, _ := model.GetPayloadString(point.Payload, "full_path"); p == fullPath {
+			note = append(note, point)
+		}
+	}
+	return note, nil
+}
+
+// sampleSources picks at most maxSimilarQueries chunks, spread evenly over the note
+func sampleSources(sources []sourceChunk) []sourceChunk {
+	if len(sources) <= maxSimilarQueries {
+		return sources
+	}
+
+	sampled := make([]sourceChunk, maxSimilarQueries)
+	for i := range sampled {
+		sampled[i] = sources[i*len(sources)/maxSimilarQueries]
+	}
+	return sampled
+}
+
+// centroid returns the mean of the chunk vectors
+func centroid(sources []sourceChunk) []float32 {
+	mean := make([]float32, len(sources[0].vector))
+	for _, source := range sources {
+		for i, v := range source.vector {
+			if i < len(mean) {
+				mean[i] += v
+			}
+		}
+	}
+	for i := range mean {
+		mean[i] /= float32(len(sources))
+	}
+	return mean
+}
+
+// annotateSource records which chunk of the source note a match resembles
+func annotateSource(result *SearchResult, source sourceChunk) {
+	metadata := make(map[string]interface{}, len(result.Metadata)+3)
+	for k, v := range result.Metadata {
+		metadata[k] = v
+	}
+	metadata["source_section"] = source.section
+	metadata["source_chunk"] = source.index
+	if source.startLine > 0 {
+		metadata["source_line"] = source.startLine
+	}
+	result.Metadata = metadata
+}
+
+// rankedResults returns the results of ranked hits
+func rankedResults(hits []rankedResult) []SearchResult {
+	results := make([]SearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = hit.result
+	}
+	return results
+}
+
+// groupResults groups chunks by note if requested
+func groupResults(results []SearchResult, groupBy GroupBy, groupSize int) []SearchResult {
+	if groupBy != GroupByDocument {
+		return results
+	}
+	if groupSize <= 0 {
+		groupSize = DefaultGroupSize
+	}
+	return groupByDocument(results, groupSize)
+}
+
+// pageResults returns up to limit results starting at offset
+func pageResults(results []SearchResult, offset, limit int) []SearchResult {
+	page := make([]SearchResult, 0, limit)
+	for i := offset; i < len(results) && len(page) < limit; i++ {
+		page = append(page, results[i])
+	}
+	return page
+}
@@ -0,0 +1,86 @@
+package indexer
+
+import (
+	"obsfind/src/pkg/markdown"
+	"os"
+	"path/filepath"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/rs/zerolog/log"
+)
+
+// transclude inlines the embeds of a note, reading the embedded notes from its vault
+func (s *Service) transclude(vaultPath, path, content string) *markdown.Transclusion {
+	transcluder := markdown.NewTranscluder(
+		func(link markdown.Link, source string) string {
+			return s.links.Resolve(vaultPath, link, source)
+		},
+		func(note string) (string, error) {
+			data, err := os.ReadFile(filepath.Join(vaultPath, filepath.FromSlash(note)))
+			return string(data), err
+		},
+		s.config.Indexing.TransclusionDepth,
+	)
+
+	return transcluder.Transclude(content, vaultRelative(vaultPath, path))
+}
+
+// reindexEmbedders queues the notes that inline a note through embeds, also
+// through other embedded notes, so they pick up its edited text
+func (s *Service) reindexEmbedders(path string) {
+	if !s.config.Indexing.TranscludeEmbeds {
+		return
+	}
+
+	vaultPath := s.findBaseVaultPath(path)
+	seen := map[string]bool{path: true}
+	level := []string{path}
+
+	for depth := 0; depth < s.config.Indexing.TransclusionDepth && len(level) > 0; depth++ {
+		var next []string
+		for _, note := range level {
+			for _, backlink := range s.links.Backlinks(note) {
+				source := filepath.Join(vaultPath, filepath.FromSlash(backlink.Source))
+				if !backlink.Embed || seen[source] {
+					continue
+				}
+				seen[source] = true
+				next = append(next, source)
+
+				s.Enqueue(Job{Type: JobIndex, Path: source, Force: true, Priority: PriorityChange})
+				log.Debug().Str("path", source).Str("embedded", path).Msg("Queued note embedding a changed note")
+			}
+		}
+		level = next
+	}
+}
+
+// embedPayload describes the embeds of a chunk for its payload
+func embedPayload(embeds []markdown.Embed) []map[string]interface{} {
+	payload := make([]map[string]interface{}, len(embeds))
+	for i, embed := range embeds {
+		payload[i] = map[string]interface{}{
+			"path":       embed.Path,
+			"heading":    embed.Heading,
+			"block":      embed.Block,
+			"depth":      embed.Depth,
+			"start_line": embed.StartLine,
+			"end_line":   embed.EndLine,
+		}
+	}
+	return payload
+}
+
+// embedPaths returns the distinct notes a chunk has embedded text of, from its payload
+func embedPaths(payload map[string]*pb.Value) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, value := range payload["embeds"].GetListValue().GetValues() {
+		path := value.GetStructValue().GetFields()["path"].GetStringValue()
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,378 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BlockType is the kind of a markdown block
+type BlockType string
+
+const (
+	BlockParagraph BlockType = "paragraph"
+	BlockHeading   BlockType = "heading"
+	BlockCode      BlockType = "code"    // fenced code block
+	BlockCallout   BlockType = "callout" // Obsidian callout, > [!note] Title
+	BlockQuote     BlockType = "quote"
+	BlockTable     BlockType = "table"
+	BlockList      BlockType = "list"
+	BlockTasks     BlockType = "tasks"   // list with at least one task, - [ ] or - [x]
+	BlockComment   BlockType = "comment" // <!-- HTML --> or %% Obsidian %% comment
+)
+
+var (
+	// fenceRegex matches the opening line of a fenced code block and its info string
+	fenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	// calloutRegex matches the first line of a callout: its type, fold marker and title
+	calloutRegex = regexp.MustCompile(`^\s*>\s*\[!([^\]]+)\]([+-]?)[ \t]*(.*)$`)
+	// tableDelimiterRegex matches the row under a table header, like |---|:--:|
+	tableDelimiterRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	// taskRegex matches a list item with a checkbox
+	taskRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[(.)\](?:\s|$)`)
+	// listMarkerRegex matches the marker and checkbox of a list item
+	listMarkerRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[.\](?:\s+|$))?`)
+	// quotePrefixRegex matches the > markers of a quote or callout line
+	quotePrefixRegex = regexp.MustCompile(`^\s*(?:>\s?)+`)
+	// commentRegex matches comments, running to the end of the text if unclosed
+	commentRegex = regexp.MustCompile(`(?s)%%.*?(?:%%|$)|<!--.*?(?:-->|$)`)
+	// strongRegex matches bold, strikethrough and highlighted text
+	strongRegex = regexp.MustCompile(`\*\*([^*\n]+)\*\*|__([^_\n]+)__|~~([^~\n]+)~~|==([^=\n]+)==`)
+	// emphasisRegex matches italic text, leaving underscores inside words alone
+	emphasisRegex = regexp.MustCompile(`\*([^*\s](?:[^*\n]*[^*\s])?)\*|\b_([^_\s](?:[^_\n]*[^_\s])?)_\b`)
+	// thematicBreakRegex matches a horizontal rule, like --- or * * *
+	thematicBreakRegex = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	// htmlTagRegex matches HTML tags, like <br> or <span class="x">
+	htmlTagRegex = regexp.MustCompile(`</?[A-Za-z][^>\n]*>`)
+)
+
+// Block is a piece of a note that chunks don't split: a paragraph, a heading,
+// a code block, a callout, a quote, a table, a list or a comment
+type Block struct {
+	Type  BlockType
+	Text  string // text of the block as written
+	Level int    // heading level
+
+	Title    string // heading text, or the title of a callout
+	Callout  string // callout type in lower case, like "note" or "warning"
+	Language string // language of a code block in lower case, empty if not given
+	Code     string // code of a code block, without its fences
+
+	// StartLine and EndLine are 1-based lines of the parsed text, the offsets
+	// are in bytes with EndOffset at the end of the last line
+	StartLine   int
+	EndLine     int
+	StartOffset int
+	EndOffset   int
+}
+
+// ParseBlocks splits markdown into its blocks. Blank lines separate blocks
+// except inside code blocks and comments, which run to their closing line
+// or the end of the text. Headings inside code blocks are code.
+func ParseBlocks(content string) []Block {
+	lines := strings.Split(content, "\n")
+	offsets := make([]int, len(lines))
+	for i, offset := 0, 0; i < len(lines); i++ {
+		offsets[i] = offset
+		offset += len(lines[i]) + 1
+	}
+
+	var blocks []Block
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			i++
+			continue
+		}
+
+		fence := fenceOpening(line)
+		heading := embedHeadingRegex.FindStringSubmatch(line)
+
+		block := Block{Type: BlockParagraph}
+		end := i
+		switch {
+		case fence != nil:
+			block.Type = BlockCode
+			block.Language = codeLanguage(fence[2])
+
+			end = len(lines) - 1
+			codeEnd := len(lines)
+			for j := i + 1; j < len(lines); j++ {
+				if closesFence(lines[j], fence[1]) {
+					end, codeEnd = j, j
+					break
+				}
+			}
+			block.Code = strings.Join(lines[i+1:codeEnd], "\n")
+
+		case strings.HasPrefix(trimmed, "<!--"):
+			block.Type = BlockComment
+			end = commentEnd(lines, i, "<!--", "-->")
+
+		case strings.HasPrefix(trimmed, "%%"):
+			block.Type = BlockComment
+			end = commentEnd(lines, i, "%%", "%%")
+
+		case heading != nil && strings.TrimSpace(heading[2]) != "":
+			block.Type = BlockHeading
+			block.Level = len(heading[1])
+			block.Title = strings.TrimSpace(heading[2])
+
+		case strings.HasPrefix(trimmed, ">"):
+			block.Type = BlockQuote
+			if callout := calloutRegex.FindStringSubmatch(line); callout != nil {
+				block.Type = BlockCallout
+				block.Callout = strings.ToLower(strings.TrimSpace(callout[1]))
+				block.Title = strings.TrimSpace(callout[3])
+			}
+			for end+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end+1]), ">") {
+				end++
+			}
+
+		case strings.Contains(line, "|") && i+1 < len(lines) &&
+			strings.Contains(lines[i+1], "|") && tableDelimiterRegex.MatchString(lines[i+1]):
+			block.Type = BlockTable
+			end = i + 1
+			for end+1 < len(lines) && strings.Contains(lines[end+1], "|") && strings.TrimSpace(lines[end+1]) != "" {
+				end++
+			}
+
+		case listItemRegex.MatchString(line):
+			block.Type = BlockList
+			// Items and their indented continuation lines
+			for end+1 < len(lines) && strings.TrimSpace(lines[end+1]) != "" &&
+				(listItemRegex.MatchString(lines[end+1]) || startsIndented(lines[end+1])) &&
+				fenceOpening(lines[end+1]) == nil {
+				end++
+			}
+			for _, item := range lines[i : end+1] {
+				if taskRegex.MatchString(item) {
+					block.Type = BlockTasks
+					break
+				}
+			}
+
+		default:
+			for end+1 < len(lines) && !interruptsParagraph(lines[end+1]) {
+				end++
+			}
+		}
+
+		block.StartLine = i + 1
+		block.EndLine = end + 1
+		block.StartOffset = offsets[i]
+		block.EndOffset = offsets[end] + len(lines[end])
+		block.Text = content[block.StartOffset:block.EndOffset]
+		blocks = append(blocks, block)
+
+		i = end + 1
+	}
+
+	return blocks
+}
+
+// fenceOpening returns the fence and info string of a line opening a code
+// block, or nil. Info strings of backtick fences can't contain backticks,
+// which tells ```inline code``` apart.
+func fenceOpening(line string) []string {
+	fence := fenceRegex.FindStringSubmatch(line)
+	if fence == nil || fence[1][0] == '`' && strings.Contains(fence[2], "`") {
+		return nil
+	}
+	return fence
+}
+
+// codeLanguage returns the language named by the info string of a code fence,
+// like "go" for ```go or "python" for ```{python}
+func codeLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(fields[0], "{}."))
+}
+
+// closesFence reports whether a line closes a code block opened with fence:
+// the same character at least as many times and nothing else
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 || len(trimmed) < len(fence) {
+		return false
+	}
+	return strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// commentEnd returns the line a comment starting on line start is closed on,
+// or the last line if it is never closed
+func commentEnd(lines []string, start int, open, close string) int {
+	first := strings.TrimSpace(lines[start])
+	if strings.Contains(first[len(open):], close) {
+		return start
+	}
+	for i := start + 1; i < len(lines); i++ {
+		if strings.Contains(lines[i], close) {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+// interruptsParagraph reports whether a line ends the paragraph before it:
+// a blank line or the start of another kind of block
+func interruptsParagraph(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" ||
+		fenceOpening(line) != nil ||
+		strings.HasPrefix(trimmed, "<!--") ||
+		strings.HasPrefix(trimmed, "%%") ||
+		strings.HasPrefix(trimmed, ">") ||
+		listItemRegex.MatchString(line) ||
+		embedHeadingRegex.MatchString(line)
+}
+
+// startsIndented reports whether a line starts with a space or tab
+func startsIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// splitParagraphs splits text at blank lines outside of blocks, so code blocks
+// and comments containing blank lines stay in one piece
+func splitParagraphs(text string) []string {
+	blocks := ParseBlocks(text)
+
+	var paragraphs []string
+	for i := 0; i < len(blocks); {
+		last := i
+		for last+1 < len(blocks) && blocks[last+1].StartLine == blocks[last].EndLine+1 {
+			last++
+		}
+		paragraphs = append(paragraphs, text[blocks[i].StartOffset:blocks[last].EndOffset])
+		i = last + 1
+	}
+	return paragraphs
+}
+
+// plainText returns the text of markdown without its markup: code blocks and
+// comments are left out, headings, callouts, quotes, tables and lists keep
+// their text only, and links are replaced by the text they show
+func plainText(text string) string {
+	var parts []string
+	for _, block := range ParseBlocks(text) {
+		lines := strings.Split(block.Text, "\n")
+		switch block.Type {
+		case BlockCode:
+			continue
+		case BlockComment:
+			// Text after a comment on its last line is kept
+			lines = strings.Split(commentRegex.ReplaceAllString(block.Text, ""), "\n")
+		case BlockHeading:
+			lines = []string{block.Title}
+		case BlockCallout:
+			lines[0] = block.Title
+			for i := 1; i < len(lines); i++ {
+				lines[i] = quotePrefixRegex.ReplaceAllString(lines[i], "")
+			}
+		case BlockQuote:
+			for i, line := range lines {
+				lines[i] = quotePrefixRegex.ReplaceAllString(line, "")
+			}
+		case BlockTable:
+			var rows []string
+			for i, line := range lines {
+				if i == 1 {
+					continue // delimiter row
+				}
+				var cells []string
+				for _, cell := range tableCells(line) {
+					if cell = plainLine(cell); cell != "" {
+						cells = append(cells, cell)
+					}
+				}
+				rows = append(rows, strings.Join(cells, ", "))
+			}
+			lines = rows
+		case BlockList, BlockTasks:
+			for i, line := range lines {
+				lines[i] = listMarkerRegex.ReplaceAllString(line, "")
+			}
+		}
+
+		var plain []string
+		for _, line := range lines {
+			if thematicBreakRegex.MatchString(line) {
+				continue
+			}
+			if line = plainLine(line); line != "" {
+				plain = append(plain, line)
+			}
+		}
+		if len(plain) > 0 {
+			parts = append(parts, strings.Join(plain, "\n"))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// plainLine removes the inline markup of a line. Code spans keep their text.
+func plainLine(line string) string {
+	line = commentRegex.ReplaceAllString(line, "")
+	if loc := blockIDRegex.FindStringIndex(line); loc != nil {
+		line = line[:loc[0]]
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range inlineCodeRegex.FindAllStringIndex(line, -1) {
+		b.WriteString(plainInline(line[last:span[0]]))
+		b.WriteString(line[span[0]+1 : span[1]-1])
+		last = span[1]
+	}
+	b.WriteString(plainInline(line[last:]))
+
+	return strings.TrimSpace(b.String())
+}
+
+// plainInline replaces links by the text they show and removes emphasis and HTML tags
+func plainInline(text string) string {
+	text = wikiLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		link, ok := parseWikiLink(wikiLinkRegex.FindStringSubmatch(match)[2])
+		switch {
+		case !ok:
+			return match
+		case link.Alias != "":
+			return link.Alias
+		case link.Heading != "" && link.Target != "":
+			return link.Target + " > " + link.Heading
+		case link.Heading != "":
+			return link.Heading
+		}
+		return link.Target
+	})
+	text = markdownLinkRegex.ReplaceAllString(text, "$2")
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	text = strongRegex.ReplaceAllString(text, "$1$2$3$4")
+	return emphasisRegex.ReplaceAllString(text, "$1$2")
+}
+
+// tableCells splits a table row into its cells. Escaped pipes, like those of
+// [[Note\|alias]] inside tables, don't separate cells.
+func tableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+
+	var cells []string
+	start := 0
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, row[start:i])
+			start = i + 1
+		}
+	}
+	return append(cells, row[start:])
+}
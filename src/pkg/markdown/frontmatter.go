@@ -0,0 +1,181 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// zonedDateLayouts are the datetime formats with a zone
+	zonedDateLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999Z07:00",
+	}
+	// localDateLayouts are the date and datetime formats without a zone,
+	// including Obsidian's datetimes without seconds
+	localDateLayouts = []string{
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+)
+
+// extractFrontmatter splits the YAML frontmatter off a note and parses it.
+// The body is returned without the frontmatter even if it isn't valid YAML.
+func extractFrontmatter(content []byte) (map[string]interface{}, []byte, error) {
+	block, body, ok := splitFrontmatter(content)
+	if !ok {
+		return nil, content, nil
+	}
+
+	frontmatter, err := parseFrontmatter(block)
+	if err != nil {
+		return nil, body, err
+	}
+	return frontmatter, body, nil
+}
+
+// splitFrontmatter separates the frontmatter block, between a first line of ---
+// and the next line of --- or ..., from the body of a note
+func splitFrontmatter(content []byte) ([]byte, []byte, bool) {
+	start := bytes.IndexByte(content, '\n')
+	if start < 0 || strings.TrimSpace(string(content[:start])) != "---" {
+		return nil, content, false
+	}
+	start++
+
+	for offset := start; offset < len(content); {
+		line, next := content[offset:], len(content)
+		if end := bytes.IndexByte(line, '\n'); end >= 0 {
+			line, next = line[:end], offset+end+1
+		}
+
+		if delimiter := strings.TrimSpace(string(line)); delimiter == "---" || delimiter == "..." {
+			return content[start:offset], content[next:], true
+		}
+		offset = next
+	}
+
+	return nil, content, false
+}
+
+// parseFrontmatter parses a frontmatter block into typed values: strings, int
+// and float64 numbers, bool checkboxes, []interface{} lists, nested
+// map[string]interface{} objects and time.Time dates and datetimes. Dates and
+// datetimes without a zone are read in local time, like in queries.
+func parseFrontmatter(block []byte) (map[string]interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(block, &doc); err != nil {
+		return nil, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+
+	// An empty block has no document node
+	if len(doc.Content) == 0 {
+		return make(map[string]interface{}), nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid frontmatter: expected properties, got a %s", root.ShortTag())
+	}
+	return propertyValue(root).(map[string]interface{}), nil
+}
+
+// propertyValue converts a YAML node into its Go value
+func propertyValue(n *yaml.Node) interface{} {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return propertyValue(n.Content[0])
+	case yaml.AliasNode:
+		return propertyValue(n.Alias)
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			list = append(list, propertyValue(item))
+		}
+		return list
+	case yaml.MappingNode:
+		object := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			object[n.Content[i].Value] = propertyValue(n.Content[i+1])
+		}
+		return object
+	default:
+		return scalarValue(n)
+	}
+}
+
+// scalarValue converts a YAML scalar into a string, number, bool, date or nil
+func scalarValue(n *yaml.Node) interface{} {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool", "!!int", "!!float":
+		var v interface{}
+		if err := n.Decode(&v); err == nil {
+			return v
+		}
+	case "!!timestamp":
+		if t, ok := parsePropertyDate(n.Value); ok {
+			return t
+		}
+	case "!!str":
+		// Obsidian writes datetimes without seconds, which YAML reads as text
+		if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 {
+			if t, ok := parsePropertyDate(n.Value); ok {
+				return t
+			}
+		}
+	}
+	return n.Value
+}
+
+// parsePropertyDate parses the value of a date or datetime property. Values
+// with a zone are moved to local time, so all dates read as local clock times.
+func parsePropertyDate(value string) (time.Time, bool) {
+	for _, layout := range zonedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.In(time.Local), true
+		}
+	}
+
+	for _, layout := range localDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// frontmatterStrings reads a text or list property as a list of strings.
+// Text is split at commas, the way Obsidian reads older notes.
+func frontmatterStrings(value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if item != nil {
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
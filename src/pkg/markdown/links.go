@@ -0,0 +1,223 @@
+package markdown
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// wikiLinkRegex matches [[Note]], [[Note#Heading|alias]] and embeds like ![[Note]]
+	wikiLinkRegex = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+?)\]\]`)
+	// markdownLinkRegex matches [text](path) and ![alt](path), with an optional title
+	markdownLinkRegex = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\((<[^>\n]+>|[^)\s]+)(?:\s+"[^"\n]*")?\)`)
+	// inlineCodeRegex matches code spans, which don't contain links
+	inlineCodeRegex = regexp.MustCompile("`[^`\n]+`")
+)
+
+// Link is an outgoing link of a note
+type Link struct {
+	Target   string `json:"target"`             // note as written, without heading, block and alias
+	Heading  string `json:"heading,omitempty"`  // heading of [[Note#Heading]]
+	Block    string `json:"block,omitempty"`    // block ID of [[Note#^id]]
+	Alias    string `json:"alias,omitempty"`    // display text of [[Note|alias]] and [text](note.md)
+	Embed    bool   `json:"embed,omitempty"`    // ![[Note]] and ![alt](file)
+	Markdown bool   `json:"markdown,omitempty"` // [text](path) rather than a wikilink
+	Line     int    `json:"line"`               // line of the note the link is on, 1-based
+
+	// Resolved is the path of the linked note relative to the vault, empty if no note matches
+	Resolved string `json:"resolved,omitempty"`
+}
+
+// extractLinks returns the wikilinks, embeds and relative markdown links of
+// content, skipping code. firstLine is the line number of the content's first line.
+func extractLinks(content string, firstLine int) []Link {
+	links := []Link{}
+
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.Contains(line, "](") && !strings.Contains(line, "[[") {
+			continue
+		}
+
+		line = inlineCodeRegex.ReplaceAllStringFunc(line, func(code string) string {
+			return strings.Repeat(" ", len(code))
+		})
+		lineNumber := firstLine + i
+
+		for _, match := range wikiLinkRegex.FindAllStringSubmatch(line, -1) {
+			if link, ok := parseWikiLink(match[2]); ok {
+				link.Embed = match[1] == "!"
+				link.Line = lineNumber
+				links = append(links, link)
+			}
+		}
+
+		for _, match := range markdownLinkRegex.FindAllStringSubmatch(line, -1) {
+			if link, ok := parseMarkdownLink(match[3]); ok {
+				link.Alias = match[2]
+				link.Embed = match[1] == "!"
+				link.Line = lineNumber
+				links = append(links, link)
+			}
+		}
+	}
+
+	return links
+}
+
+// parseWikiLink splits the inside of [[...]] into target, heading or block, and alias
+func parseWikiLink(inner string) (Link, bool) {
+	var link Link
+
+	// Links inside tables escape the alias separator
+	inner = strings.ReplaceAll(inner, `\|`, "|")
+	if target, alias, ok := strings.Cut(inner, "|"); ok {
+		inner = target
+		link.Alias = strings.TrimSpace(alias)
+	}
+
+	target, fragment, _ := strings.Cut(inner, "#")
+	link.Target = strings.TrimSpace(target)
+	setFragment(&link, fragment)
+
+	return link, link.Target != "" || link.Heading != "" || link.Block != ""
+}
+
+// parseMarkdownLink turns the destination of [text](...) into a link, leaving out
+// web addresses and anchors within the note
+func parseMarkdownLink(destination string) (Link, bool) {
+	destination = strings.TrimSuffix(strings.TrimPrefix(destination, "<"), ">")
+	if destination == "" || strings.HasPrefix(destination, "#") || strings.Contains(destination, ":") {
+		return Link{}, false
+	}
+
+	target, fragment, _ := strings.Cut(destination, "#")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	link := Link{Target: target, Markdown: true}
+	setFragment(&link, fragment)
+	return link, true
+}
+
+// setFragment sets the heading or the block a link points at
+func setFragment(link *Link, fragment string) {
+	fragment = strings.TrimSpace(fragment)
+	if strings.HasPrefix(fragment, "^") {
+		link.Block = fragment[1:]
+	} else {
+		link.Heading = fragment
+	}
+}
+
+// LinkResolver resolves link targets to notes of a vault the way Obsidian does:
+// a path next to the linking note or from the vault root matches exactly,
+// otherwise the note with that name and the shortest path wins.
+type LinkResolver struct {
+	paths map[string]string   // paths by their lower-cased form without extension
+	names map[string][]string // paths by their lower-cased base name without extension
+}
+
+// NewLinkResolver creates a resolver for the notes at the given paths, relative
+// to the vault and separated by slashes
+func NewLinkResolver(notes []string) *LinkResolver {
+	r := &LinkResolver{
+		paths: make(map[string]string, len(notes)),
+		names: make(map[string][]string, len(notes)),
+	}
+
+	for _, note := range notes {
+		key := linkKey(note)
+		r.paths[key] = note
+		name := path.Base(key)
+		r.names[name] = append(r.names[name], note)
+	}
+
+	// Shortest paths first, so the first candidate of a name is the one Obsidian picks
+	for _, candidates := range r.names {
+		sort.Slice(candidates, func(i, j int) bool {
+			return shorterPath(candidates[i], candidates[j])
+		})
+	}
+
+	return r
+}
+
+// Resolve returns the note a link from source points at, or "" if there is none.
+// Links without a target, like [[#Heading]], point at source itself.
+func (r *LinkResolver) Resolve(link Link, source string) string {
+	if link.Target == "" {
+		return source
+	}
+
+	target := strings.TrimPrefix(link.Target, "/")
+	sourceDir := path.Dir(source)
+
+	// Targets are looked up next to the linking note first, unless they start at the vault root
+	if !strings.HasPrefix(link.Target, "/") {
+		if note, ok := r.paths[linkKey(path.Join(sourceDir, target))]; ok {
+			return note
+		}
+	}
+
+	key := linkKey(path.Clean(target))
+	if note, ok := r.paths[key]; ok {
+		return note
+	}
+
+	// A partial path or a bare name matches notes ending in it, the shortest wins
+	for _, candidate := range r.names[path.Base(key)] {
+		if strings.HasSuffix(linkKey(candidate), "/"+key) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// ResolveLinks sets the resolved note of each link of a document linked from source
+func (r *LinkResolver) ResolveLinks(links []Link, source string) {
+	for i := range links {
+		links[i].Resolved = r.Resolve(links[i], source)
+	}
+}
+
+// ResolvedTargets returns the distinct notes the links point at, in link order
+func ResolvedTargets(links []Link) []string {
+	targets := []string{}
+	seen := make(map[string]bool)
+	for _, link := range links {
+		if link.Resolved != "" && !seen[link.Resolved] {
+			seen[link.Resolved] = true
+			targets = append(targets, link.Resolved)
+		}
+	}
+	return targets
+}
+
+// linkKey normalizes a note path for matching: lower case without the .md extension
+func linkKey(note string) string {
+	key := strings.ToLower(note)
+	return strings.TrimSuffix(key, ".md")
+}
+
+// shorterPath orders paths by depth, then length, then alphabetically
+func shorterPath(a, b string) bool {
+	depthA, depthB := strings.Count(a, "/"), strings.Count(b, "/")
+	if depthA != depthB {
+		return depthA < depthB
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
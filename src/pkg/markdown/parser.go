@@ -1,7 +1,6 @@
 package markdown
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -17,6 +16,12 @@ type Document struct {
 	Frontmatter map[string]interface{}
 	Sections    []Section
 	Tags        []string
+	Aliases     []string // Alternative names from the aliases property
+	Links       []Link   // Outgoing wikilinks, embeds and relative markdown links
+	Blocks      []Block  // Blocks of the content without frontmatter
+
+	// FrontmatterError is set when the frontmatter isn't valid YAML, Frontmatter is empty then
+	FrontmatterError error
 }
 
 // Section represents a section in a markdown document
@@ -44,12 +49,21 @@ type Chunk struct {
 	EndLine     int
 	StartOffset int
 	EndOffset   int
+
+	// Set for notes with inlined embeds, see Transclusion.LocateEmbeds
+	Embeds []Embed // embedded text the chunk contains
+	Origin *Embed  // note all of the chunk's text is embedded from
+
+	// Set for code blocks chunked on their own, see ChunkCodeBlocks
+	Type     BlockType // BlockCode, or empty for text
+	Language string    // language of the code block
 }
 
 // ParseOptions contains options for parsing markdown
 type ParseOptions struct {
 	ExtractTags        bool
 	ExtractFrontmatter bool
+	ExtractLinks       bool
 	IncludeTitle       bool
 }
 
@@ -58,6 +72,7 @@ func DefaultParseOptions() ParseOptions {
 	return ParseOptions{
 		ExtractTags:        true,
 		ExtractFrontmatter: true,
+		ExtractLinks:       true,
 		IncludeTitle:       true,
 	}
 }
@@ -80,46 +95,43 @@ func (p *Parser) Parse(content string) (*Document, error) {
 		Content:  content,
 		Sections: []Section{},
 		Tags:     []string{},
+		Aliases:  []string{},
+		Links:    []Link{},
 	}
 
 	// Extract frontmatter if enabled
 	if p.options.ExtractFrontmatter {
 		frontmatter, contentWithoutFrontmatter, err := extractFrontmatter([]byte(content))
-		if err != nil {
-			return nil, fmt.Errorf("failed to extract frontmatter: %w", err)
-		}
+		content = string(contentWithoutFrontmatter)
+
+		// Broken YAML shouldn't keep the note out of the index, it is parsed without properties
+		doc.FrontmatterError = err
 
 		if frontmatter != nil {
 			doc.Frontmatter = frontmatter
-			content = string(contentWithoutFrontmatter)
 
 			// Extract title from frontmatter if available
-			if title, ok := frontmatter["title"]; ok {
-				if titleStr, ok := title.(string); ok {
-					doc.Title = titleStr
-				}
+			if title, ok := frontmatter["title"].(string); ok {
+				doc.Title = title
 			}
 
+			// Alternative names of the note, "alias" is the older spelling
+			doc.Aliases = append(frontmatterStrings(frontmatter["aliases"]), frontmatterStrings(frontmatter["alias"])...)
+
 			// Extract tags from frontmatter if enabled
 			if p.options.ExtractTags {
-				if tags, ok := frontmatter["tags"]; ok {
-					switch t := tags.(type) {
-					case []interface{}:
-						for _, tag := range t {
-							if tagStr, ok := tag.(string); ok {
-								doc.Tags = append(doc.Tags, tagStr)
-							}
-						}
-					case string:
-						doc.Tags = append(doc.Tags, t)
+				for _, tag := range frontmatterStrings(frontmatter["tags"]) {
+					if tag = strings.TrimPrefix(tag, "#"); tag != "" && !contains(doc.Tags, tag) {
+						doc.Tags = append(doc.Tags, tag)
 					}
 				}
 			}
 		}
 	}
 
-	// Parse sections
-	doc.Sections = parseSections([]byte(content))
+	// Parse blocks and the sections their headings start, headings in code don't count
+	doc.Blocks = ParseBlocks(content)
+	doc.Sections = parseSections([]byte(content), doc.Blocks)
 
 	// Extract title from first heading if not found in frontmatter
 	if doc.Title == "" && len(doc.Sections) > 0 && p.options.IncludeTitle {
@@ -128,7 +140,7 @@ func (p *Parser) Parse(content string) (*Document, error) {
 
 	// Extract inline tags if enabled
 	if p.options.ExtractTags {
-		inlineTags := extractInlineTags([]byte(content))
+		inlineTags := extractInlineTags([]byte(withoutCode(content, doc.Blocks)))
 		for _, tag := range inlineTags {
 			if !contains(doc.Tags, tag) {
 				doc.Tags = append(doc.Tags, tag)
@@ -136,6 +148,12 @@ func (p *Parser) Parse(content string) (*Document, error) {
 		}
 	}
 
+	// Extract links if enabled, numbering lines from the top of the note
+	if p.options.ExtractLinks {
+		firstLine := strings.Count(doc.Content[:len(doc.Content)-len(content)], "\n") + 1
+		doc.Links = extractLinks(content, firstLine)
+	}
+
 	return doc, nil
 }
 
@@ -174,7 +192,7 @@ func (p *Parser) ChunkByHeaders(doc *Document) []*Chunk {
 		chunk := &Chunk{
 			ID:          fmt.Sprintf("%s:%d", doc.Path, i),
 			Content:     section.Content,
-			ContentOnly: section.Content, // Should filter out code blocks and other non-textual content
+			ContentOnly: plainText(section.Content),
 			Title:       doc.Title,
 			Section:     section.Title,
 			Tags:        doc.Tags,
@@ -198,8 +216,8 @@ func (p *Parser) ChunkBySlidingWindow(doc *Document, windowSize, overlap int) []
 	// Get full text content
 	text := doc.Content
 
-	// Split into paragraphs
-	paragraphs := strings.Split(text, "\n\n")
+	// Split into paragraphs, keeping blocks like code and tables whole
+	paragraphs := splitParagraphs(text)
 
 	// Apply sliding window chunking
 	var currentChunk strings.Builder
@@ -220,7 +238,7 @@ func (p *Parser) ChunkBySlidingWindow(doc *Document, windowSize, overlap int) []
 			chunk := &Chunk{
 				ID:          fmt.Sprintf("%s:chunk_%d", doc.Path, chunkIndex),
 				Content:     currentChunk.String(),
-				ContentOnly: currentChunk.String(), // Should filter out code blocks and other non-textual content
+				ContentOnly: plainText(currentChunk.String()),
 				Title:       doc.Title,
 				Tags:        doc.Tags,
 				Path:        doc.Path,
@@ -247,7 +265,7 @@ func (p *Parser) ChunkBySlidingWindow(doc *Document, windowSize, overlap int) []
 			chunk := &Chunk{
 				ID:          fmt.Sprintf("%s:chunk_%d", doc.Path, chunkIndex),
 				Content:     currentChunk.String(),
-				ContentOnly: currentChunk.String(), // Should filter out code blocks and other non-textual content
+				ContentOnly: plainText(currentChunk.String()),
 				Title:       doc.Title,
 				Tags:        doc.Tags,
 				Path:        doc.Path,
@@ -260,6 +278,40 @@ func (p *Parser) ChunkBySlidingWindow(doc *Document, windowSize, overlap int) []
 	return chunks
 }
 
+// ChunkCodeBlocks makes a chunk of every code block of a document, with the
+// code as ContentOnly and its language, so code can be found on its own
+func (p *Parser) ChunkCodeBlocks(doc *Document) []*Chunk {
+	chunks := []*Chunk{}
+
+	section := ""
+	for _, block := range doc.Blocks {
+		if block.Type == BlockHeading {
+			section = block.Title
+		}
+		if block.Type != BlockCode || strings.TrimSpace(block.Code) == "" {
+			continue
+		}
+
+		chunks = append(chunks, &Chunk{
+			ID:          fmt.Sprintf("%s:code_%d", doc.Path, len(chunks)),
+			Content:     block.Text,
+			ContentOnly: block.Code,
+			Title:       doc.Title,
+			Section:     section,
+			Tags:        doc.Tags,
+			Path:        doc.Path,
+			StartLine:   block.StartLine,
+			EndLine:     block.EndLine,
+			StartOffset: block.StartOffset,
+			EndOffset:   block.EndOffset,
+			Type:        BlockCode,
+			Language:    block.Language,
+		})
+	}
+
+	return chunks
+}
+
 // ChunkHybrid uses a hybrid approach combining headers and sliding windows
 func (p *Parser) ChunkHybrid(doc *Document, windowSize, overlap int) []*Chunk {
 	// First try header-based chunking
@@ -298,97 +350,138 @@ func (p *Parser) ChunkHybrid(doc *Document, windowSize, overlap int) []*Chunk {
 	return finalChunks
 }
 
-// extractFrontmatter extracts YAML frontmatter from markdown content
-func extractFrontmatter(content []byte) (map[string]interface{}, []byte, error) {
-	frontmatterRegex := regexp.MustCompile(`(?s)^---\s*\n(.*?)\n---\s*\n(.*)$`)
-	matches := frontmatterRegex.FindSubmatch(content)
-
-	if len(matches) != 3 {
-		// No frontmatter found
-		return nil, content, nil
-	}
-
-	frontmatterYAML := matches[1]
-	remainingContent := matches[2]
-
-	// Placeholder for frontmatter parsing
-	// In a real implementation, we would use a YAML library to parse the frontmatter
-	// For simplicity, we'll just create a dummy map
-	frontmatter := make(map[string]interface{})
-
-	// Parse simple key-value pairs
-	scanner := bufio.NewScanner(bytes.NewReader(frontmatterYAML))
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			// Handle tags specially
-			if key == "tags" {
-				if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
-					// Array format
-					tagsStr := value[1 : len(value)-1]
-					tags := []interface{}{}
-					for _, tag := range strings.Split(tagsStr, ",") {
-						tags = append(tags, strings.Trim(strings.TrimSpace(tag), "\"'"))
-					}
-					frontmatter[key] = tags
-				} else {
-					// Single tag
-					frontmatter[key] = value
-				}
-			} else {
-				frontmatter[key] = value
+// LocateChunks sets the line range of each chunk to the lines of the note that
+// hold its first and last non-blank line. Chunkers work on the note without its
+// frontmatter and sliding windows don't track lines at all, so this resolves
+// the lines against the original file content.
+func LocateChunks(content string, chunks []*Chunk) {
+	// Chunks come in document order and usually start after the previous one
+	// ends, which tells repeated lines like code fences apart, but may overlap it
+	previousStart, previousEnd := 0, 0
+	for _, chunk := range chunks {
+		lines := nonBlankLines(chunk.Content)
+		if len(lines) == 0 {
+			continue
+		}
+
+		start := findLine(content, previousEnd, lines[0])
+		if start < 0 || followLines(content, start, lines) < 0 {
+			start = findLine(content, previousStart, lines[0])
+		}
+		if start < 0 {
+			if start = findLine(content, 0, lines[0]); start < 0 {
+				continue
 			}
 		}
+
+		// Following every line finds the right last line even if it repeats
+		// inside the chunk, like the fences of code blocks
+		end := followLines(content, start, lines)
+		if end < 0 {
+			end = start
+		}
+
+		chunk.StartLine = strings.Count(content[:start], "\n") + 1
+		chunk.EndLine = strings.Count(content[:end], "\n") + 1
+		previousStart, previousEnd = start+len(lines[0]), end+len(lines[len(lines)-1])
 	}
+}
 
-	return frontmatter, remainingContent, nil
+// nonBlankLines returns the non-blank lines of text, trimmed
+func nonBlankLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
 }
 
-// parseSections parses markdown content into sections
-func parseSections(content []byte) []Section {
-	var sections []Section
+// findLine returns the offset of the first line from offset from that reads
+// line once trimmed, or of the first occurrence of line if no line matches it
+// as a whole. It returns -1 if line doesn't occur.
+func findLine(content string, from int, line string) int {
+	first := -1
+	for offset := from; offset < len(content); {
+		i := strings.Index(content[offset:], line)
+		if i < 0 {
+			break
+		}
+		i += offset
+		if first < 0 {
+			first = i
+		}
 
-	// Define regex for headers
-	headerRegex := regexp.MustCompile(`(?m)^(#{1,6})\s+(.+)$`)
+		lineStart := strings.LastIndexByte(content[:i], '\n') + 1
+		lineEnd := len(content)
+		if j := strings.IndexByte(content[i:], '\n'); j >= 0 {
+			lineEnd = i + j
+		}
+		if strings.TrimSpace(content[lineStart:lineEnd]) == line {
+			return i
+		}
+		offset = i + 1
+	}
+	return first
+}
 
-	// Find all headers
-	matches := headerRegex.FindAllSubmatchIndex(content, -1)
+// followLines matches lines one after the other against the lines of content
+// from offset start and returns the offset of the line matching the last one,
+// or -1 if they don't all match
+func followLines(content string, start int, lines []string) int {
+	offset, end := start, -1
+	for _, line := range lines {
+		for {
+			if offset > len(content) {
+				return -1
+			}
+			lineStart, lineEnd := offset, len(content)
+			if i := strings.IndexByte(content[offset:], '\n'); i >= 0 {
+				lineEnd = offset + i
+			}
+			offset = lineEnd + 1
+			if strings.TrimSpace(content[lineStart:lineEnd]) == line {
+				end = lineStart
+				break
+			}
+		}
+	}
+	return end
+}
 
-	// Process headers and their content
-	for i, match := range matches {
-		headerStart := match[0]
-		headerEnd := match[1]
-		levelStart := match[2]
-		levelEnd := match[3]
-		titleStart := match[4]
-		titleEnd := match[5]
+// parseSections parses markdown content into sections, one for each heading block
+func parseSections(content []byte, blocks []Block) []Section {
+	var sections []Section
 
-		level := levelEnd - levelStart
-		title := string(content[titleStart:titleEnd])
+	// Find all headers
+	var headings []Block
+	for _, block := range blocks {
+		if block.Type == BlockHeading {
+			headings = append(headings, block)
+		}
+	}
 
+	// Process headers and their content
+	for i, heading := range headings {
 		// Determine content boundaries
-		contentStart := headerEnd
+		contentStart := heading.EndOffset
 		contentEnd := len(content)
-		if i < len(matches)-1 {
-			contentEnd = matches[i+1][0]
+		if i < len(headings)-1 {
+			contentEnd = headings[i+1].StartOffset
 		}
 
 		// Calculate line numbers
-		startLine := bytes.Count(content[:headerStart], []byte{'\n'}) + 1
 		endLine := bytes.Count(content[:contentEnd], []byte{'\n'}) + 1
 
 		// Create section
 		section := Section{
-			Title:       title,
-			Level:       level,
+			Title:       heading.Title,
+			Level:       heading.Level,
 			Content:     string(content[contentStart:contentEnd]),
-			StartLine:   startLine,
+			StartLine:   heading.StartLine,
 			EndLine:     endLine,
-			StartOffset: headerStart,
+			StartOffset: heading.StartOffset,
 			EndOffset:   contentEnd,
 		}
 
@@ -411,6 +504,30 @@ func parseSections(content []byte) []Section {
 	return sections
 }
 
+// withoutCode returns content with its code blocks, comments and code spans
+// blanked out, so tags are only found in text
+func withoutCode(content string, blocks []Block) string {
+	text := []byte(content)
+	for _, block := range blocks {
+		if block.Type == BlockCode || block.Type == BlockComment {
+			blank(text[block.StartOffset:block.EndOffset])
+		}
+	}
+	for _, span := range inlineCodeRegex.FindAllIndex(text, -1) {
+		blank(text[span[0]:span[1]])
+	}
+	return string(text)
+}
+
+// blank replaces everything but line breaks with spaces
+func blank(text []byte) {
+	for i, c := range text {
+		if c != '\n' {
+			text[i] = ' '
+		}
+	}
+}
+
 // extractInlineTags extracts tags in the format #tag from markdown
 func extractInlineTags(content []byte) []string {
 	tags := []string{}
@@ -556,6 +673,7 @@ func headerBasedChunking(doc *Document, options ChunkOptions) ([]Chunk, error) {
 		chunk := Chunk{
 			ID:          chunkID,
 			Content:     section.Content,
+			ContentOnly: plainText(section.Content),
 			Title:       doc.Title,
 			Section:     section.Title,
 			SectionPath: sectionTitle,
@@ -580,8 +698,8 @@ func slidingWindowChunking(doc *Document, options ChunkOptions) ([]Chunk, error)
 	// Get full text content
 	text := doc.Content
 
-	// Split into paragraphs
-	paragraphs := strings.Split(text, "\n\n")
+	// Split into paragraphs, keeping blocks like code and tables whole
+	paragraphs := splitParagraphs(text)
 
 	// Apply sliding window chunking
 	var currentChunk strings.Builder
@@ -600,11 +718,12 @@ func slidingWindowChunking(doc *Document, options ChunkOptions) ([]Chunk, error)
 		if currentSize+paragraphSize > options.MaxChunkSize && currentSize >= options.MinChunkSize {
 			// Create chunk
 			chunk := Chunk{
-				ID:      fmt.Sprintf("%s:chunk_%d", doc.Path, chunkIndex),
-				Content: currentChunk.String(),
-				Title:   doc.Title,
-				Tags:    doc.Tags,
-				Path:    doc.Path,
+				ID:          fmt.Sprintf("%s:chunk_%d", doc.Path, chunkIndex),
+				Content:     currentChunk.String(),
+				ContentOnly: plainText(currentChunk.String()),
+				Title:       doc.Title,
+				Tags:        doc.Tags,
+				Path:        doc.Path,
 				// Line numbers and offsets would require more precise tracking
 			}
 
@@ -628,11 +747,12 @@ func slidingWindowChunking(doc *Document, options ChunkOptions) ([]Chunk, error)
 		// If this is the last paragraph, add the remaining content as a chunk
 		if i == len(paragraphs)-1 && currentSize > 0 {
 			chunk := Chunk{
-				ID:      fmt.Sprintf("%s:chunk_%d", doc.Path, chunkIndex),
-				Content: currentChunk.String(),
-				Title:   doc.Title,
-				Tags:    doc.Tags,
-				Path:    doc.Path,
+				ID:          fmt.Sprintf("%s:chunk_%d", doc.Path, chunkIndex),
+				Content:     currentChunk.String(),
+				ContentOnly: plainText(currentChunk.String()),
+				Title:       doc.Title,
+				Tags:        doc.Tags,
+				Path:        doc.Path,
 				// Line numbers and offsets would require more precise tracking
 			}
 
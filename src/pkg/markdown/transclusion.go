@@ -0,0 +1,331 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// embedHeadingRegex matches an ATX heading with optional closing hashes
+	embedHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	// blockIDRegex matches a block ID at the end of a line, like "text ^id"
+	blockIDRegex = regexp.MustCompile(`(?:^|\s)\^([A-Za-z0-9-]+)\s*$`)
+	// listItemRegex matches the start of a list item or task
+	listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s`)
+)
+
+// Embed is the text of a note inlined in place of an embed like ![[Note#Heading]]
+type Embed struct {
+	Path    string // embedded note, relative to the vault
+	Heading string // heading of ![[Note#Heading]], the whole note if empty
+	Block   string // block ID of ![[Note#^id]]
+	Depth   int    // 1 for embeds of the note itself, 2 for embeds inside those, and so on
+
+	// StartLine and EndLine are the lines of the inlined text in the embedded note
+	StartLine int
+	EndLine   int
+
+	// first and last are the lines of the transcluded content holding the text
+	first, last int
+}
+
+// Transcluder inlines the notes, headings and blocks embedded with ![[...]]
+type Transcluder struct {
+	resolve  func(link Link, source string) string
+	load     func(note string) (string, error)
+	maxDepth int
+}
+
+// NewTranscluder creates a transcluder. resolve returns the note a link from
+// source points at, or "" if there is none, and load reads a note. Both take
+// paths relative to the vault. Embeds nested deeper than maxDepth stay as they are.
+func NewTranscluder(resolve func(link Link, source string) string, load func(note string) (string, error), maxDepth int) *Transcluder {
+	return &Transcluder{
+		resolve:  resolve,
+		load:     load,
+		maxDepth: maxDepth,
+	}
+}
+
+// Transclusion is the content of a note with its embeds inlined
+type Transclusion struct {
+	Content string
+	Embeds  []Embed // inlined text in document order, nested embeds after the one containing them
+
+	source string
+	lines  []lineOrigin // by line of Content, 0-based
+}
+
+// lineOrigin records where a line of transcluded content comes from
+type lineOrigin struct {
+	line     int    // line of the note itself the text is at, the embed's line for inlined text
+	note     string // note the line comes from
+	noteLine int    // line in that note
+}
+
+// Transclude inlines the embeds of content, the text of the note at source.
+// Embeds that don't resolve, can't be read or would embed a note that is being
+// inlined already are kept as written. Embeds in code are left alone.
+func (t *Transcluder) Transclude(content, source string) *Transclusion {
+	tr := &Transclusion{source: source}
+
+	var out []string
+	t.expand(tr, &out, content, source, 1, 0, 0, []string{source})
+	tr.Content = strings.Join(out, "\n")
+
+	return tr
+}
+
+// expand appends the lines of text to out with its embeds inlined. text comes
+// from note, starting at firstLine, and is inlined at line hostLine of the
+// transcluded note unless it is that note's own text (depth 0).
+func (t *Transcluder) expand(tr *Transclusion, out *[]string, text, note string, firstLine, hostLine, depth int, stack []string) {
+	emit := func(line string, noteLine int) {
+		at := hostLine
+		if depth == 0 {
+			at = noteLine
+		}
+		*out = append(*out, line)
+		tr.lines = append(tr.lines, lineOrigin{line: at, note: note, noteLine: noteLine})
+	}
+
+	inFence := false
+	for i, line := range strings.Split(text, "\n") {
+		lineNumber := firstLine + i
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if inFence || !strings.Contains(line, "![[") {
+			emit(line, lineNumber)
+			continue
+		}
+
+		// Code spans are blanked out so embeds inside them aren't found
+		masked := inlineCodeRegex.ReplaceAllStringFunc(line, func(code string) string {
+			return strings.Repeat(" ", len(code))
+		})
+
+		// Text around inlined embeds goes on lines of its own
+		last := 0
+		inlined := false
+		for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(masked, -1) {
+			if masked[match[2]:match[3]] != "!" {
+				continue
+			}
+
+			link, ok := parseWikiLink(line[match[4]:match[5]])
+			if !ok || depth >= t.maxDepth {
+				continue
+			}
+			link.Embed = true
+
+			target := t.resolve(link, note)
+			if target == "" || contains(stack, target) {
+				continue
+			}
+
+			content, err := t.load(target)
+			if err != nil {
+				continue
+			}
+
+			embedded, startLine, ok := selectEmbed(content, link)
+			if !ok {
+				continue
+			}
+
+			if before := line[last:match[0]]; strings.TrimSpace(before) != "" {
+				emit(before, lineNumber)
+				emit("", lineNumber)
+			}
+
+			// Reserved first so embeds stay in document order
+			index := len(tr.Embeds)
+			tr.Embeds = append(tr.Embeds, Embed{
+				Path:      target,
+				Heading:   link.Heading,
+				Block:     link.Block,
+				Depth:     depth + 1,
+				StartLine: startLine,
+				EndLine:   startLine + strings.Count(embedded, "\n"),
+				first:     len(*out) + 1,
+			})
+
+			at := hostLine
+			if depth == 0 {
+				at = lineNumber
+			}
+			t.expand(tr, out, embedded, target, startLine, at, depth+1, append(stack, target))
+			tr.Embeds[index].last = len(*out)
+
+			last = match[1]
+			inlined = true
+		}
+
+		if !inlined {
+			emit(line, lineNumber)
+			continue
+		}
+		if after := line[last:]; strings.TrimSpace(after) != "" {
+			emit("", lineNumber)
+			emit(after, lineNumber)
+		}
+	}
+}
+
+// selectEmbed returns the part of a note an embed shows and the line it starts
+// on: the note without its frontmatter, the section under a heading or a block
+func selectEmbed(content string, link Link) (string, int, bool) {
+	_, rest, _ := splitFrontmatter([]byte(content))
+	body := string(rest)
+	firstLine := strings.Count(content[:len(content)-len(body)], "\n") + 1
+	lines := strings.Split(body, "\n")
+
+	start, end := 0, len(lines)
+	switch {
+	case link.Block != "":
+		var ok bool
+		if start, end, ok = findBlock(lines, link.Block); !ok {
+			return "", 0, false
+		}
+	case link.Heading != "":
+		var ok bool
+		if start, end, ok = findHeading(lines, link.Heading); !ok {
+			return "", 0, false
+		}
+	}
+
+	selected := make([]string, end-start)
+	copy(selected, lines[start:end])
+	if link.Block != "" {
+		// The block ID is an anchor, not text
+		for i, line := range selected {
+			if loc := blockIDRegex.FindStringSubmatchIndex(line); loc != nil && line[loc[2]:loc[3]] == link.Block {
+				selected[i] = strings.TrimRight(line[:loc[0]], " \t")
+			}
+		}
+	}
+
+	return strings.Join(selected, "\n"), firstLine + start, true
+}
+
+// findHeading returns the lines of the section under a heading, up to the next
+// heading of the same or a higher level. Nested headings like [[Note#A#B]] match
+// by their last part, headings compare without regard to case.
+func findHeading(lines []string, heading string) (int, int, bool) {
+	if i := strings.LastIndex(heading, "#"); i >= 0 {
+		heading = heading[i+1:]
+	}
+	heading = strings.TrimSpace(heading)
+
+	start, level := -1, 0
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		match := embedHeadingRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if start >= 0 && len(match[1]) <= level {
+			return start, i, true
+		}
+		if start < 0 && strings.EqualFold(strings.TrimSpace(match[2]), heading) {
+			start, level = i, len(match[1])
+		}
+	}
+
+	if start < 0 {
+		return 0, 0, false
+	}
+	return start, len(lines), true
+}
+
+// findBlock returns the lines of the block with an ID: the list item or
+// paragraph the ID ends, or the block before an ID on a line of its own
+func findBlock(lines []string, id string) (int, int, bool) {
+	for i, line := range lines {
+		match := blockIDRegex.FindStringSubmatch(line)
+		if match == nil || match[1] != id {
+			continue
+		}
+
+		end := i + 1
+		if strings.TrimSpace(line) == "^"+id {
+			// The ID follows the block it names, like a table or a quote
+			end = i
+			for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+				end--
+			}
+		} else if listItemRegex.MatchString(line) {
+			return i, end, true
+		}
+
+		start := end - 1
+		for start > 0 && strings.TrimSpace(lines[start-1]) != "" && !listItemRegex.MatchString(lines[start]) {
+			start--
+		}
+		if start < 0 {
+			return 0, 0, false
+		}
+		return start, end, true
+	}
+
+	return 0, 0, false
+}
+
+// LocateEmbeds maps the chunks of transcluded content back to the note. Chunks
+// must be located in Content first, see LocateChunks. Their lines become those
+// of the note, holding the embed for inlined text, they get the embeds whose
+// text they contain and chunks made of text from a single embedded note get
+// that note as their origin.
+func (t *Transclusion) LocateEmbeds(chunks []*Chunk) {
+	for _, chunk := range chunks {
+		first, last := chunk.StartLine-1, chunk.EndLine-1
+		if first < 0 || last >= len(t.lines) || first > last {
+			continue
+		}
+
+		for _, embed := range t.Embeds {
+			if embed.first <= chunk.EndLine && embed.last >= chunk.StartLine {
+				chunk.Embeds = append(chunk.Embeds, embed)
+			}
+		}
+
+		// Lines of another note only, so the chunk belongs to that note
+		note := t.lines[first].note
+		single := note != t.source
+		for _, origin := range t.lines[first : last+1] {
+			if origin.note != note {
+				single = false
+				break
+			}
+		}
+		if single {
+			chunk.Origin = &Embed{
+				Path:      note,
+				StartLine: t.lines[first].noteLine,
+				EndLine:   t.lines[last].noteLine,
+			}
+			for _, embed := range chunk.Embeds {
+				if embed.Path == note {
+					chunk.Origin.Heading = embed.Heading
+					chunk.Origin.Block = embed.Block
+					chunk.Origin.Depth = embed.Depth
+				}
+			}
+		}
+
+		chunk.StartLine = t.lines[first].line
+		chunk.EndLine = t.lines[last].line
+	}
+}
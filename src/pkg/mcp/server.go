@@ -0,0 +1,209 @@
+// Package mcp serves the vault search to local agents as a Model Context Protocol
+// server, speaking JSON-RPC 2.0 over newline-delimited stdio. The tools are backed
+// by an api.Client, so the daemon has to be running.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	api2 "obsfind/src/pkg/api"
+	"obsfind/src/pkg/loggingutil"
+	"sync"
+)
+
+// protocolVersions lists the supported protocol versions, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// request is a JSON-RPC request, or a notification if it has no ID
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response carrying either a result or an error
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server answers MCP requests with the tools in tools.go
+type Server struct {
+	client  *api2.Client
+	version string
+	tools   []tool
+
+	writeMutex sync.Mutex
+}
+
+// NewServer creates an MCP server backed by the daemon behind client
+func NewServer(client *api2.Client, version string) *Server {
+	s := &Server{
+		client:  client,
+		version: version,
+	}
+	s.tools = s.defineTools()
+	return s
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// exhausted or ctx is done. Requests are handled one at a time.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	logger := loggingutil.Get(ctx)
+	logger.Info("MCP server listening on stdio")
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			logger.Info("MCP client disconnected")
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			if resp := s.handle(ctx, line); resp != nil {
+				if err := s.write(out, resp); err != nil {
+					return fmt.Errorf("failed to write response: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// write sends a single response as one line
+func (s *Server) write(out io.Writer, resp *response) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
+
+// handle processes one message and returns its response, nil for notifications
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	logger := loggingutil.Get(ctx)
+
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "invalid JSON: "+err.Error())
+	}
+
+	// Notifications such as notifications/initialized need no answer
+	if len(req.ID) == 0 {
+		logger.Debug("MCP notification", "method", req.Method)
+		return nil
+	}
+
+	if req.JSONRPC != "2.0" {
+		return errorResponse(req.ID, codeInvalidRequest, "expected JSON-RPC 2.0")
+	}
+
+	logger.Debug("MCP request", "method", req.Method)
+
+	switch req.Method {
+	case "initialize":
+		return resultResponse(req.ID, s.initialize(req.Params))
+	case "ping":
+		return resultResponse(req.ID, struct{}{})
+	case "tools/list":
+		return resultResponse(req.ID, map[string]interface{}{"tools": s.tools})
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, codeInvalidParams, "invalid tools/call parameters: "+err.Error())
+		}
+		for _, t := range s.tools {
+			if t.Name == params.Name {
+				return resultResponse(req.ID, s.callTool(ctx, t, params.Arguments))
+			}
+		}
+		return errorResponse(req.ID, codeInvalidParams, fmt.Sprintf("unknown tool %q", params.Name))
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, fmt.Sprintf("method %q not found", req.Method))
+	}
+}
+
+// initialize agrees on the protocol version and describes the server
+func (s *Server) initialize(params json.RawMessage) interface{} {
+	var request struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(params, &request)
+
+	// Answer with the client's version if supported, otherwise with our newest
+	version := protocolVersions[0]
+	for _, supported := range protocolVersions {
+		if supported == request.ProtocolVersion {
+			version = supported
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]string{
+			"name":    "obsfind",
+			"version": s.version,
+		},
+		"instructions": "Search the user's Obsidian vault semantically with search_notes, " +
+			"find related notes with find_similar, read notes or line ranges with read_note " +
+			"and list the vault's tags with list_tags. Prefer these tools over reading vault files directly.",
+	}
+}
+
+// resultResponse wraps a result
+func resultResponse(id json.RawMessage, result interface{}) *response {
+	return &response{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+// errorResponse wraps an error
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
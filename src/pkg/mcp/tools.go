@@ -0,0 +1,290 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	api2 "obsfind/src/pkg/api"
+	"obsfind/src/pkg/indexer"
+	"strings"
+)
+
+// tool is an MCP tool with the handler that runs it
+type tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	run func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// toolResult is the result of a tool call, errors of the tool itself are
+// reported here so the agent can see them
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// textContent is a text block of a tool result
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// defineTools returns the tools of the server
+func (s *Server) defineTools() []tool {
+	return []tool{
+		{
+			Name: "search_notes",
+			Description: "Search the vault's notes by meaning and keywords. Returns the best matching notes " +
+				"with their matching sections, line numbers and excerpts. The query may contain filters such as " +
+				"tag:work, path:projects/, title:weekly, fm.status:active or created:>2024-01-01.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"query":       stringProperty("What to search for, optionally with filters"),
+				"limit":       integerProperty("Maximum number of notes, 10 by default"),
+				"tags":        stringArrayProperty("Only notes with any of these tags"),
+				"path_prefix": stringProperty("Only notes inside this folder"),
+				"mode":        enumProperty("Ranking: hybrid (default), semantic or keyword", "hybrid", "semantic", "keyword"),
+				"where":       stringArrayProperty("Frontmatter conditions like status=active or priority>=2"),
+			}, "query"),
+			run: s.searchNotes,
+		},
+		{
+			Name:        "find_similar",
+			Description: "Find notes similar to a note of the vault, given its vault-relative path.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"path":     stringProperty("Vault-relative path of the note, e.g. projects/roadmap.md"),
+				"limit":    integerProperty("Maximum number of notes, 10 by default"),
+				"strategy": enumProperty("How the note is compared: max (default), centroid or section", "max", "centroid", "section"),
+			}, "path"),
+			run: s.findSimilar,
+		},
+		{
+			Name:        "read_note",
+			Description: "Read a note of the vault, or a range of its lines, given its vault-relative path.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"path":       stringProperty("Vault-relative path of the note, e.g. projects/roadmap.md"),
+				"start_line": integerProperty("First line to read, 1-based"),
+				"end_line":   integerProperty("Last line to read, inclusive"),
+			}, "path"),
+			run: s.readNote,
+		},
+		{
+			Name:        "list_tags",
+			Description: "List the tags used in the vault with the number of notes carrying each, most used first.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"limit": integerProperty("Maximum number of tags, all by default"),
+			}),
+			run: s.listTags,
+		},
+	}
+}
+
+// callTool runs a tool and wraps its output or error
+func (s *Server) callTool(ctx context.Context, t tool, arguments json.RawMessage) toolResult {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+
+	text, err := t.run(ctx, arguments)
+	if err != nil {
+		return toolResult{
+			Content: []textContent{{Type: "text", Text: fmt.Sprintf("%s failed: %v", t.Name, err)}},
+			IsError: true,
+		}
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}}
+}
+
+// searchNotes runs the search_notes tool
+func (s *Server) searchNotes(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Query      string   `json:"query"`
+		Limit      int      `json:"limit"`
+		Tags       []string `json:"tags"`
+		PathPrefix string   `json:"path_prefix"`
+		Mode       string   `json:"mode"`
+		Where      []string `json:"where"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	results, err := s.client.Search(ctx, &api2.SearchRequest{
+		Query:      args.Query,
+		Limit:      args.Limit,
+		Tags:       args.Tags,
+		PathPrefix: args.PathPrefix,
+		Mode:       args.Mode,
+		Where:      args.Where,
+		GroupBy:    string(indexer.GroupByDocument),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(results) == 0 {
+		return "No matching notes.", nil
+	}
+	return formatResults(results), nil
+}
+
+// findSimilar runs the find_similar tool
+func (s *Server) findSimilar(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path     string `json:"path"`
+		Limit    int    `json:"limit"`
+		Strategy string `json:"strategy"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	results, err := s.client.Similar(ctx, &api2.SimilarRequest{
+		Path:     args.Path,
+		Limit:    args.Limit,
+		Strategy: args.Strategy,
+		GroupBy:  string(indexer.GroupByDocument),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(results) == 0 {
+		return "No similar notes.", nil
+	}
+	return formatResults(results), nil
+}
+
+// readNote runs the read_note tool
+func (s *Server) readNote(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	note, err := s.client.ReadNote(ctx, args.Path, args.StartLine, args.EndLine)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s, lines %d-%d of %d:\n\n%s", note.Path, note.StartLine, note.EndLine, note.TotalLines, note.Content), nil
+}
+
+// listTags runs the list_tags tool
+func (s *Server) listTags(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Limit int `json:"limit"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	tags, err := s.client.ListTags(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tags) == 0 {
+		return "No tags.", nil
+	}
+	if args.Limit > 0 && len(tags) > args.Limit {
+		tags = tags[:args.Limit]
+	}
+
+	var b strings.Builder
+	for _, tag := range tags {
+		fmt.Fprintf(&b, "#%s (%d)\n", tag.Tag, tag.Count)
+	}
+	return b.String(), nil
+}
+
+// formatResults renders grouped search results as plain text for agents
+func formatResults(results []api2.SearchResult) string {
+	var b strings.Builder
+	for i, result := range results {
+		fmt.Fprintf(&b, "%d. %s", i+1, result.Path)
+		if result.Title != "" {
+			fmt.Fprintf(&b, " - %s", result.Title)
+		}
+		fmt.Fprintf(&b, " (score %.2f)\n", result.Score)
+		if len(result.Tags) > 0 {
+			fmt.Fprintf(&b, "   Tags: %s\n", strings.Join(result.Tags, ", "))
+		}
+
+		sections := result.Sections
+		if len(sections) == 0 {
+			sections = []api2.SearchResult{result}
+		}
+		for _, section := range sections {
+			name := section.Section
+			if name == "" {
+				name = "(top)"
+			}
+			fmt.Fprintf(&b, "   - %s%s: %s\n", name, lineRange(section), section.Excerpt)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// lineRange describes the lines of a result, e.g. " (lines 12-20)"
+func lineRange(result api2.SearchResult) string {
+	switch {
+	case result.StartLine > 0 && result.EndLine > result.StartLine:
+		return fmt.Sprintf(" (lines %d-%d)", result.StartLine, result.EndLine)
+	case result.StartLine > 0:
+		return fmt.Sprintf(" (line %d)", result.StartLine)
+	default:
+		return ""
+	}
+}
+
+// objectSchema returns a JSON schema for an object with the given properties
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// stringProperty returns the schema of a string argument
+func stringProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+// integerProperty returns the schema of an integer argument
+func integerProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": description}
+}
+
+// stringArrayProperty returns the schema of a string list argument
+func stringArrayProperty(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]string{"type": "string"},
+		"description": description,
+	}
+}
+
+// enumProperty returns the schema of a string argument with fixed values
+func enumProperty(description string, values ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values, "description": description}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+// ChatModel generates answers from a system prompt and a user message
+type ChatModel interface {
+	// Chat returns the complete answer, passing each piece to onToken as it is
+	// generated if onToken is set. An error from onToken stops the generation.
+	Chat(ctx context.Context, system, message string, onToken func(string) error) (string, error)
+
+	// Name returns the model name
+	Name() string
+}
+
+// OllamaChatConfig holds configuration for answering with an Ollama chat model
+type OllamaChatConfig struct {
+	ModelName   string
+	ServerURL   string
+	Temperature float64
+}
+
+// OllamaChatModel answers with a local Ollama chat model
+type OllamaChatModel struct {
+	client      *ollama.LLM
+	modelName   string
+	temperature float64
+}
+
+// NewOllamaChatModel creates a new Ollama-based chat model
+func NewOllamaChatModel(config OllamaChatConfig) (*OllamaChatModel, error) {
+	if config.ModelName == "" {
+		return nil, fmt.Errorf("no chat model configured")
+	}
+
+	client, err := ollama.New(
+		ollama.WithModel(config.ModelName),
+		ollama.WithServerURL(config.ServerURL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Ollama client: %w", err)
+	}
+
+	return &OllamaChatModel{
+		client:      client,
+		modelName:   config.ModelName,
+		temperature: config.Temperature,
+	}, nil
+}
+
+// Chat sends the system prompt and the message and streams the answer
+func (m *OllamaChatModel) Chat(ctx context.Context, system, message string, onToken func(string) error) (string, error) {
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, system),
+		llms.TextParts(llms.ChatMessageTypeHuman, message),
+	}
+
+	options := []llms.CallOption{llms.WithTemperature(m.temperature)}
+	if onToken != nil {
+		options = append(options, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			return onToken(string(chunk))
+		}))
+	}
+
+	response, err := m.client.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate answer with %s: %w", m.modelName, err)
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("%s returned no answer", m.modelName)
+	}
+	return response.Choices[0].Content, nil
+}
+
+// Name returns the model name
+func (m *OllamaChatModel) Name() string {
+	return m.modelName
+}
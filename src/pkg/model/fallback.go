@@ -101,6 +101,11 @@ func (e *HybridEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 		return embeddings, nil
 	}
 
+	// A canceled caller is no reason to try the fallbacks
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
 	// Log the error with the current embedder
 	log.Printf("Primary embedder %s failed batch operation: %v, trying fallbacks",
 		e.embedders[current].Name(), err)
@@ -147,6 +147,11 @@ func (e *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 
 		batch := texts[i:end]
 
+		// Stop between batches once the caller gave up
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("embedding canceled by parent context: %w", err)
+		}
+
 		var embeddings [][]float32
 		var err error
 
@@ -178,7 +183,11 @@ func (e *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 			if attempt < e.maxAttempts-1 {
 				backoffTime := time.Duration(500*(1<<attempt)) * time.Millisecond
 				fmt.Printf("Retrying in %v...\n", backoffTime)
-				time.Sleep(backoffTime)
+				select {
+				case <-time.After(backoffTime):
+				case <-ctx.Done():
+					return nil, fmt.Errorf("embedding canceled by parent context: %w", ctx.Err())
+				}
 			}
 		}
 
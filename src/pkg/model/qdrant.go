@@ -6,6 +6,15 @@ import (
 	pb "github.com/qdrant/go-client/qdrant"
 )
 
+// Payload index field types accepted by CreatePayloadIndex
+const (
+	PayloadIndexText     = 1
+	PayloadIndexKeyword  = 2
+	PayloadIndexInteger  = 3
+	PayloadIndexFloat    = 4
+	PayloadIndexDatetime = 5
+)
+
 // QdrantClient interface defines the operations needed for the Qdrant vector database
 type QdrantClient interface {
 	// Collection management
@@ -17,7 +26,8 @@ type QdrantClient interface {
 	// Point operations
 	UpsertPoints(ctx context.Context, collectionName string, points []*pb.PointStruct) error
 	DeletePoints(ctx context.Context, collectionName string, ids []string) error
-	GetPointsByPath(ctx context.Context, collectionName string, path string) ([]*pb.RetrievedPoint, error)
+	GetPointsByPath(ctx context.Context, collectionName string, path string, withVectors bool) ([]*pb.RetrievedPoint, error)
+	ScrollPoints(ctx context.Context, collectionName string, filter *pb.Filter, withVectors bool) ([]*pb.RetrievedPoint, error)
 
 	// Search operations
 	Search(
@@ -28,6 +38,7 @@ type QdrantClient interface {
 		offset uint64,
 		filter *pb.Filter,
 		params *pb.SearchParams,
+		scoreThreshold *float32,
 	) ([]*pb.ScoredPoint, error)
 
 	// Index operations
@@ -37,6 +48,9 @@ type QdrantClient interface {
 		fieldName string,
 		fieldType int,
 	) error
+
+	// Close releases the connection or flushes local storage
+	Close() error
 }
 
 // GetPayloadString extracts a string value from a Qdrant payload field
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+// Reranker scores how well passages answer a query, for a second, more precise
+// ranking of the candidates a vector search found
+type Reranker interface {
+	// Rerank returns a relevance between 0 and 1 for every passage, in passage order
+	Rerank(ctx context.Context, query string, passages []string) ([]float64, error)
+
+	// Name returns the model name
+	Name() string
+}
+
+// OllamaRerankerConfig holds configuration for reranking with an Ollama model
+type OllamaRerankerConfig struct {
+	ModelName   string
+	ServerURL   string
+	Concurrency int
+}
+
+// rerankPrompt asks a generate model for a relevance grade, small models follow it reliably
+const rerankPrompt = `Rate how relevant the passage is to the search query on a scale from 0 (unrelated) to 10 (answers it directly).
+Reply with the number only.
+
+Query: %s
+
+Passage:
+%s
+
+Relevance:`
+
+// maxRerankPassage caps the passage length in bytes so prompts stay fast
+const maxRerankPassage = 2000
+
+// OllamaReranker grades query/passage pairs with a local Ollama generate model
+type OllamaReranker struct {
+	client      *ollama.LLM
+	modelName   string
+	concurrency int
+}
+
+// NewOllamaReranker creates a new Ollama-based reranker
+func NewOllamaReranker(config OllamaRerankerConfig) (*OllamaReranker, error) {
+	if config.ModelName == "" {
+		return nil, fmt.Errorf("no rerank model configured")
+	}
+
+	client, err := ollama.New(
+		ollama.WithModel(config.ModelName),
+		ollama.WithServerURL(config.ServerURL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Ollama client: %w", err)
+	}
+
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	return &OllamaReranker{
+		client:      client,
+		modelName:   config.ModelName,
+		concurrency: concurrency,
+	}, nil
+}
+
+// Rerank grades every passage with its own prompt, a few at a time.
+// It fails as a whole if any passage can't be graded, so callers never mix
+// graded and ungraded candidates.
+func (r *OllamaReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	scores := make([]float64, len(passages))
+	if len(passages) == 0 {
+		return scores, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, r.concurrency)
+
+	for i, passage := range passages {
+		wg.Add(1)
+		go func(i int, passage string) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return
+			}
+
+			score, err := r.grade(ctx, query, passage)
+			if err != nil {
+				errMutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				errMutex.Unlock()
+				return
+			}
+			scores[i] = score
+		}(i, passage)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// grade asks the model for the relevance of a single passage
+func (r *OllamaReranker) grade(ctx context.Context, query, passage string) (float64, error) {
+	if len(passage) > maxRerankPassage {
+		passage = passage[:maxRerankPassage]
+	}
+
+	reply, err := r.client.Call(ctx, fmt.Sprintf(rerankPrompt, query, passage),
+		llms.WithTemperature(0),
+		llms.WithMaxTokens(4),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to grade passage: %w", err)
+	}
+
+	grade, err := parseGrade(reply)
+	if err != nil {
+		return 0, err
+	}
+	return grade / 10, nil
+}
+
+// Name returns the model name
+func (r *OllamaReranker) Name() string {
+	return r.modelName
+}
+
+// parseGrade reads the leading number of a model reply and clamps it to 0..10
+func parseGrade(reply string) (float64, error) {
+	reply = strings.TrimSpace(reply)
+	end := strings.IndexFunc(reply, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if end < 0 {
+		end = len(reply)
+	}
+
+	grade, err := strconv.ParseFloat(strings.TrimSuffix(reply[:end], "."), 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected reranker reply %q", reply)
+	}
+
+	if grade < 0 {
+		grade = 0
+	} else if grade > 10 {
+		grade = 10
+	}
+	return grade, nil
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
 
@@ -21,6 +22,26 @@ func HashString(input string) string {
 	return uuid.NewSHA1(namespaceUUID, []byte(input)).String()
 }
 
+// CosineSimilarity returns the cosine of the angle between two vectors,
+// or 0 if they differ in length or either is zero
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
 // StructToPayload converts a struct or map to a Qdrant payload
 func StructToPayload(input interface{}) map[string]*pb.Value {
 	payload := make(map[string]*pb.Value)
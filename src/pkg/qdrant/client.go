@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
+	model2 "obsfind/src/pkg/model"
+
 	pb "github.com/qdrant/go-client/qdrant"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -75,6 +75,7 @@ type Config struct {
 	Port           int
 	APIKey         string
 	Embedded       bool
+	BinaryPath     string
 	DataPath       string
 	Collection     string
 	DefaultTimeout time.Duration
@@ -114,9 +115,9 @@ func NewClient(config *Config, options ...ClientOption) (*Client, error) {
 		option(client)
 	}
 
-	// If using embedded mode, start the embedded server
+	// If using embedded mode, supervise a local Qdrant process
 	if config.Embedded {
-		embedded, err := NewEmbeddedServer(config.DataPath, config.Port)
+		embedded, err := NewEmbeddedServer(config.BinaryPath, config.DataPath, config.Port, client.logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create embedded server: %w", err)
 		}
@@ -189,8 +190,11 @@ func (c *Client) Connect(ctx context.Context) error {
 			return fmt.Errorf("failed to start embedded server: %w", err)
 		}
 
-		// Wait for server to start
+		// Wait for server to start, and don't leave a half started process behind
 		if err := c.waitForServerReady(ctx); err != nil {
+			if stopErr := c.embedded.Stop(); stopErr != nil {
+				c.logger.Error("Error stopping embedded server", "error", stopErr)
+			}
 			return err
 		}
 	}
@@ -1252,6 +1256,7 @@ func (c *Client) Search(
 	offset uint64,
 	filter *pb.Filter,
 	params *pb.SearchParams,
+	scoreThreshold *float32,
 ) ([]*pb.ScoredPoint, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -1299,6 +1304,11 @@ func (c *Client) Search(
 		request.Params = params
 	}
 
+	// Let Qdrant drop weak matches so limit and offset apply to the remaining ones
+	if scoreThreshold != nil {
+		request.ScoreThreshold = scoreThreshold
+	}
+
 	// Execute search
 	response, err := c.points.Search(ctx, request)
 	if err != nil {
@@ -1327,7 +1337,7 @@ func (c *Client) SearchWithOptions(ctx context.Context, collectionName string, v
 		"has_filter", options.Filter != nil)
 
 	// Call the interface-compliant Search method
-	scoredPoints, err := c.Search(ctx, collectionName, vector, options.Limit, options.Offset, options.Filter, nil)
+	scoredPoints, err := c.Search(ctx, collectionName, vector, options.Limit, options.Offset, options.Filter, nil, nil)
 	if err != nil {
 		// Error already logged in the Search method
 		return nil, err
@@ -1363,6 +1373,9 @@ func (c *Client) SearchWithOptions(ctx context.Context, collectionName string, v
 type Schema struct {
 	VectorSize int
 	IndexType  string
+
+	// PayloadIndexes maps payload fields used in search filters to their index type
+	PayloadIndexes map[string]int
 }
 
 // DefaultSchema returns the default schema for ObsFind
@@ -1370,16 +1383,39 @@ func DefaultSchema() *Schema {
 	return &Schema{
 		VectorSize: 768, // Default for nomic-embed-text model
 		IndexType:  "hnsw",
+		PayloadIndexes: map[string]int{
+			"tags":       model2.PayloadIndexKeyword,
+			"path":       model2.PayloadIndexKeyword,
+			"path_dirs":  model2.PayloadIndexKeyword,
+			"vault_name": model2.PayloadIndexKeyword,
+			"language":   model2.PayloadIndexKeyword,
+			"title":      model2.PayloadIndexText,
+			"created":    model2.PayloadIndexInteger,
+			"modified":   model2.PayloadIndexInteger,
+		},
 	}
 }
 
 // Apply creates or updates the collection according to schema
-func (s *Schema) Apply(ctx context.Context, client *Client, collection string) error {
+func (s *Schema) Apply(ctx context.Context, client model2.QdrantClient, collection string) error {
 	// Create collection if it doesn't exist
 	if err := client.CreateCollection(ctx, collection, uint64(s.VectorSize), pb.Distance_Cosine); err != nil {
 		return fmt.Errorf("failed to create collection: %w", err)
 	}
 
+	// Index the filtered fields; creating an index that already exists is a no-op
+	fields := make([]string, 0, len(s.PayloadIndexes))
+	for field := range s.PayloadIndexes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		if err := client.CreatePayloadIndex(ctx, collection, field, s.PayloadIndexes[field]); err != nil {
+			return fmt.Errorf("failed to create payload index: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -1388,14 +1424,16 @@ func (c *Client) CreatePayloadIndex(ctx context.Context, collectionName string,
 	// Convert the int field type to qdrant.FieldType enum
 	var qFieldType pb.FieldType
 	switch fieldType {
-	case 1: // Text
+	case model2.PayloadIndexText:
 		qFieldType = pb.FieldType_FieldTypeText
-	case 2: // Keyword
+	case model2.PayloadIndexKeyword:
 		qFieldType = pb.FieldType_FieldTypeKeyword
-	case 3: // Integer
+	case model2.PayloadIndexInteger:
 		qFieldType = pb.FieldType_FieldTypeInteger
-	case 4: // Float
+	case model2.PayloadIndexFloat:
 		qFieldType = pb.FieldType_FieldTypeFloat
+	case model2.PayloadIndexDatetime:
+		qFieldType = pb.FieldType_FieldTypeDatetime
 	default:
 		return fmt.Errorf("unsupported field type: %d", fieldType)
 	}
@@ -1419,7 +1457,7 @@ func (c *Client) CreatePayloadIndex(ctx context.Context, collectionName string,
 }
 
 // GetPointsByPath retrieves points with a specific path with proper pagination
-func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, path string) ([]*pb.RetrievedPoint, error) {
+func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, path string, withVectors bool) ([]*pb.RetrievedPoint, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -1432,14 +1470,14 @@ func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, pat
 
 	c.logger.Debug("Getting points by path", "collection", collectionName, "path", path)
 
-	// Create a match condition for the path field
+	// Create an exact match condition for the path field
 	matchCondition := &pb.Condition{
 		ConditionOneOf: &pb.Condition_Field{
 			Field: &pb.FieldCondition{
 				Key: "path",
 				Match: &pb.Match{
-					MatchValue: &pb.Match_Text{
-						Text: path,
+					MatchValue: &pb.Match_Keyword{
+						Keyword: path,
 					},
 				},
 			},
@@ -1451,6 +1489,43 @@ func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, pat
 		Must: []*pb.Condition{matchCondition},
 	}
 
+	allResults, err := c.scrollPoints(ctx, collectionName, filter, withVectors)
+	if err != nil {
+		c.logger.Error("Failed to scroll points", "collection", collectionName, "path", path, "error", err)
+		return nil, fmt.Errorf("failed to get points by path: %w", err)
+	}
+
+	c.logger.Info("Retrieved points by path", "collection", collectionName, "path", path, "count", len(allResults))
+	return allResults, nil
+}
+
+// ScrollPoints retrieves all points matching the filter with proper pagination.
+// A nil filter returns every point in the collection.
+func (c *Client) ScrollPoints(ctx context.Context, collectionName string, filter *pb.Filter, withVectors bool) ([]*pb.RetrievedPoint, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.conn == nil {
+		return nil, fmt.Errorf("not connected to Qdrant, call Connect() first")
+	}
+
+	ctx, cancel := c.ensureContext(ctx)
+	defer cancel()
+
+	c.logger.Debug("Scrolling points", "collection", collectionName, "has_filter", filter != nil)
+
+	allResults, err := c.scrollPoints(ctx, collectionName, filter, withVectors)
+	if err != nil {
+		c.logger.Error("Failed to scroll points", "collection", collectionName, "error", err)
+		return nil, fmt.Errorf("failed to scroll points: %w", err)
+	}
+
+	c.logger.Debug("Scrolled points", "collection", collectionName, "count", len(allResults))
+	return allResults, nil
+}
+
+// scrollPoints pages through the points matching the filter, used by GetPointsByPath and ScrollPoints
+func (c *Client) scrollPoints(ctx context.Context, collectionName string, filter *pb.Filter, withVectors bool) ([]*pb.RetrievedPoint, error) {
 	// Paginate through results using scroll API
 	var allResults []*pb.RetrievedPoint
 	var pointId *pb.PointId = nil
@@ -1469,7 +1544,7 @@ func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, pat
 			},
 			WithVectors: &pb.WithVectorsSelector{
 				SelectorOptions: &pb.WithVectorsSelector_Enable{
-					Enable: true,
+					Enable: withVectors,
 				},
 			},
 			Offset: pointId, // Use the last point ID as offset for pagination
@@ -1478,8 +1553,7 @@ func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, pat
 		// Execute scroll request
 		response, err := c.points.Scroll(ctx, request)
 		if err != nil {
-			c.logger.Error("Failed to scroll points", "collection", collectionName, "path", path, "error", err)
-			return nil, fmt.Errorf("failed to get points by path: %w", err)
+			return nil, err
 		}
 
 		// Add results to the collection
@@ -1496,89 +1570,5 @@ func (c *Client) GetPointsByPath(ctx context.Context, collectionName string, pat
 		c.logger.Debug("Retrieved batch of points", "count", len(response.Result), "total_so_far", len(allResults))
 	}
 
-	c.logger.Info("Retrieved points by path", "collection", collectionName, "path", path, "count", len(allResults))
 	return allResults, nil
 }
-
-// EmbeddedServer manages an embedded Qdrant instance
-type EmbeddedServer struct {
-	cmd      *exec.Cmd
-	dataPath string
-	port     int
-}
-
-// NewEmbeddedServer creates a new embedded Qdrant server
-func NewEmbeddedServer(dataPath string, port int) (*EmbeddedServer, error) {
-	// Ensure the data directory exists
-	if err := os.MkdirAll(dataPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	// Create log directory
-	logDir := filepath.Join(dataPath, "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	return &EmbeddedServer{
-		dataPath: dataPath,
-		port:     port,
-	}, nil
-}
-
-// Start launches the embedded server
-func (s *EmbeddedServer) Start() error {
-	// Check if already running
-	if s.cmd != nil && s.cmd.Process != nil {
-		// Check if process is still running
-		if s.IsRunning() {
-			return nil
-		}
-	}
-
-	// For now, this is a placeholder for embedded Qdrant
-	// In a real implementation, we would:
-	// 1. Download Qdrant binary if not available
-	// 2. Launch it with appropriate arguments
-	// 3. Monitor the process
-
-	log.Println("Embedded Qdrant is not yet implemented - using external Qdrant")
-
-	// Simulating a running server (will be replaced in a real implementation)
-	s.cmd = exec.Command("sleep", "3600")
-	return nil
-}
-
-// Stop gracefully stops the server
-func (s *EmbeddedServer) Stop() error {
-	if s.cmd == nil || s.cmd.Process == nil {
-		return nil
-	}
-
-	// Send signal to terminate
-	if err := s.cmd.Process.Signal(os.Interrupt); err != nil {
-		// If interrupt fails, force kill
-		if killErr := s.cmd.Process.Kill(); killErr != nil {
-			return fmt.Errorf("failed to kill process: %w", killErr)
-		}
-	}
-
-	// Wait for process to exit
-	return s.cmd.Wait()
-}
-
-// IsRunning checks if the server is operational
-func (s *EmbeddedServer) IsRunning() bool {
-	if s.cmd == nil || s.cmd.Process == nil {
-		return false
-	}
-
-	// Try to get process state (will return nil if running)
-	if s.cmd.ProcessState != nil {
-		return false
-	}
-
-	// Check if process exists - on Unix we'd use Signal(0)
-	// For now, just assume process is running if we get here
-	return true
-}
@@ -0,0 +1,289 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// embeddedStopTimeout is how long Stop waits for a graceful shutdown before killing the process
+	embeddedStopTimeout = 5 * time.Second
+
+	// embeddedMinBackoff and embeddedMaxBackoff bound the delay between restarts after a crash
+	embeddedMinBackoff = time.Second
+	embeddedMaxBackoff = time.Minute
+
+	// embeddedStableRuntime is how long the process has to stay up before the backoff resets
+	embeddedStableRuntime = 5 * time.Minute
+)
+
+// EmbeddedServer supervises a locally installed Qdrant binary.
+// The gRPC API listens on the configured port and the REST API, used for
+// health probes, on the port just below it, matching Qdrant's 6333/6334 defaults.
+type EmbeddedServer struct {
+	mu         sync.Mutex
+	binaryPath string
+	dataPath   string
+	port       int
+	httpPort   int
+	logger     Logger
+	probe      *http.Client
+
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	stopping bool
+	stopped  chan struct{}
+	backoff  time.Duration
+}
+
+// NewEmbeddedServer creates a supervisor for the Qdrant binary at binaryPath
+func NewEmbeddedServer(binaryPath, dataPath string, port int, logger Logger) (*EmbeddedServer, error) {
+	if binaryPath == "" {
+		return nil, fmt.Errorf("no Qdrant binary configured")
+	}
+
+	// Resolve bare names like "qdrant" through PATH
+	resolved, err := exec.LookPath(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("qdrant binary not found: %w", err)
+	}
+
+	// Ensure the data directory exists
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// Create log directory
+	logDir := filepath.Join(dataPath, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	if logger == nil {
+		logger = &defaultLogger{}
+	}
+
+	return &EmbeddedServer{
+		binaryPath: resolved,
+		dataPath:   dataPath,
+		port:       port,
+		httpPort:   port - 1,
+		logger:     logger,
+		probe:      &http.Client{Timeout: time.Second},
+		backoff:    embeddedMinBackoff,
+	}, nil
+}
+
+// Start launches the server and begins supervising it
+func (s *EmbeddedServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Already launched and supervised
+	if s.stopped != nil {
+		return nil
+	}
+
+	s.stopping = false
+	s.stopped = make(chan struct{})
+
+	if err := s.launch(); err != nil {
+		close(s.stopped)
+		s.stopped = nil
+		return err
+	}
+
+	go s.supervise(s.stopped)
+	return nil
+}
+
+// launch starts a new Qdrant process, the caller must hold the lock
+func (s *EmbeddedServer) launch() error {
+	logFile, err := os.OpenFile(
+		filepath.Join(s.dataPath, "logs", "qdrant.log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		0644,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open qdrant log file: %w", err)
+	}
+
+	cmd := exec.Command(s.binaryPath)
+	cmd.Dir = s.dataPath
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcessGroup(cmd)
+	cmd.Env = append(os.Environ(),
+		"QDRANT__SERVICE__HOST=127.0.0.1",
+		fmt.Sprintf("QDRANT__SERVICE__GRPC_PORT=%d", s.port),
+		fmt.Sprintf("QDRANT__SERVICE__HTTP_PORT=%d", s.httpPort),
+		"QDRANT__STORAGE__STORAGE_PATH="+filepath.Join(s.dataPath, "storage"),
+		"QDRANT__STORAGE__SNAPSHOTS_PATH="+filepath.Join(s.dataPath, "snapshots"),
+		"QDRANT__TELEMETRY_DISABLED=true",
+	)
+
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("failed to start qdrant: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		logFile.Close()
+		close(exited)
+	}()
+
+	s.cmd = cmd
+	s.exited = exited
+
+	s.logger.Info("Started embedded Qdrant", "pid", cmd.Process.Pid, "port", s.port)
+	return nil
+}
+
+// supervise restarts the process with exponential backoff until Stop is called
+func (s *EmbeddedServer) supervise(stopped chan struct{}) {
+	for {
+		s.mu.Lock()
+		exited := s.exited
+		startedAt := time.Now()
+		s.mu.Unlock()
+
+		select {
+		case <-stopped:
+			return
+		case <-exited:
+		}
+
+		s.mu.Lock()
+		if s.stopping {
+			s.mu.Unlock()
+			return
+		}
+
+		// A process that ran for a while counts as healthy, so start over with a short delay
+		if time.Since(startedAt) > embeddedStableRuntime {
+			s.backoff = embeddedMinBackoff
+		}
+		delay := s.backoff
+		s.backoff *= 2
+		if s.backoff > embeddedMaxBackoff {
+			s.backoff = embeddedMaxBackoff
+		}
+		s.mu.Unlock()
+
+		s.logger.Warn("Embedded Qdrant exited unexpectedly, restarting", "delay", delay)
+
+		select {
+		case <-stopped:
+			return
+		case <-time.After(delay):
+		}
+
+		s.mu.Lock()
+		if s.stopping {
+			s.mu.Unlock()
+			return
+		}
+		if err := s.launch(); err != nil {
+			s.logger.Error("Failed to restart embedded Qdrant", "error", err)
+			// Retry on the next round with a closed channel standing in for the dead process
+			closedCh := make(chan struct{})
+			close(closedCh)
+			s.exited = closedCh
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Stop gracefully stops the server and ends supervision
+func (s *EmbeddedServer) Stop() error {
+	s.mu.Lock()
+	if s.stopped == nil {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.stopping = true
+	close(s.stopped)
+	s.stopped = nil
+	cmd, exited := s.cmd, s.exited
+	s.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	// Check whether the process already exited
+	select {
+	case <-exited:
+		return nil
+	default:
+	}
+
+	// Send signal to terminate; interrupts aren't supported on Windows
+	if runtime.GOOS == "windows" {
+		if err := cmd.Process.Kill(); err != nil {
+			return fmt.Errorf("failed to kill process: %w", err)
+		}
+	} else if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		// If interrupt fails, force kill
+		if killErr := cmd.Process.Kill(); killErr != nil {
+			return fmt.Errorf("failed to kill process: %w", killErr)
+		}
+	}
+
+	// Wait for process to exit
+	select {
+	case <-exited:
+	case <-time.After(embeddedStopTimeout):
+		s.logger.Warn("Embedded Qdrant did not stop in time, killing it")
+		if err := cmd.Process.Kill(); err != nil {
+			return fmt.Errorf("failed to kill process: %w", err)
+		}
+		<-exited
+	}
+
+	s.logger.Info("Stopped embedded Qdrant")
+	return nil
+}
+
+// IsRunning checks if the server process is alive and answering its readiness probe
+func (s *EmbeddedServer) IsRunning() bool {
+	s.mu.Lock()
+	exited := s.exited
+	s.mu.Unlock()
+
+	if exited == nil {
+		return false
+	}
+
+	select {
+	case <-exited:
+		return false
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.probe.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/readyz", s.httpPort), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := s.probe.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
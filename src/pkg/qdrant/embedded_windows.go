@@ -0,0 +1,14 @@
+//go:build windows
+
+package qdrant
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcessGroup keeps console signals aimed at the daemon away from the
+// supervised process, so it only stops when the daemon asks it to
+func detachProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
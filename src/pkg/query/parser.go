@@ -0,0 +1,366 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse parses a search query into its terms, phrases and filters
+func Parse(input string) (*Query, error) {
+	p := &parser{input: input}
+	q := &Query{}
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+
+		start := p.pos
+		negated := false
+		if p.peek() == '-' {
+			negated = true
+			p.advance()
+			// A dash on its own is just punctuation
+			if p.done() || unicode.IsSpace(p.peek()) {
+				continue
+			}
+		}
+
+		if p.peek() == '"' {
+			phrase, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			if phrase = strings.TrimSpace(phrase); phrase == "" {
+				continue
+			}
+			if negated {
+				q.Excluded = append(q.Excluded, phrase)
+			} else {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			continue
+		}
+
+		if name, ok := p.fieldName(); ok {
+			filter, err := p.filter(name, start)
+			if err != nil {
+				return nil, err
+			}
+			filter.Negated = negated
+			q.Filters = append(q.Filters, filter)
+			continue
+		}
+
+		word := p.word()
+		if negated {
+			q.Excluded = append(q.Excluded, word)
+		} else {
+			q.Terms = append(q.Terms, word)
+		}
+	}
+
+	return q, nil
+}
+
+// parser holds the scanning state
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+// peek decodes the character at the position, queries are UTF-8
+func (p *parser) peek() rune {
+	r, _ := utf8.DecodeRuneInString(p.input[p.pos:])
+	return r
+}
+
+// advance moves the position past the character at it
+func (p *parser) advance() {
+	_, size := utf8.DecodeRuneInString(p.input[p.pos:])
+	p.pos += size
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && unicode.IsSpace(p.peek()) {
+		p.advance()
+	}
+}
+
+func (p *parser) errorf(pos int, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// word reads a bare word up to the next space
+func (p *parser) word() string {
+	start := p.pos
+	for !p.done() && !unicode.IsSpace(p.peek()) {
+		p.advance()
+	}
+	return p.input[start:p.pos]
+}
+
+// quoted reads a double quoted string, backslash escapes the next character
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.peek()
+		p.advance()
+		switch {
+		case c == '\\' && !p.done():
+			b.WriteRune(p.peek())
+			p.advance()
+		case c == '"':
+			return b.String(), nil
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	return "", p.errorf(start, "unterminated quote")
+}
+
+// fieldName reads "name:" if the next word is a filter and leaves the position after the colon.
+// Names start with a letter so times like 12:30 stay plain words, and only known fields
+// count, so words like "TODO:" or links like https://example.com are searched as text.
+func (p *parser) fieldName() (string, bool) {
+	if !unicode.IsLetter(p.peek()) {
+		return "", false
+	}
+
+	end := p.pos
+	for end < len(p.input) {
+		c, size := utf8.DecodeRuneInString(p.input[end:])
+		if c == ':' {
+			break
+		}
+		if !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-' || c == '.') {
+			return "", false
+		}
+		end += size
+	}
+
+	if end == p.pos || end >= len(p.input) {
+		return "", false
+	}
+
+	name := p.input[p.pos:end]
+	if _, err := canonicalField(name); err != nil && !strings.HasPrefix(strings.ToLower(name), FrontmatterPrefix) {
+		return "", false
+	}
+
+	p.pos = end + 1
+	return name, true
+}
+
+// filter reads the operator and values following "name:"
+func (p *parser) filter(name string, start int) (Filter, error) {
+	field, err := canonicalField(name)
+	if err != nil {
+		return Filter{}, p.errorf(start, "%v", err)
+	}
+
+	filter := Filter{Field: field, Op: p.operator()}
+
+	valueStart := p.pos
+	values, quoted, err := p.values()
+	if err != nil {
+		return Filter{}, err
+	}
+	if len(values) == 0 {
+		return Filter{}, p.errorf(valueStart, "missing value for %s", name)
+	}
+
+	// start..end ranges are written as a single bare value
+	if filter.Op == OpMatch && !quoted {
+		values = splitRange(&filter, values)
+	}
+	filter.Values = values
+
+	if err := checkFilter(name, &filter); err != nil {
+		return Filter{}, p.errorf(valueStart, "%v", err)
+	}
+
+	return filter, nil
+}
+
+// splitRange turns a single start..end value of a comparable field into a range
+func splitRange(filter *Filter, values []string) []string {
+	if !(filter.IsDate() || filter.IsFrontmatter()) || len(values) != 1 {
+		return values
+	}
+
+	from, to, ok := strings.Cut(values[0], "..")
+	if !ok {
+		return values
+	}
+
+	filter.Op = OpRange
+	return []string{from, to}
+}
+
+// checkFilter validates the values of a filter against its field and operator
+func checkFilter(name string, filter *Filter) error {
+	for _, v := range filter.Values {
+		if v == "" {
+			if filter.Op == OpRange {
+				return fmt.Errorf("range for %s needs both a start and an end", name)
+			}
+			return fmt.Errorf("empty value for %s", name)
+		}
+	}
+
+	if filter.Op != OpMatch && filter.Op != OpRange && len(filter.Values) > 1 {
+		return fmt.Errorf("%s:%s takes a single value", name, filter.Op)
+	}
+
+	switch {
+	case filter.IsDate():
+		for _, v := range filter.Values {
+			if _, _, err := ParseDate(v); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	case filter.IsFrontmatter():
+		if filter.Op == OpMatch {
+			break
+		}
+		// Comparisons need numbers or dates, and ranges the same kind on both ends
+		var kinds []string
+		for _, v := range filter.Values {
+			kind := valueKind(v)
+			if kind == "" {
+				return fmt.Errorf("%s:%s needs a number or a date, got %q", name, filter.Op, v)
+			}
+			kinds = append(kinds, kind)
+		}
+		if len(kinds) == 2 && kinds[0] != kinds[1] {
+			return fmt.Errorf("range for %s mixes a %s and a %s", name, kinds[0], kinds[1])
+		}
+	default:
+		if filter.Op != OpMatch {
+			return fmt.Errorf("%s doesn't support comparisons", name)
+		}
+	}
+
+	if filter.Field == FieldTag {
+		for i, v := range filter.Values {
+			filter.Values[i] = strings.TrimPrefix(v, "#")
+		}
+	}
+
+	// Languages are stored in lower case
+	if filter.Field == FieldLanguage {
+		for i, v := range filter.Values {
+			filter.Values[i] = strings.ToLower(v)
+		}
+	}
+
+	return nil
+}
+
+// valueKind classifies a comparison value as "number" or "date", or "" for neither
+func valueKind(v string) string {
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return "number"
+	}
+	if _, _, err := ParseDate(v); err == nil {
+		return "date"
+	}
+	return ""
+}
+
+// operator reads an optional comparison operator
+func (p *parser) operator() Op {
+	rest := p.input[p.pos:]
+	for _, candidate := range []struct {
+		token string
+		op    Op
+	}{
+		{">=", OpGreaterEqual},
+		{"<=", OpLessEqual},
+		{">", OpGreater},
+		{"<", OpLess},
+		{"=", OpMatch},
+	} {
+		if strings.HasPrefix(rest, candidate.token) {
+			p.pos += len(candidate.token)
+			return candidate.op
+		}
+	}
+	return OpMatch
+}
+
+// values reads a comma separated list of bare or quoted values.
+// It also reports whether any value was quoted.
+func (p *parser) values() ([]string, bool, error) {
+	var values []string
+	quoted := false
+
+	for !p.done() && !unicode.IsSpace(p.peek()) {
+		if p.peek() == '"' {
+			v, err := p.quoted()
+			if err != nil {
+				return nil, false, err
+			}
+			quoted = true
+			values = append(values, v)
+		} else {
+			start := p.pos
+			for !p.done() && !unicode.IsSpace(p.peek()) && p.peek() != ',' {
+				if p.peek() == '"' {
+					return nil, false, p.errorf(p.pos, "unexpected quote inside a value, quote the whole value instead")
+				}
+				p.advance()
+			}
+			if v := p.input[start:p.pos]; v != "" {
+				values = append(values, v)
+			}
+		}
+
+		if p.done() || unicode.IsSpace(p.peek()) {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, false, p.errorf(p.pos, "expected a comma or space after a quoted value")
+		}
+		p.pos++
+	}
+
+	return values, quoted, nil
+}
+
+// canonicalField resolves aliases and rejects unknown fields
+func canonicalField(name string) (string, error) {
+	lower := strings.ToLower(name)
+
+	if strings.HasPrefix(lower, FrontmatterPrefix) {
+		key := name[len(FrontmatterPrefix):]
+		if key == "" {
+			return "", fmt.Errorf("missing frontmatter key after %q", FrontmatterPrefix)
+		}
+		return FrontmatterPrefix + key, nil
+	}
+
+	if alias, ok := fieldAliases[lower]; ok {
+		return alias, nil
+	}
+
+	switch lower {
+	case FieldTag, FieldPath, FieldTitle, FieldVault, FieldCreated, FieldModified, FieldLanguage:
+		return lower, nil
+	}
+
+	return "", fmt.Errorf("unknown filter %q (known filters: tag, path, title, vault, created, modified, lang, fm.<key>)", name)
+}
@@ -0,0 +1,331 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{
+			name:  "terms",
+			input: "  machine   learning ",
+			want:  Query{Terms: []string{"machine", "learning"}},
+		},
+		{
+			name:  "phrase",
+			input: `notes "exact phrase" here`,
+			want:  Query{Terms: []string{"notes", "here"}, Phrases: []string{"exact phrase"}},
+		},
+		{
+			name:  "escaped quote in phrase",
+			input: `"say \"hi\""`,
+			want:  Query{Phrases: []string{`say "hi"`}},
+		},
+		{
+			name:  "empty phrase is skipped",
+			input: `"  " word`,
+			want:  Query{Terms: []string{"word"}},
+		},
+		{
+			name:  "negated word and phrase",
+			input: `auth -draft -"old plan"`,
+			want:  Query{Terms: []string{"auth"}, Excluded: []string{"draft", "old plan"}},
+		},
+		{
+			name:  "lone dash is punctuation",
+			input: "before - after",
+			want:  Query{Terms: []string{"before", "after"}},
+		},
+		{
+			name:  "utf-8 words",
+			input: "Рабочая задача voilà",
+			want:  Query{Terms: []string{"Рабочая", "задача", "voilà"}},
+		},
+		{
+			name:  "utf-8 phrase",
+			input: `"café crème"`,
+			want:  Query{Phrases: []string{"café crème"}},
+		},
+		{
+			name:  "times stay words",
+			input: "standup 12:30",
+			want:  Query{Terms: []string{"standup", "12:30"}},
+		},
+		{
+			name:  "unknown field names stay words",
+			input: "see https://example.com TODO: auth Re: meeting Note:foo",
+			want:  Query{Terms: []string{"see", "https://example.com", "TODO:", "auth", "Re:", "meeting", "Note:foo"}},
+		},
+		{
+			name:  "tag filter",
+			input: "tag:work report",
+			want: Query{
+				Terms:   []string{"report"},
+				Filters: []Filter{{Field: FieldTag, Values: []string{"work"}}},
+			},
+		},
+		{
+			name:  "negated filter with alternatives",
+			input: "-tag:#archive,old",
+			want: Query{
+				Filters: []Filter{{Field: FieldTag, Negated: true, Values: []string{"archive", "old"}}},
+			},
+		},
+		{
+			name:  "aliases and case",
+			input: "Tags:a in:projects/ mtime:2024 language:Go",
+			want: Query{
+				Filters: []Filter{
+					{Field: FieldTag, Values: []string{"a"}},
+					{Field: FieldPath, Values: []string{"projects/"}},
+					{Field: FieldModified, Values: []string{"2024"}},
+					{Field: FieldLanguage, Values: []string{"go"}},
+				},
+			},
+		},
+		{
+			name:  "quoted values",
+			input: `title:"weekly review",daily vault:"My Vault"`,
+			want: Query{
+				Filters: []Filter{
+					{Field: FieldTitle, Values: []string{"weekly review", "daily"}},
+					{Field: FieldVault, Values: []string{"My Vault"}},
+				},
+			},
+		},
+		{
+			name:  "frontmatter match",
+			input: "fm.status:active fm.Owner:ana",
+			want: Query{
+				Filters: []Filter{
+					{Field: "fm.status", Values: []string{"active"}},
+					{Field: "fm.Owner", Values: []string{"ana"}},
+				},
+			},
+		},
+		{
+			name:  "frontmatter comparisons",
+			input: "fm.priority:>=2 fm.due:<2025-01-01 fm.score:=3",
+			want: Query{
+				Filters: []Filter{
+					{Field: "fm.priority", Op: OpGreaterEqual, Values: []string{"2"}},
+					{Field: "fm.due", Op: OpLess, Values: []string{"2025-01-01"}},
+					{Field: "fm.score", Values: []string{"3"}},
+				},
+			},
+		},
+		{
+			name:  "frontmatter range",
+			input: "fm.priority:1..3",
+			want: Query{
+				Filters: []Filter{{Field: "fm.priority", Op: OpRange, Values: []string{"1", "3"}}},
+			},
+		},
+		{
+			name:  "quoted range is a value",
+			input: `fm.version:"1..3"`,
+			want: Query{
+				Filters: []Filter{{Field: "fm.version", Values: []string{"1..3"}}},
+			},
+		},
+		{
+			name:  "date filters",
+			input: "created:>2024-01 modified:2024-05-01..2024-06 created:<=2024-03-01T10:30",
+			want: Query{
+				Filters: []Filter{
+					{Field: FieldCreated, Op: OpGreater, Values: []string{"2024-01"}},
+					{Field: FieldModified, Op: OpRange, Values: []string{"2024-05-01", "2024-06"}},
+					{Field: FieldCreated, Op: OpLessEqual, Values: []string{"2024-03-01T10:30"}},
+				},
+			},
+		},
+		{
+			name:  "path range is a value",
+			input: "path:a..b",
+			want: Query{
+				Filters: []Filter{{Field: FieldPath, Values: []string{"a..b"}}},
+			},
+		},
+		{
+			name:  "mixed query",
+			input: `tag:work -tag:archive path:projects/ title:"weekly" fm.status:active created:>2024-01-01 "exact phrase" kickoff`,
+			want: Query{
+				Terms:   []string{"kickoff"},
+				Phrases: []string{"exact phrase"},
+				Filters: []Filter{
+					{Field: FieldTag, Values: []string{"work"}},
+					{Field: FieldTag, Negated: true, Values: []string{"archive"}},
+					{Field: FieldPath, Values: []string{"projects/"}},
+					{Field: FieldTitle, Values: []string{"weekly"}},
+					{Field: "fm.status", Values: []string{"active"}},
+					{Field: FieldCreated, Op: OpGreater, Values: []string{"2024-01-01"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(normalize(*got), normalize(tt.want)) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"open phrase`, `invalid query at position 1: unterminated quote`},
+		{`word "open`, `invalid query at position 6: unterminated quote`},
+		{`tag:"open`, `invalid query at position 5: unterminated quote`},
+		{`tag:wo"rk`, `invalid query at position 7: unexpected quote inside a value, quote the whole value instead`},
+		{`tag:"a"b`, `invalid query at position 8: expected a comma or space after a quoted value`},
+		{`tag:`, `invalid query at position 5: missing value for tag`},
+		{`tag:, next`, `invalid query at position 5: missing value for tag`},
+		{`tag:""`, `invalid query at position 5: empty value for tag`},
+		{`fm.:x`, `invalid query at position 1: missing frontmatter key after "fm."`},
+		{`-fm.:x`, `invalid query at position 1: missing frontmatter key after "fm."`},
+		{`tag:>work`, `invalid query at position 6: tag doesn't support comparisons`},
+		{`path:<a`, `invalid query at position 7: path doesn't support comparisons`},
+		{`created:2024-13`, `invalid query at position 9: created: invalid date "2024-13", expected YYYY-MM-DD with an optional THH:MM[:SS] time`},
+		{`created:>yesterday`, `invalid query at position 10: created: invalid date "yesterday", expected YYYY-MM-DD with an optional THH:MM[:SS] time`},
+		{`modified:2024..`, `invalid query at position 10: range for modified needs both a start and an end`},
+		{`fm.priority:>=2,3`, `invalid query at position 15: fm.priority:>= takes a single value`},
+		{`fm.priority:>high`, `invalid query at position 14: fm.priority:> needs a number or a date, got "high"`},
+		{`fm.due:1..2024-01-01`, `invalid query at position 8: range for fm.due mixes a number and a date`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded, want error %q", tt.input, tt.want)
+			}
+			if _, ok := err.(*Error); !ok {
+				t.Errorf("Parse(%q) returned %T, want *Error", tt.input, err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Parse(%q) error = %q, want %q", tt.input, err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterStringRoundTrip(t *testing.T) {
+	filters := []Filter{
+		{Field: FieldTag, Values: []string{"work", "home"}},
+		{Field: FieldTag, Negated: true, Values: []string{"archive"}},
+		{Field: FieldTitle, Values: []string{"weekly review"}},
+		{Field: FieldPath, Values: []string{`odd "folder"/`}},
+		{Field: FieldCreated, Op: OpGreaterEqual, Values: []string{"2024-01"}},
+		{Field: FieldModified, Op: OpRange, Values: []string{"2024-01-01", "2024-02"}},
+		{Field: "fm.priority", Op: OpLess, Values: []string{"3"}},
+		{Field: "fm.version", Values: []string{"1..3"}},
+	}
+
+	for _, filter := range filters {
+		t.Run(filter.String(), func(t *testing.T) {
+			q, err := Parse(filter.String())
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", filter.String(), err)
+			}
+			if len(q.Filters) != 1 || !reflect.DeepEqual(q.Filters[0], filter) {
+				t.Errorf("Parse(%q) = %+v, want %+v", filter.String(), q.Filters, filter)
+			}
+		})
+	}
+}
+
+func TestTerm(t *testing.T) {
+	tests := []struct {
+		field  string
+		values []string
+		want   string
+	}{
+		{FieldTag, []string{"work"}, "tag:work"},
+		{FieldTag, []string{"work", "home"}, "tag:work,home"},
+		{FieldPath, []string{"My Notes/"}, `path:"My Notes/"`},
+		{FieldPath, []string{`a"b`}, `path:"a\"b"`},
+		{FieldPath, []string{"a,b"}, `path:"a,b"`},
+	}
+
+	for _, tt := range tests {
+		if got := Term(tt.field, tt.values...); got != tt.want {
+			t.Errorf("Term(%q, %q) = %q, want %q", tt.field, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		value      string
+		start, end time.Time
+	}{
+		{"2024", day(2024, 1, 1), day(2025, 1, 1)},
+		{"2024-01", day(2024, 1, 1), day(2024, 2, 1)},
+		{"2024-12", day(2024, 12, 1), day(2025, 1, 1)},
+		{"2024-02-28", day(2024, 2, 28), day(2024, 2, 29)},
+		{"2024-03-01T10:30", day(2024, 3, 1).Add(10*time.Hour + 30*time.Minute), day(2024, 3, 1).Add(10*time.Hour + 31*time.Minute)},
+		{"2024-03-01 10:30:15", day(2024, 3, 1).Add(10*time.Hour + 30*time.Minute + 15*time.Second), day(2024, 3, 1).Add(10*time.Hour + 30*time.Minute + 16*time.Second)},
+		{" 2024-05-01 ", day(2024, 5, 1), day(2024, 5, 2)},
+	}
+
+	for _, tt := range tests {
+		start, end, err := ParseDate(tt.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("ParseDate(%q) = %v, %v, want %v, %v", tt.value, start, end, tt.start, tt.end)
+		}
+	}
+
+	start, end, err := ParseDate("2024-01-01T12:00:00Z")
+	if err != nil {
+		t.Fatalf("ParseDate of an RFC 3339 timestamp returned error: %v", err)
+	}
+	if want := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC); !start.Equal(want) || !end.Equal(want.Add(time.Second)) {
+		t.Errorf("ParseDate of an RFC 3339 timestamp = %v, %v, want %v", start, end, want)
+	}
+
+	for _, value := range []string{"", "2024-1", "24-01-01", "2024-02-30", "next week"} {
+		if _, _, err := ParseDate(value); err == nil {
+			t.Errorf("ParseDate(%q) succeeded, want error", value)
+		}
+	}
+}
+
+// normalize makes nil and empty slices compare equal
+func normalize(q Query) Query {
+	if len(q.Terms) == 0 {
+		q.Terms = nil
+	}
+	if len(q.Phrases) == 0 {
+		q.Phrases = nil
+	}
+	if len(q.Excluded) == 0 {
+		q.Excluded = nil
+	}
+	if len(q.Filters) == 0 {
+		q.Filters = nil
+	}
+	return q
+}
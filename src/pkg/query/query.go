@@ -0,0 +1,209 @@
+// Package query parses the search query language.
+//
+// A query mixes free text with field filters:
+//
+//	tag:work -tag:archive path:projects/ title:"weekly" fm.status:active created:>2024-01-01 "exact phrase"
+//
+// Free text ranks the results, quoted phrases must occur in a matching chunk
+// and field filters restrict the results. A leading "-" negates a filter,
+// phrase or word. Comma separated values match any of them (tag:work,home).
+// lang:go matches code blocks in a language, which are chunked on their own
+// when indexing code blocks is enabled.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields understood by the parser besides fm.<key>
+const (
+	FieldTag      = "tag"
+	FieldPath     = "path"
+	FieldTitle    = "title"
+	FieldVault    = "vault"
+	FieldCreated  = "created"
+	FieldModified = "modified"
+	FieldLanguage = "lang"
+)
+
+// FrontmatterPrefix starts filters on frontmatter keys, e.g. fm.status
+const FrontmatterPrefix = "fm."
+
+// fieldAliases maps alternative spellings to the canonical field name
+var fieldAliases = map[string]string{
+	"tags":     FieldTag,
+	"in":       FieldPath,
+	"mtime":    FieldModified,
+	"language": FieldLanguage,
+}
+
+// Op is the comparison of a filter
+type Op int
+
+const (
+	// OpMatch matches any of the values
+	OpMatch Op = iota
+	// OpLess matches values before the first value
+	OpLess
+	// OpLessEqual matches values up to and including the first value
+	OpLessEqual
+	// OpGreater matches values after the first value
+	OpGreater
+	// OpGreaterEqual matches values from the first value on
+	OpGreaterEqual
+	// OpRange matches values between the two values, both included
+	OpRange
+)
+
+// String returns the operator as written in queries
+func (o Op) String() string {
+	switch o {
+	case OpLess:
+		return "<"
+	case OpLessEqual:
+		return "<="
+	case OpGreater:
+		return ">"
+	case OpGreaterEqual:
+		return ">="
+	case OpRange:
+		return ".."
+	default:
+		return ""
+	}
+}
+
+// Query is a parsed search query
+type Query struct {
+	// Terms are the free text words used for ranking
+	Terms []string
+	// Phrases have to occur in matching chunks
+	Phrases []string
+	// Excluded words and phrases must not occur in matching chunks
+	Excluded []string
+	// Filters restrict the results by field
+	Filters []Filter
+}
+
+// Filter restricts results by the value of a field
+type Filter struct {
+	Field   string
+	Negated bool
+	Op      Op
+	// Values holds the alternatives for OpMatch, the bounds for OpRange
+	// and a single value for the other comparisons
+	Values []string
+}
+
+// Text returns the words and phrases that rank the results
+func (q *Query) Text() string {
+	parts := make([]string, 0, len(q.Terms)+len(q.Phrases))
+	parts = append(parts, q.Terms...)
+	parts = append(parts, q.Phrases...)
+	return strings.Join(parts, " ")
+}
+
+// IsEmpty reports whether the query neither ranks nor filters anything
+func (q *Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Excluded) == 0 && len(q.Filters) == 0
+}
+
+// IsFrontmatter reports whether the filter applies to a frontmatter key
+func (f Filter) IsFrontmatter() bool {
+	return strings.HasPrefix(f.Field, FrontmatterPrefix)
+}
+
+// FrontmatterKey returns the frontmatter key of an fm.<key> filter
+func (f Filter) FrontmatterKey() string {
+	return strings.TrimPrefix(f.Field, FrontmatterPrefix)
+}
+
+// IsDate reports whether the filter compares note dates
+func (f Filter) IsDate() bool {
+	return f.Field == FieldCreated || f.Field == FieldModified
+}
+
+// String formats the filter in query syntax
+func (f Filter) String() string {
+	var b strings.Builder
+	if f.Negated {
+		b.WriteByte('-')
+	}
+	b.WriteString(f.Field)
+	b.WriteByte(':')
+
+	values := make([]string, len(f.Values))
+	for i, v := range f.Values {
+		values[i] = quoteValue(v)
+	}
+
+	switch f.Op {
+	case OpMatch:
+		b.WriteString(strings.Join(values, ","))
+	case OpRange:
+		b.WriteString(strings.Join(values, ".."))
+	default:
+		b.WriteString(f.Op.String())
+		b.WriteString(strings.Join(values, ""))
+	}
+	return b.String()
+}
+
+// Term formats a filter matching any of the values, quoting them where needed.
+// It's meant for building query strings from structured parameters.
+func Term(field string, values ...string) string {
+	return Filter{Field: field, Values: values}.String()
+}
+
+// quoteValue quotes a value that wouldn't survive parsing as a bare word
+func quoteValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\",<>=") && !strings.Contains(v, "..") {
+		return v
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
+
+// Error describes a malformed query
+type Error struct {
+	// Pos is the byte offset of the problem, or -1 if it concerns the whole query
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Pos < 0 {
+		return "invalid query: " + e.Msg
+	}
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// dateLayouts are the accepted date formats, from most to least precise
+var dateLayouts = []struct {
+	layout string
+	span   func(time.Time) time.Time
+}{
+	{time.RFC3339, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02 15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02 15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// ParseDate parses a date or timestamp in local time.
+// It returns the start of the period the value names and the start of the next
+// one, so 2024-01 covers all of January.
+func ParseDate(value string) (start, end time.Time, err error) {
+	value = strings.TrimSpace(value)
+	for _, l := range dateLayouts {
+		t, err := time.ParseInLocation(l.layout, value, time.Local)
+		if err == nil {
+			return t, l.span(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD with an optional THH:MM[:SS] time", value)
+}
@@ -0,0 +1,82 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// whereOperators lists the operators of where clauses, longest first so >= wins over >
+var whereOperators = []struct {
+	token   string
+	op      Op
+	negated bool
+}{
+	{"!=", OpMatch, true},
+	{">=", OpGreaterEqual, false},
+	{"<=", OpLessEqual, false},
+	{"=", OpMatch, false},
+	{">", OpGreater, false},
+	{"<", OpLess, false},
+}
+
+// ParseWhere parses a frontmatter condition such as status=active,draft,
+// priority>=2, due<2025-01-01 or status!=done into an fm.<key> filter.
+// Comma separated values match any of them and start..end gives a range.
+func ParseWhere(expr string) (Filter, error) {
+	fail := func(format string, args ...interface{}) (Filter, error) {
+		return Filter{}, &Error{Pos: -1, Msg: fmt.Sprintf("where clause %q: ", expr) + fmt.Sprintf(format, args...)}
+	}
+
+	// Find the first operator, the key itself can't contain one
+	opIndex := strings.IndexAny(expr, "!=<>")
+	if opIndex < 0 {
+		return fail("expected key=value, key!=value or a comparison like key>=value")
+	}
+
+	key := strings.TrimSpace(expr[:opIndex])
+	key = strings.TrimPrefix(key, FrontmatterPrefix)
+	if key == "" {
+		return fail("missing frontmatter key")
+	}
+	for _, r := range key {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.') {
+			return fail("frontmatter key %q may only contain letters, digits, '_', '-' and '.'", key)
+		}
+	}
+
+	rest := expr[opIndex:]
+	for _, candidate := range whereOperators {
+		if !strings.HasPrefix(rest, candidate.token) {
+			continue
+		}
+
+		value := strings.TrimSpace(rest[len(candidate.token):])
+		if value == "" {
+			return fail("missing value")
+		}
+
+		filter := Filter{
+			Field:   FrontmatterPrefix + key,
+			Negated: candidate.negated,
+			Op:      candidate.op,
+		}
+
+		values := []string{value}
+		if candidate.op == OpMatch {
+			values = strings.Split(value, ",")
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+			values = splitRange(&filter, values)
+		}
+		filter.Values = values
+
+		if err := checkFilter(key, &filter); err != nil {
+			return fail("%v", err)
+		}
+		return filter, nil
+	}
+
+	return fail("unknown operator in %q", rest)
+}
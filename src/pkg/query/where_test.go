@@ -0,0 +1,65 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Filter
+	}{
+		{"status=active", Filter{Field: "fm.status", Values: []string{"active"}}},
+		{"fm.status = active, draft", Filter{Field: "fm.status", Values: []string{"active", "draft"}}},
+		{"status!=done", Filter{Field: "fm.status", Negated: true, Values: []string{"done"}}},
+		{"priority>=2", Filter{Field: "fm.priority", Op: OpGreaterEqual, Values: []string{"2"}}},
+		{"priority>2", Filter{Field: "fm.priority", Op: OpGreater, Values: []string{"2"}}},
+		{"due<2025-01-01", Filter{Field: "fm.due", Op: OpLess, Values: []string{"2025-01-01"}}},
+		{"due<=2025-01", Filter{Field: "fm.due", Op: OpLessEqual, Values: []string{"2025-01"}}},
+		{"priority=1..3", Filter{Field: "fm.priority", Op: OpRange, Values: []string{"1", "3"}}},
+		{"project.phase=beta", Filter{Field: "fm.project.phase", Values: []string{"beta"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseWhere(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseWhere(%q) returned error: %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWhere(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"status", `invalid query: where clause "status": expected key=value, key!=value or a comparison like key>=value`},
+		{"=active", `invalid query: where clause "=active": missing frontmatter key`},
+		{"fm.=active", `invalid query: where clause "fm.=active": missing frontmatter key`},
+		{"my key=x", `invalid query: where clause "my key=x": frontmatter key "my key" may only contain letters, digits, '_', '-' and '.'`},
+		{"status=", `invalid query: where clause "status=": missing value`},
+		{"status!active", `invalid query: where clause "status!active": unknown operator in "!active"`},
+		{"priority>high", `invalid query: where clause "priority>high": priority:> needs a number or a date, got "high"`},
+		{"priority>=1,2", `invalid query: where clause "priority>=1,2": priority:>= needs a number or a date, got "1,2"`},
+		{"status=a,,b", `invalid query: where clause "status=a,,b": empty value for status`},
+		{"priority=1..", `invalid query: where clause "priority=1..": range for priority needs both a start and an end`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseWhere(tt.expr)
+			if err == nil {
+				t.Fatalf("ParseWhere(%q) succeeded, want error %q", tt.expr, tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("ParseWhere(%q) error = %q, want %q", tt.expr, err.Error(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+// storedPoint is a single point held in memory
+type storedPoint struct {
+	id      *pb.PointId
+	vector  []float32
+	payload map[string]*pb.Value
+}
+
+// collection is a set of points sharing one vector configuration
+type collection struct {
+	name           string
+	dimensions     uint64
+	distance       pb.Distance
+	points         map[string]*storedPoint
+	byPath         map[string]map[string]bool // point keys by path payload
+	payloadIndexes map[string]int
+	dirty          bool
+}
+
+// newCollection creates an empty collection
+func newCollection(name string, dimensions uint64, distance pb.Distance) *collection {
+	return &collection{
+		name:           name,
+		dimensions:     dimensions,
+		distance:       distance,
+		points:         make(map[string]*storedPoint),
+		byPath:         make(map[string]map[string]bool),
+		payloadIndexes: make(map[string]int),
+		dirty:          true,
+	}
+}
+
+// pointKey returns the map key for a point ID
+func pointKey(id *pb.PointId) string {
+	if uuid := id.GetUuid(); uuid != "" {
+		return uuid
+	}
+	return strconv.FormatUint(id.GetNum(), 10)
+}
+
+// upsert validates and stores points, replacing existing points with the same ID
+func (c *collection) upsert(points []*pb.PointStruct) error {
+	// Validate everything first so a bad point doesn't leave a partial write
+	stored := make([]*storedPoint, 0, len(points))
+	for _, point := range points {
+		if point.GetId() == nil {
+			return fmt.Errorf("point without ID")
+		}
+
+		vector := point.GetVectors().GetVector().GetData()
+		if uint64(len(vector)) != c.dimensions {
+			return fmt.Errorf("%w: expected %d, got %d", ErrDimensionMismatch, c.dimensions, len(vector))
+		}
+
+		// Cosine similarity is a dot product on normalized vectors, as in Qdrant
+		vector = append([]float32(nil), vector...)
+		if c.distance == pb.Distance_Cosine {
+			normalize(vector)
+		}
+
+		stored = append(stored, &storedPoint{
+			id:      point.GetId(),
+			vector:  vector,
+			payload: copyPayload(point.GetPayload()),
+		})
+	}
+
+	for _, point := range stored {
+		c.put(point)
+	}
+
+	if len(stored) > 0 {
+		c.dirty = true
+	}
+	return nil
+}
+
+// delete removes points by ID, unknown IDs are ignored
+func (c *collection) delete(ids []string) {
+	for _, id := range ids {
+		if point, ok := c.points[id]; ok {
+			c.unindexPath(id, point)
+			delete(c.points, id)
+			c.dirty = true
+		}
+	}
+}
+
+// put stores a point, replacing the one with the same ID, and keeps the path index current
+func (c *collection) put(point *storedPoint) {
+	key := pointKey(point.id)
+	if old, ok := c.points[key]; ok {
+		c.unindexPath(key, old)
+	}
+	c.points[key] = point
+
+	if path := point.payload["path"].GetStringValue(); path != "" {
+		keys := c.byPath[path]
+		if keys == nil {
+			keys = make(map[string]bool)
+			c.byPath[path] = keys
+		}
+		keys[key] = true
+	}
+}
+
+// unindexPath removes a point from the path index
+func (c *collection) unindexPath(key string, point *storedPoint) {
+	path := point.payload["path"].GetStringValue()
+	if keys := c.byPath[path]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.byPath, path)
+		}
+	}
+}
+
+// candidateKeys returns the sorted keys of the points that can match the filter.
+// A required path or set of IDs narrows them down without looking at every point.
+func (c *collection) candidateKeys(filter *pb.Filter) []string {
+	var keys []string
+	narrowed := false
+	for _, cond := range filter.GetMust() {
+		if field := cond.GetField(); field != nil && field.GetKey() == "path" {
+			if path, ok := field.GetMatch().GetMatchValue().(*pb.Match_Keyword); ok {
+				for key := range c.byPath[path.Keyword] {
+					keys = append(keys, key)
+				}
+				narrowed = true
+				break
+			}
+		}
+		if hasID := cond.GetHasId(); hasID != nil {
+			seen := make(map[string]bool)
+			for _, id := range hasID.GetHasId() {
+				key := pointKey(id)
+				if _, ok := c.points[key]; ok && !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+			narrowed = true
+			break
+		}
+	}
+
+	if !narrowed {
+		keys = make([]string, 0, len(c.points))
+		for key := range c.points {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// search scores every point matching the filter and returns the requested page.
+// Like Qdrant, the score threshold is a lower bound for similarities and an upper bound for distances.
+func (c *collection) search(ctx context.Context, vector []float32, limit, offset uint64, filter *pb.Filter, scoreThreshold *float32) ([]*pb.ScoredPoint, error) {
+	if uint64(len(vector)) != c.dimensions {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrDimensionMismatch, c.dimensions, len(vector))
+	}
+
+	query := append([]float32(nil), vector...)
+	if c.distance == pb.Distance_Cosine {
+		normalize(query)
+	}
+
+	type candidate struct {
+		key   string
+		point *storedPoint
+		score float32
+	}
+
+	candidates := make([]candidate, 0, len(c.points))
+	for key, point := range c.points {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ok, err := c.matchFilter(filter, point)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		score := c.score(query, point.vector)
+		if scoreThreshold != nil && !c.withinThreshold(score, *scoreThreshold) {
+			continue
+		}
+
+		candidates = append(candidates, candidate{
+			key:   key,
+			point: point,
+			score: score,
+		})
+	}
+
+	// Similarities sort descending, distances ascending; ties are broken by ID for stable pages
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			if c.higherIsBetter() {
+				return candidates[i].score > candidates[j].score
+			}
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].key < candidates[j].key
+	})
+
+	if offset >= uint64(len(candidates)) {
+		return []*pb.ScoredPoint{}, nil
+	}
+	end := uint64(len(candidates))
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	results := make([]*pb.ScoredPoint, 0, end-offset)
+	for _, cand := range candidates[offset:end] {
+		scored := &pb.ScoredPoint{
+			Id:      cand.point.id,
+			Payload: copyPayload(cand.point.payload),
+			Score:   cand.score,
+			Vectors: vectorsOutput(cand.point.vector),
+		}
+		results = append(results, scored)
+	}
+
+	return results, nil
+}
+
+// higherIsBetter reports whether scores are similarities rather than distances
+func (c *collection) higherIsBetter() bool {
+	return c.distance == pb.Distance_Cosine || c.distance == pb.Distance_Dot
+}
+
+// withinThreshold reports whether a score is at least as good as the threshold
+func (c *collection) withinThreshold(score, threshold float32) bool {
+	if c.higherIsBetter() {
+		return score >= threshold
+	}
+	return score <= threshold
+}
+
+// score compares two vectors using the collection's distance
+func (c *collection) score(a, b []float32) float32 {
+	var sum float64
+	switch c.distance {
+	case pb.Distance_Euclid:
+		for i := range a {
+			d := float64(a[i] - b[i])
+			sum += d * d
+		}
+		return float32(math.Sqrt(sum))
+	case pb.Distance_Manhattan:
+		for i := range a {
+			sum += math.Abs(float64(a[i] - b[i]))
+		}
+		return float32(sum)
+	default:
+		for i := range a {
+			sum += float64(a[i]) * float64(b[i])
+		}
+		return float32(sum)
+	}
+}
+
+// info builds a Qdrant style description of the collection
+func (c *collection) info() *pb.CollectionInfo {
+	count := uint64(len(c.points))
+	vectorsCount := count
+	indexedCount := count
+
+	return &pb.CollectionInfo{
+		Status:              pb.CollectionStatus_Green,
+		SegmentsCount:       1,
+		PointsCount:         &count,
+		VectorsCount:        &vectorsCount,
+		IndexedVectorsCount: &indexedCount,
+		Config: &pb.CollectionConfig{
+			Params: &pb.CollectionParams{
+				VectorsConfig: &pb.VectorsConfig{
+					Config: &pb.VectorsConfig_Params{
+						Params: &pb.VectorParams{
+							Size:     c.dimensions,
+							Distance: c.distance,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// normalize scales a vector to unit length in place
+func normalize(vector []float32) {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+// vectorsOutput wraps a stored vector for a search or retrieve response
+func vectorsOutput(vector []float32) *pb.VectorsOutput {
+	return &pb.VectorsOutput{
+		VectorsOptions: &pb.VectorsOutput_Vector{
+			Vector: &pb.VectorOutput{Data: vector},
+		},
+	}
+}
@@ -0,0 +1,360 @@
+package vectorstore
+
+import (
+	"fmt"
+	"obsfind/src/pkg/model"
+	"strings"
+	"time"
+	"unicode"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+// matchFilter evaluates a Qdrant filter against a point.
+// A nil filter matches every point.
+func (c *collection) matchFilter(filter *pb.Filter, point *storedPoint) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	// All must conditions have to match
+	for _, cond := range filter.GetMust() {
+		ok, err := c.matchCondition(cond, point)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	// None of the must_not conditions may match
+	for _, cond := range filter.GetMustNot() {
+		ok, err := c.matchCondition(cond, point)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return false, nil
+		}
+	}
+
+	// At least one should condition has to match if there are any
+	if should := filter.GetShould(); len(should) > 0 {
+		count, err := c.countMatches(should, point, 1)
+		if err != nil || count == 0 {
+			return false, err
+		}
+	}
+
+	if minShould := filter.GetMinShould(); minShould != nil {
+		count, err := c.countMatches(minShould.GetConditions(), point, minShould.GetMinCount())
+		if err != nil || count < minShould.GetMinCount() {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// countMatches counts matching conditions, stopping once enough have matched
+func (c *collection) countMatches(conditions []*pb.Condition, point *storedPoint, enough uint64) (uint64, error) {
+	var count uint64
+	for _, cond := range conditions {
+		ok, err := c.matchCondition(cond, point)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			count++
+			if count >= enough {
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+// matchCondition evaluates a single filter condition
+func (c *collection) matchCondition(cond *pb.Condition, point *storedPoint) (bool, error) {
+	switch cc := cond.GetConditionOneOf().(type) {
+	case *pb.Condition_Field:
+		return c.matchField(cc.Field, point.payload)
+	case *pb.Condition_Filter:
+		return c.matchFilter(cc.Filter, point)
+	case *pb.Condition_HasId:
+		key := pointKey(point.id)
+		for _, id := range cc.HasId.GetHasId() {
+			if pointKey(id) == key {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *pb.Condition_IsEmpty:
+		values, found := lookupValues(point.payload, cc.IsEmpty.GetKey())
+		if !found {
+			return true, nil
+		}
+		for _, v := range values {
+			if _, isNull := v.GetKind().(*pb.Value_NullValue); !isNull {
+				return false, nil
+			}
+		}
+		return true, nil
+	case *pb.Condition_IsNull:
+		values, found := lookupValues(point.payload, cc.IsNull.GetKey())
+		if !found || len(values) != 1 {
+			return false, nil
+		}
+		_, isNull := values[0].GetKind().(*pb.Value_NullValue)
+		return isNull, nil
+	default:
+		return false, fmt.Errorf("unsupported filter condition %T", cc)
+	}
+}
+
+// matchField evaluates a field condition against the payload
+func (c *collection) matchField(field *pb.FieldCondition, payload map[string]*pb.Value) (bool, error) {
+	values, _ := lookupValues(payload, field.GetKey())
+
+	switch {
+	case field.GetMatch() != nil:
+		return matchValues(field.GetMatch(), values, c.payloadIndexes[field.GetKey()] == model.PayloadIndexText)
+	case field.GetRange() != nil:
+		return anyValue(values, func(v *pb.Value) bool {
+			n, ok := numericValue(v)
+			return ok && inRange(field.GetRange(), n)
+		}), nil
+	case field.GetDatetimeRange() != nil:
+		return anyValue(values, func(v *pb.Value) bool {
+			t, ok := datetimeValue(v)
+			return ok && inDatetimeRange(field.GetDatetimeRange(), t)
+		}), nil
+	case field.GetValuesCount() != nil:
+		return inValuesCount(field.GetValuesCount(), uint64(len(values))), nil
+	default:
+		return false, fmt.Errorf("unsupported condition on field %q", field.GetKey())
+	}
+}
+
+// matchValues applies a match condition; for array fields any element may match
+func matchValues(match *pb.Match, values []*pb.Value, textIndexed bool) (bool, error) {
+	switch m := match.GetMatchValue().(type) {
+	case *pb.Match_Keyword:
+		return anyValue(values, func(v *pb.Value) bool {
+			return isString(v) && v.GetStringValue() == m.Keyword
+		}), nil
+	case *pb.Match_Text:
+		// With a full-text index Qdrant matches when all words occur, ignoring case;
+		// without one it matches text as an exact substring
+		if textIndexed {
+			words := textTokens(m.Text)
+			return anyValue(values, func(v *pb.Value) bool {
+				return isString(v) && containsAllWords(textTokens(v.GetStringValue()), words)
+			}), nil
+		}
+		return anyValue(values, func(v *pb.Value) bool {
+			return isString(v) && strings.Contains(v.GetStringValue(), m.Text)
+		}), nil
+	case *pb.Match_Keywords:
+		return anyValue(values, func(v *pb.Value) bool {
+			return isString(v) && containsString(m.Keywords.GetStrings(), v.GetStringValue())
+		}), nil
+	case *pb.Match_ExceptKeywords:
+		return anyValue(values, func(v *pb.Value) bool {
+			return !isString(v) || !containsString(m.ExceptKeywords.GetStrings(), v.GetStringValue())
+		}), nil
+	case *pb.Match_Integer:
+		return anyValue(values, func(v *pb.Value) bool {
+			return isInteger(v) && v.GetIntegerValue() == m.Integer
+		}), nil
+	case *pb.Match_Integers:
+		return anyValue(values, func(v *pb.Value) bool {
+			return isInteger(v) && containsInt(m.Integers.GetIntegers(), v.GetIntegerValue())
+		}), nil
+	case *pb.Match_ExceptIntegers:
+		return anyValue(values, func(v *pb.Value) bool {
+			return !isInteger(v) || !containsInt(m.ExceptIntegers.GetIntegers(), v.GetIntegerValue())
+		}), nil
+	case *pb.Match_Boolean:
+		return anyValue(values, func(v *pb.Value) bool {
+			b, ok := v.GetKind().(*pb.Value_BoolValue)
+			return ok && b.BoolValue == m.Boolean
+		}), nil
+	default:
+		return false, fmt.Errorf("unsupported match %T", m)
+	}
+}
+
+// lookupValues resolves a dotted key in the payload and flattens arrays.
+// The second return value reports whether the key exists at all.
+func lookupValues(payload map[string]*pb.Value, key string) ([]*pb.Value, bool) {
+	parts := strings.Split(strings.ReplaceAll(key, "[]", ""), ".")
+
+	root, ok := payload[parts[0]]
+	if !ok {
+		return nil, false
+	}
+	current := flatten(root)
+
+	for _, part := range parts[1:] {
+		var next []*pb.Value
+		for _, v := range current {
+			if field, ok := v.GetStructValue().GetFields()[part]; ok {
+				next = append(next, flatten(field)...)
+			}
+		}
+		if len(next) == 0 {
+			return nil, false
+		}
+		current = next
+	}
+
+	return current, true
+}
+
+// flatten expands list values into their elements
+func flatten(v *pb.Value) []*pb.Value {
+	if list, ok := v.GetKind().(*pb.Value_ListValue); ok {
+		return list.ListValue.GetValues()
+	}
+	return []*pb.Value{v}
+}
+
+// anyValue reports whether any of the values satisfies the predicate
+func anyValue(values []*pb.Value, predicate func(*pb.Value) bool) bool {
+	for _, v := range values {
+		if predicate(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// numericValue returns the value as float64 if it is a number
+func numericValue(v *pb.Value) (float64, bool) {
+	switch n := v.GetKind().(type) {
+	case *pb.Value_IntegerValue:
+		return float64(n.IntegerValue), true
+	case *pb.Value_DoubleValue:
+		return n.DoubleValue, true
+	default:
+		return 0, false
+	}
+}
+
+// inRange checks a number against all bounds of a range
+func inRange(r *pb.Range, n float64) bool {
+	if r.Lt != nil && !(n < *r.Lt) {
+		return false
+	}
+	if r.Gt != nil && !(n > *r.Gt) {
+		return false
+	}
+	if r.Lte != nil && !(n <= *r.Lte) {
+		return false
+	}
+	if r.Gte != nil && !(n >= *r.Gte) {
+		return false
+	}
+	return true
+}
+
+// datetimeLayouts are the datetime formats Qdrant accepts in payloads
+var datetimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// datetimeValue parses a string value as a datetime, values without a zone are UTC
+func datetimeValue(v *pb.Value) (time.Time, bool) {
+	if !isString(v) {
+		return time.Time{}, false
+	}
+	for _, layout := range datetimeLayouts {
+		if t, err := time.Parse(layout, v.GetStringValue()); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// inDatetimeRange checks a datetime against all bounds of a range
+func inDatetimeRange(r *pb.DatetimeRange, t time.Time) bool {
+	if r.Lt != nil && !t.Before(r.Lt.AsTime()) {
+		return false
+	}
+	if r.Gt != nil && !t.After(r.Gt.AsTime()) {
+		return false
+	}
+	if r.Lte != nil && t.After(r.Lte.AsTime()) {
+		return false
+	}
+	if r.Gte != nil && t.Before(r.Gte.AsTime()) {
+		return false
+	}
+	return true
+}
+
+// inValuesCount checks the number of values against a values count condition
+func inValuesCount(vc *pb.ValuesCount, n uint64) bool {
+	if vc.Lt != nil && !(n < *vc.Lt) {
+		return false
+	}
+	if vc.Gt != nil && !(n > *vc.Gt) {
+		return false
+	}
+	if vc.Lte != nil && !(n <= *vc.Lte) {
+		return false
+	}
+	if vc.Gte != nil && !(n >= *vc.Gte) {
+		return false
+	}
+	return true
+}
+
+func isString(v *pb.Value) bool {
+	_, ok := v.GetKind().(*pb.Value_StringValue)
+	return ok
+}
+
+func isInteger(v *pb.Value) bool {
+	_, ok := v.GetKind().(*pb.Value_IntegerValue)
+	return ok
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// textTokens splits text into lowercase words like Qdrant's default word tokenizer
+func textTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsAllWords reports whether every word occurs in tokens
+func containsAllWords(tokens, words []string) bool {
+	for _, word := range words {
+		if !containsString(tokens, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(list []int64, n int64) bool {
+	for _, item := range list {
+		if item == n {
+			return true
+		}
+	}
+	return false
+}
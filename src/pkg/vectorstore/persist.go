@@ -0,0 +1,118 @@
+package vectorstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/protobuf/proto"
+)
+
+// fileVersion is the current version of the collection file format
+const fileVersion = 1
+
+// collectionFile is the on-disk representation of a collection.
+// Points are stored as an encoded pb.UpsertPoints message so payloads keep their exact types.
+type collectionFile struct {
+	Version        int
+	Name           string
+	Dimensions     uint64
+	Distance       int32
+	PayloadIndexes map[string]int
+	Points         []byte
+}
+
+// loadCollection reads a collection from disk
+func loadCollection(path string) (*collection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection: %w", err)
+	}
+
+	var file collectionFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode collection: %w", err)
+	}
+
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported collection file version %d", file.Version)
+	}
+
+	var points pb.UpsertPoints
+	if err := proto.Unmarshal(file.Points, &points); err != nil {
+		return nil, fmt.Errorf("failed to decode points: %w", err)
+	}
+
+	col := newCollection(file.Name, file.Dimensions, pb.Distance(file.Distance))
+	for field, fieldType := range file.PayloadIndexes {
+		col.payloadIndexes[field] = fieldType
+	}
+
+	// Vectors were normalized before they were saved, so they are restored as is
+	for _, point := range points.Points {
+		col.put(&storedPoint{
+			id:      point.GetId(),
+			vector:  point.GetVectors().GetVector().GetData(),
+			payload: point.GetPayload(),
+		})
+	}
+
+	col.dirty = false
+	return col, nil
+}
+
+// save writes the collection to disk if it changed since the last save
+func (c *collection) save(path string) error {
+	if !c.dirty {
+		return nil
+	}
+
+	points := &pb.UpsertPoints{
+		CollectionName: c.name,
+		Points:         make([]*pb.PointStruct, 0, len(c.points)),
+	}
+	for _, point := range c.points {
+		points.Points = append(points.Points, &pb.PointStruct{
+			Id: point.id,
+			Vectors: &pb.Vectors{
+				VectorsOptions: &pb.Vectors_Vector{
+					Vector: &pb.Vector{Data: point.vector},
+				},
+			},
+			Payload: point.payload,
+		})
+	}
+
+	encodedPoints, err := proto.Marshal(points)
+	if err != nil {
+		return fmt.Errorf("failed to encode points of %s: %w", c.name, err)
+	}
+
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(collectionFile{
+		Version:        fileVersion,
+		Name:           c.name,
+		Dimensions:     c.dimensions,
+		Distance:       int32(c.distance),
+		PayloadIndexes: c.payloadIndexes,
+		Points:         encodedPoints,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode collection %s: %w", c.name, err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated collection
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write collection %s: %w", c.name, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace collection %s: %w", c.name, err)
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,370 @@
+// Package vectorstore provides an in-process vector store that implements
+// model.QdrantClient, so obsfind can run without a separate Qdrant server.
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/rs/zerolog/log"
+)
+
+// Common errors
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrDimensionMismatch  = errors.New("vector dimension mismatch")
+	ErrClosed             = errors.New("vector store is closed")
+)
+
+// collectionFileExt is the extension of persisted collection files
+const collectionFileExt = ".vectors"
+
+// defaultFlushInterval is how often dirty collections are written to disk
+const defaultFlushInterval = 5 * time.Second
+
+// Store is an embedded vector store backed by a flat index.
+// Every collection is kept in memory and persisted to a file under the data path.
+type Store struct {
+	mu            sync.RWMutex
+	dataPath      string
+	collections   map[string]*collection
+	flushInterval time.Duration
+	done          chan struct{}
+	wg            sync.WaitGroup
+	closed        bool
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithFlushInterval sets how often changes are persisted to disk
+func WithFlushInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.flushInterval = interval
+	}
+}
+
+// Open loads all collections found in dataPath and starts the background flusher
+func Open(dataPath string, options ...Option) (*Store, error) {
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	s := &Store{
+		dataPath:      dataPath,
+		collections:   make(map[string]*collection),
+		flushInterval: defaultFlushInterval,
+		done:          make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dataPath, "*"+collectionFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collection files: %w", err)
+	}
+
+	for _, file := range files {
+		col, err := loadCollection(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", filepath.Base(file), err)
+		}
+		s.collections[col.name] = col
+
+		log.Info().
+			Str("collection", col.name).
+			Int("points", len(col.points)).
+			Msg("Loaded embedded collection")
+	}
+
+	if s.flushInterval > 0 {
+		s.wg.Add(1)
+		go s.flushLoop()
+	}
+
+	return s, nil
+}
+
+// flushLoop periodically persists collections that have changed
+func (s *Store) flushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to persist embedded vector store")
+			}
+		}
+	}
+}
+
+// Flush writes every changed collection to disk
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, col := range s.collections {
+		if err := col.save(s.collectionFile(col.name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close stops the background flusher and persists all pending changes
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return s.Flush()
+}
+
+// collectionFile returns the on-disk location of a collection
+func (s *Store) collectionFile(name string) string {
+	return filepath.Join(s.dataPath, name+collectionFileExt)
+}
+
+// getCollection looks up a collection, the caller must hold the lock
+func (s *Store) getCollection(name string) (*collection, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	col, ok := s.collections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return col, nil
+}
+
+// CollectionExists checks if a collection exists
+func (s *Store) CollectionExists(ctx context.Context, name string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return false, ErrClosed
+	}
+
+	_, ok := s.collections[name]
+	return ok, nil
+}
+
+// CreateCollection creates a new collection if it doesn't exist
+func (s *Store) CreateCollection(ctx context.Context, name string, dimensions uint64, distance pb.Distance) error {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid collection name %q", name)
+	}
+
+	switch distance {
+	case pb.Distance_Cosine, pb.Distance_Dot, pb.Distance_Euclid, pb.Distance_Manhattan:
+	default:
+		return fmt.Errorf("unsupported distance: %v", distance)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if _, ok := s.collections[name]; ok {
+		return nil
+	}
+
+	col := newCollection(name, dimensions, distance)
+	s.collections[name] = col
+
+	log.Info().Str("collection", name).Uint64("dimensions", dimensions).Msg("Created embedded collection")
+	return col.save(s.collectionFile(name))
+}
+
+// GetCollectionInfo returns point counts and the vector configuration of a collection
+func (s *Store) GetCollectionInfo(ctx context.Context, name string) (*pb.CollectionInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	col, err := s.getCollection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return col.info(), nil
+}
+
+// DeleteCollection removes a collection and its file
+func (s *Store) DeleteCollection(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.getCollection(name); err != nil {
+		return err
+	}
+
+	delete(s.collections, name)
+
+	if err := os.Remove(s.collectionFile(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove collection file: %w", err)
+	}
+
+	log.Info().Str("collection", name).Msg("Deleted embedded collection")
+	return nil
+}
+
+// UpsertPoints adds or updates points in a collection
+func (s *Store) UpsertPoints(ctx context.Context, collectionName string, points []*pb.PointStruct) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	col, err := s.getCollection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	return col.upsert(points)
+}
+
+// DeletePoints removes points by ID
+func (s *Store) DeletePoints(ctx context.Context, collectionName string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	col, err := s.getCollection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	col.delete(ids)
+	return nil
+}
+
+// GetPointsByPath returns all points whose path payload equals path
+func (s *Store) GetPointsByPath(ctx context.Context, collectionName string, path string, withVectors bool) ([]*pb.RetrievedPoint, error) {
+	filter := &pb.Filter{
+		Must: []*pb.Condition{
+			{
+				ConditionOneOf: &pb.Condition_Field{
+					Field: &pb.FieldCondition{
+						Key: "path",
+						Match: &pb.Match{
+							MatchValue: &pb.Match_Keyword{Keyword: path},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return s.ScrollPoints(ctx, collectionName, filter, withVectors)
+}
+
+// ScrollPoints returns all points matching the filter ordered by ID.
+// A nil filter returns every point in the collection.
+func (s *Store) ScrollPoints(ctx context.Context, collectionName string, filter *pb.Filter, withVectors bool) ([]*pb.RetrievedPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	col, err := s.getCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*pb.RetrievedPoint
+	for _, key := range col.candidateKeys(filter) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		point := col.points[key]
+		ok, err := col.matchFilter(filter, point)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		retrieved := &pb.RetrievedPoint{
+			Id:      point.id,
+			Payload: copyPayload(point.payload),
+		}
+		if withVectors {
+			retrieved.Vectors = vectorsOutput(point.vector)
+		}
+		results = append(results, retrieved)
+	}
+
+	return results, nil
+}
+
+// Search performs an exact nearest neighbour search over the points matching the filter
+func (s *Store) Search(
+	ctx context.Context,
+	collectionName string,
+	vector []float32,
+	limit uint64,
+	offset uint64,
+	filter *pb.Filter,
+	params *pb.SearchParams,
+	scoreThreshold *float32,
+) ([]*pb.ScoredPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	col, err := s.getCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return col.search(ctx, vector, limit, offset, filter, scoreThreshold)
+}
+
+// CreatePayloadIndex records the index for the field.
+// The flat index scans every point, so indexes don't change query execution.
+func (s *Store) CreatePayloadIndex(ctx context.Context, collectionName string, fieldName string, fieldType int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	col, err := s.getCollection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	col.payloadIndexes[fieldName] = fieldType
+	col.dirty = true
+	return nil
+}
+
+// copyPayload returns a shallow copy so callers can modify the returned map
+func copyPayload(payload map[string]*pb.Value) map[string]*pb.Value {
+	result := make(map[string]*pb.Value, len(payload))
+	for k, v := range payload {
+		result[k] = v
+	}
+	return result
+}